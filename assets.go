@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"yummy-go.com/m/v2/frontend"
+	"yummy-go.com/m/v2/scir"
+	"yummy-go.com/m/v2/span"
+)
+
+// loads the costumes and sounds declared in the program into the editing
+// target, asset paths are relative to the source file
+func declareAssets(project *scir.Scir, ast frontend.Program, sourcePath string) bool {
+	baseDir := filepath.Dir(sourcePath)
+	ok := true
+	for _, declaration := range ast.Declarations {
+		switch declaration := declaration.(type) {
+		case *frontend.CostumeDeclaration:
+			content, err := os.ReadFile(filepath.Join(baseDir, declaration.Path.StringValue()))
+			if err != nil {
+				span.Report(declaration.Path.Span, span.Error, "cannot read costume: %s", err.Error())
+				ok = false
+				continue
+			}
+			var center *[2]float64
+			if declaration.Center != nil {
+				xText, yText := declaration.Center.Coordinates()
+				x, errX := strconv.ParseFloat(xText, 64)
+				y, errY := strconv.ParseFloat(yText, 64)
+				if errX != nil || errY != nil {
+					span.Report(declaration.Center.Span, span.Error, "invalid rotation center")
+					ok = false
+					continue
+				}
+				center = &[2]float64{x, y}
+			}
+			costume, err := scir.NewCostume(declaration.Name.StringValue(), content, center)
+			if err != nil {
+				span.Report(declaration.Path.Span, span.Error, "invalid costume: %s", err.Error())
+				ok = false
+				continue
+			}
+			project.AddCostume(project.EditingTarget, costume, content)
+		case *frontend.SoundDeclaration:
+			content, err := os.ReadFile(filepath.Join(baseDir, declaration.Path.StringValue()))
+			if err != nil {
+				span.Report(declaration.Path.Span, span.Error, "cannot read sound: %s", err.Error())
+				ok = false
+				continue
+			}
+			sound, err := scir.NewSound(declaration.Name.StringValue(), content)
+			if err != nil {
+				span.Report(declaration.Path.Span, span.Error, "invalid sound: %s", err.Error())
+				ok = false
+				continue
+			}
+			project.AddSound(project.EditingTarget, sound, content)
+		}
+	}
+	return ok
+}
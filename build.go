@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"yummy-go.com/m/v2/omitter"
+	"yummy-go.com/m/v2/scir"
+	"yummy-go.com/m/v2/span"
+)
+
+func runBuild(args []string) int {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	outputPath := flags.String("o", "", "path of the generated .sb3 (default: next to the source)")
+	templatePath := flags.String("template", "", "start from an existing .sb3 instead of an empty project")
+	intoPath := flags.String("into", "", "rebuild into an existing .sb3, keeping everything the previous build did not generate")
+	target := flags.String("target", "Stage", "the target a .mir source is built into")
+	debugComments := flags.Bool("debug-comments", false, "annotate each generated statement with its source location")
+	optimization := addOptimizationFlags(flags)
+	flags.Parse(expandOptimizationFlags(args))
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: yummy build [-o output.sb3] [-O0|-O1|-O2] [-dump-passes] [-debug-comments] [-template template.sb3 | -into project.sb3] [-target name] <source.yum|source.mir>")
+		return 2
+	}
+	if *templatePath != "" && *intoPath != "" {
+		fmt.Fprintln(os.Stderr, "yummy build: -template and -into cannot be used together")
+		return 2
+	}
+	sourcePath := flags.Arg(0)
+	if *outputPath == "" && *intoPath != "" {
+		*outputPath = *intoPath
+	} else if *outputPath == "" {
+		*outputPath = strings.TrimSuffix(sourcePath, filepath.Ext(sourcePath)) + ".sb3"
+	}
+	idTablePath := *outputPath + ".json"
+
+	span.ResetStats()
+	program, ast, ok := loadSource(sourcePath)
+	if !ok {
+		reportSummary(sourcePath)
+		return 1
+	}
+	if !optimize(&program, optimization) {
+		return 1
+	}
+
+	var project scir.Scir
+	if *intoPath != "" {
+		// the id table of the project lives next to it
+		intoIdTablePath := *intoPath + ".json"
+		var err error
+		project, err = scir.LoadSb3(*intoPath, &intoIdTablePath)
+		if err != nil {
+			span.ReportNoSpan(span.Error, "%s: %s", *intoPath, err.Error())
+			return 1
+		}
+	} else if *templatePath != "" {
+		var err error
+		project, err = scir.LoadSb3(*templatePath, &idTablePath)
+		if err != nil {
+			span.ReportNoSpan(span.Error, "%s: %s", *templatePath, err.Error())
+			return 1
+		}
+	} else {
+		project = scir.NewProject()
+		if idTable, err := scir.OpenIdTable(idTablePath); err == nil {
+			project.IdTable = idTable
+		}
+	}
+
+	project.RemoveGeneratedBlocks()
+
+	theOmitter := omitter.New(&project)
+	theOmitter.SetDebugComments(*debugComments)
+	// a .mir source has no target declaration nor assets
+	if ast == nil {
+		theOmitter.SetTarget(*target)
+	} else {
+		theOmitter.SetTarget(ast.Target.Identifier())
+		if !declareAssets(&project, *ast, sourcePath) {
+			reportSummary(sourcePath)
+			return 1
+		}
+	}
+	if err := theOmitter.Omit(program); err != nil {
+		span.ReportNoSpan(span.Error, "%s: %s", sourcePath, err.Error())
+		return 1
+	}
+	if err := scir.ExportSb3(*outputPath, idTablePath, project); err != nil {
+		span.ReportNoSpan(span.Error, "%s: %s", *outputPath, err.Error())
+		return 1
+	}
+	if !reportSummary(sourcePath) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"yummy-go.com/m/v2/scir"
+)
+
+// a mono 8 bits wav of a single sample
+func wavFixture() []byte {
+	content := []byte("RIFF\x25\x00\x00\x00WAVEfmt \x10\x00\x00\x00")
+	content = binary.LittleEndian.AppendUint16(content, 1)
+	content = binary.LittleEndian.AppendUint16(content, 1)
+	content = binary.LittleEndian.AppendUint32(content, 8000)
+	content = binary.LittleEndian.AppendUint32(content, 8000)
+	content = binary.LittleEndian.AppendUint16(content, 1)
+	content = binary.LittleEndian.AppendUint16(content, 8)
+	return append(content, "data\x01\x00\x00\x00\x80"...)
+}
+
+func TestBuildDeclaresAssets(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"sprite.yum": []byte("target Sprite\n\ncostume \"dot\" from \"dot.svg\" center(0, 0)\nsound \"pop\" from \"pop.wav\"\n"),
+		"dot.svg":    []byte(`<svg width="2" height="2"></svg>`),
+		"pop.wav":    wavFixture(),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outputPath := filepath.Join(dir, "sprite.sb3")
+	if code := runBuild([]string{"-o", outputPath, filepath.Join(dir, "sprite.yum")}); code != 0 {
+		t.Fatalf("the build exited with %d", code)
+	}
+	project, err := scir.LoadSb3(outputPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sprite *scir.Target
+	for idx := range project.Ir.Targets {
+		if project.Ir.Targets[idx].Name == "Sprite" {
+			sprite = &project.Ir.Targets[idx]
+		}
+	}
+	if sprite == nil {
+		t.Fatal("missing target `Sprite`")
+	}
+	// the declared costume replaces the one the sprite was made with
+	if len(sprite.Costumes) != 1 || sprite.Costumes[0].Name != "dot" {
+		t.Fatalf("expected only the costume `dot`, found %+v", sprite.Costumes)
+	}
+	if dot := sprite.Costumes[0]; dot.RotationCenterX != 0 || dot.RotationCenterY != 0 {
+		t.Errorf("expected the center at (0, 0), found (%g, %g)", dot.RotationCenterX, dot.RotationCenterY)
+	}
+	if len(sprite.Sounds) != 1 || sprite.Sounds[0].Name != "pop" || sprite.Sounds[0].SampleCount != 1 {
+		t.Errorf("expected the sound `pop` of one sample, found %+v", sprite.Sounds)
+	}
+	for _, md5ext := range []string{sprite.Costumes[0].Md5ext, sprite.Sounds[0].Md5ext} {
+		if _, ok := project.Assets[md5ext]; !ok {
+			t.Errorf("missing asset `%s`", md5ext)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package checker
+
+import "yummy-go.com/m/v2/mir"
+
+// a Scratch block called like a function
+type Builtin struct {
+	Name      string
+	Arguments []BuiltinArgument
+	// nil for blocks that return nothing
+	Result mir.Type
+	Opcode string
+	Doc    string
+}
+
+type BuiltinArgument struct {
+	Name string
+	Type mir.Type
+	// the input of the block taking the value
+	Input string
+}
+
+func number(name, input string) BuiltinArgument {
+	return BuiltinArgument{Name: name, Type: &mir.NumberType{}, Input: input}
+}
+
+func text(name, input string) BuiltinArgument {
+	return BuiltinArgument{Name: name, Type: &mir.StringType{}, Input: input}
+}
+
+// takes values of any type
+func anything(name, input string) BuiltinArgument {
+	return BuiltinArgument{Name: name, Type: &mir.UntypedType{}, Input: input}
+}
+
+var Builtins = []Builtin{
+	{Name: "say", Arguments: []BuiltinArgument{anything("message", "MESSAGE")}, Opcode: "looks_say", Doc: "shows a speech bubble"},
+	{Name: "sayFor", Arguments: []BuiltinArgument{anything("message", "MESSAGE"), number("seconds", "SECS")}, Opcode: "looks_sayforsecs", Doc: "shows a speech bubble for some seconds"},
+	{Name: "think", Arguments: []BuiltinArgument{anything("message", "MESSAGE")}, Opcode: "looks_think", Doc: "shows a thought bubble"},
+	{Name: "show", Opcode: "looks_show", Doc: "shows the sprite"},
+	{Name: "hide", Opcode: "looks_hide", Doc: "hides the sprite"},
+	{Name: "switchCostume", Arguments: []BuiltinArgument{text("costume", "COSTUME")}, Opcode: "looks_switchcostumeto", Doc: "switches to the costume"},
+	{Name: "nextCostume", Opcode: "looks_nextcostume", Doc: "switches to the next costume"},
+	{Name: "move", Arguments: []BuiltinArgument{number("steps", "STEPS")}, Opcode: "motion_movesteps", Doc: "moves the sprite forward"},
+	{Name: "turn", Arguments: []BuiltinArgument{number("degrees", "DEGREES")}, Opcode: "motion_turnright", Doc: "turns the sprite clockwise"},
+	{Name: "goTo", Arguments: []BuiltinArgument{number("x", "X"), number("y", "Y")}, Opcode: "motion_gotoxy", Doc: "moves the sprite to a point"},
+	{Name: "pointIn", Arguments: []BuiltinArgument{number("direction", "DIRECTION")}, Opcode: "motion_pointindirection", Doc: "points the sprite in the direction"},
+	{Name: "x", Result: &mir.NumberType{}, Opcode: "motion_xposition", Doc: "the x position of the sprite"},
+	{Name: "y", Result: &mir.NumberType{}, Opcode: "motion_yposition", Doc: "the y position of the sprite"},
+	{Name: "direction", Result: &mir.NumberType{}, Opcode: "motion_direction", Doc: "the direction of the sprite"},
+	{Name: "wait", Arguments: []BuiltinArgument{number("seconds", "DURATION")}, Opcode: "control_wait", Doc: "waits for some seconds"},
+	{Name: "broadcast", Arguments: []BuiltinArgument{text("message", "BROADCAST_INPUT")}, Opcode: "event_broadcast", Doc: "sends a message to every sprite"},
+	{Name: "ask", Arguments: []BuiltinArgument{text("question", "QUESTION")}, Opcode: "sensing_askandwait", Doc: "asks a question and waits for the answer"},
+	{Name: "answer", Result: &mir.StringType{}, Opcode: "sensing_answer", Doc: "the last answer"},
+	{Name: "timer", Result: &mir.NumberType{}, Opcode: "sensing_timer", Doc: "the seconds since the timer was reset"},
+	{Name: "resetTimer", Opcode: "sensing_resettimer", Doc: "resets the timer"},
+	{Name: "keyPressed", Arguments: []BuiltinArgument{text("key", "KEY_OPTION")}, Result: &mir.BooleanType{}, Opcode: "sensing_keypressed", Doc: "whether the key is pressed"},
+	{Name: "mouseDown", Result: &mir.BooleanType{}, Opcode: "sensing_mousedown", Doc: "whether the mouse is down"},
+	{Name: "random", Arguments: []BuiltinArgument{number("from", "FROM"), number("to", "TO")}, Result: &mir.NumberType{}, Opcode: "operator_random", Doc: "a random number between from and to"},
+	{Name: "round", Arguments: []BuiltinArgument{number("value", "NUM")}, Result: &mir.NumberType{}, Opcode: "operator_round", Doc: "the nearest integer"},
+	{Name: "length", Arguments: []BuiltinArgument{text("value", "STRING")}, Result: &mir.NumberType{}, Opcode: "operator_length", Doc: "the length of a string"},
+	{Name: "letterOf", Arguments: []BuiltinArgument{number("index", "LETTER"), text("value", "STRING")}, Result: &mir.StringType{}, Opcode: "operator_letter_of", Doc: "the letter at the index, from 1"},
+	{Name: "playSound", Arguments: []BuiltinArgument{text("sound", "SOUND_MENU")}, Opcode: "sound_play", Doc: "starts playing the sound"},
+}
+
+// the builtin calling the block with the opcode
+func BuiltinOfOpcode(opcode string) *Builtin {
+	for idx := range Builtins {
+		if Builtins[idx].Opcode == opcode {
+			return &Builtins[idx]
+		}
+	}
+	return nil
+}
+
+func lookupBuiltin(name string) *Builtin {
+	for idx := range Builtins {
+		if Builtins[idx].Name == name {
+			return &Builtins[idx]
+		}
+	}
+	return nil
+}
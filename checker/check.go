@@ -1 +1,564 @@
 package checker
+
+import (
+	"fmt"
+
+	"yummy-go.com/m/v2/frontend"
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/span"
+)
+
+type SymbolKind uint
+
+const (
+	FunctionSymbol SymbolKind = iota
+	GlobalSymbol
+	ArgumentSymbol
+	LocalSymbol
+	FieldSymbol
+	BuiltinSymbol
+)
+
+// what a name refers to
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+	// the name at the definition, nil for builtins
+	Definition *span.Span
+	// the type of variables and fields, the return type of functions
+	TypeView mir.TypeView
+	// the comments above functions and globals, the description of builtins
+	Doc string
+	// the arguments of functions
+	Arguments []*Symbol
+	Function  *frontend.FunctionDeclaration
+	Builtin   *Builtin
+}
+
+// a name in the source and the symbol it refers to
+type Reference struct {
+	Span   span.Span
+	Symbol *Symbol
+}
+
+// the symbols of a block, each visible from its definition to the end of
+// the block
+type Scope struct {
+	Span    span.Span
+	Symbols []*Symbol
+}
+
+type Info struct {
+	// functions and global variables
+	Globals map[string]*Symbol
+	// the resolved names in source order, definitions included
+	References []Reference
+	Scopes     []*Scope
+	Types      map[frontend.Expression]mir.Type
+	// the field symbols of the struct types
+	Fields map[*mir.StructType]map[string]*Symbol
+}
+
+type checker struct {
+	info     *Info
+	scopes   []*Scope
+	checking *Symbol
+	problems uint
+}
+
+// resolves the names and the types of the program, the info is filled
+// as far as possible even when problems are found
+func Check(program frontend.Program) (*Info, error) {
+	s := checker{
+		info: &Info{
+			Globals:    make(map[string]*Symbol),
+			References: make([]Reference, 0),
+			Scopes:     make([]*Scope, 0),
+			Types:      make(map[frontend.Expression]mir.Type),
+			Fields:     make(map[*mir.StructType]map[string]*Symbol),
+		},
+	}
+	// globals and functions are visible everywhere
+	for _, declaration := range program.Declarations {
+		switch declaration := declaration.(type) {
+		case *frontend.FunctionDeclaration:
+			symbol := &Symbol{
+				Name:       declaration.Name.Identifier(),
+				Kind:       FunctionSymbol,
+				Definition: &declaration.Name.Span,
+				Doc:        declaration.Doc,
+				Function:   declaration,
+			}
+			for _, argument := range declaration.Arguments {
+				symbol.Arguments = append(symbol.Arguments, &Symbol{
+					Name:       argument.Name.Identifier(),
+					Kind:       ArgumentSymbol,
+					Definition: &argument.Name.Span,
+					TypeView:   mir.TypeView{Type: s.resolveType(argument.TypeExpression)},
+				})
+			}
+			if declaration.ReturnType != nil {
+				symbol.TypeView.Type = s.resolveType(declaration.ReturnType)
+			}
+			s.declareGlobal(symbol)
+		case *frontend.GlobalVariableDeclaration:
+			symbol := &Symbol{
+				Name:       declaration.Name.Identifier(),
+				Kind:       GlobalSymbol,
+				Definition: &declaration.Name.Span,
+				Doc:        declaration.Doc,
+			}
+			if declaration.TypeExpression != nil {
+				symbol.TypeView.Type = s.resolveType(declaration.TypeExpression)
+			}
+			s.declareGlobal(symbol)
+		}
+	}
+	for _, declaration := range program.Declarations {
+		if declaration, ok := declaration.(*frontend.GlobalVariableDeclaration); ok {
+			s.globalVariable(declaration)
+		}
+	}
+	for _, declaration := range program.Declarations {
+		switch declaration := declaration.(type) {
+		case *frontend.FunctionDeclaration:
+			s.function(declaration)
+		case *frontend.EventDeclaration:
+			s.event(declaration)
+		}
+	}
+	if s.problems > 0 {
+		return s.info, fmt.Errorf("%s found by the checker", span.Pluralize(s.problems, "problem", "problems"))
+	}
+	return s.info, nil
+}
+
+func (s *checker) report(theSpan span.Span, message string, args ...any) {
+	s.problems += 1
+	span.Report(theSpan, span.Error, message, args...)
+}
+
+func (s *checker) reference(theSpan span.Span, symbol *Symbol) {
+	s.info.References = append(s.info.References, Reference{Span: theSpan, Symbol: symbol})
+}
+
+func (s *checker) declareGlobal(symbol *Symbol) {
+	s.reference(*symbol.Definition, symbol)
+	if _, ok := s.info.Globals[symbol.Name]; ok {
+		s.report(*symbol.Definition, "`%s` is already declared", symbol.Name)
+		return
+	}
+	s.info.Globals[symbol.Name] = symbol
+}
+
+func (s *checker) openScope(theSpan span.Span) {
+	scope := &Scope{Span: theSpan, Symbols: make([]*Symbol, 0)}
+	s.scopes = append(s.scopes, scope)
+	s.info.Scopes = append(s.info.Scopes, scope)
+}
+
+func (s *checker) closeScope() {
+	s.scopes = s.scopes[:len(s.scopes)-1]
+}
+
+func (s *checker) declareLocal(symbol *Symbol) {
+	s.reference(*symbol.Definition, symbol)
+	scope := s.scopes[len(s.scopes)-1]
+	for _, other := range scope.Symbols {
+		if other.Name == symbol.Name {
+			s.report(*symbol.Definition, "`%s` is already declared in this block", symbol.Name)
+			return
+		}
+	}
+	scope.Symbols = append(scope.Symbols, symbol)
+}
+
+// locals shadow globals, globals shadow builtins
+func (s *checker) lookup(name string) *Symbol {
+	for idx := len(s.scopes) - 1; idx >= 0; idx -= 1 {
+		symbols := s.scopes[idx].Symbols
+		for idx2 := len(symbols) - 1; idx2 >= 0; idx2 -= 1 {
+			if symbols[idx2].Name == name {
+				return symbols[idx2]
+			}
+		}
+	}
+	if symbol, ok := s.info.Globals[name]; ok {
+		return symbol
+	}
+	if builtin := lookupBuiltin(name); builtin != nil {
+		return BuiltinSymbolOf(builtin)
+	}
+	return nil
+}
+
+// the symbol of a builtin, the same for every lookup
+func BuiltinSymbolOf(builtin *Builtin) *Symbol {
+	if symbol, ok := builtinSymbols[builtin]; ok {
+		return symbol
+	}
+	symbol := &Symbol{
+		Name:     builtin.Name,
+		Kind:     BuiltinSymbol,
+		TypeView: mir.TypeView{Type: builtin.Result},
+		Doc:      builtin.Doc,
+		Builtin:  builtin,
+	}
+	for _, argument := range builtin.Arguments {
+		symbol.Arguments = append(symbol.Arguments, &Symbol{
+			Name:     argument.Name,
+			Kind:     ArgumentSymbol,
+			TypeView: mir.TypeView{Type: argument.Type},
+		})
+	}
+	builtinSymbols[builtin] = symbol
+	return symbol
+}
+
+var builtinSymbols = make(map[*Builtin]*Symbol)
+
+func (s *checker) globalVariable(declaration *frontend.GlobalVariableDeclaration) {
+	symbol := s.info.Globals[declaration.Name.Identifier()]
+	if declaration.Value == nil {
+		return
+	}
+	valueType := s.expression(declaration.Value)
+	if symbol == nil || symbol.Definition != &declaration.Name.Span {
+		return
+	}
+	if declaration.TypeExpression == nil {
+		symbol.TypeView.Type = valueType
+		return
+	}
+	s.expectType(declaration.Value.GetSpan(), symbol.TypeView.Type, valueType, fmt.Sprintf("value of `%s`", symbol.Name))
+}
+
+func (s *checker) function(declaration *frontend.FunctionDeclaration) {
+	symbol := s.info.Globals[declaration.Name.Identifier()]
+	if symbol == nil || symbol.Function != declaration {
+		// a redeclared function is checked on its own
+		symbol = &Symbol{Name: declaration.Name.Identifier(), Kind: FunctionSymbol, Function: declaration}
+	}
+	s.checking = symbol
+	s.openScope(declaration.Body.Span)
+	for _, argument := range symbol.Arguments {
+		s.declareLocal(argument)
+	}
+	s.block(declaration.Body, false)
+	s.closeScope()
+	s.checking = nil
+}
+
+// handlers are checked like functions without arguments or return value
+func (s *checker) event(declaration *frontend.EventDeclaration) {
+	name := "raw event"
+	if declaration.Raw != nil {
+		s.raw(declaration.Raw)
+	} else {
+		name = declaration.Event.Identifier()
+		s.eventArguments(declaration)
+	}
+	s.checking = &Symbol{Name: name, Kind: FunctionSymbol}
+	s.block(declaration.Body, true)
+	s.checking = nil
+}
+
+// the arguments of a hat are its fields, they must be known when building
+func (s *checker) eventArguments(declaration *frontend.EventDeclaration) {
+	name := declaration.Event.Identifier()
+	event := LookupEvent(name)
+	if event == nil {
+		s.report(declaration.Event.Span, "unknown event `%s`", name)
+		return
+	}
+	for idx, argument := range declaration.Arguments {
+		s.expectType(argument.GetSpan(), &mir.StringType{}, s.expression(argument), "event argument")
+		if _, ok := argument.(*frontend.LiteralExpression); !ok && idx < len(event.Arguments) {
+			s.report(argument.GetSpan(), "argument `%s` of event `%s` must be a string literal", event.Arguments[idx].Name, name)
+		}
+	}
+	if len(declaration.Arguments) != len(event.Arguments) {
+		s.report(declaration.Event.Span, "event `%s` takes %s, found %d", name, span.Pluralize(uint(len(event.Arguments)), "argument", "arguments"), len(declaration.Arguments))
+	}
+}
+
+// the inputs of a raw block are checked, its fields are strings
+func (s *checker) raw(raw *frontend.RawExpression) mir.Type {
+	for _, argument := range raw.Arguments {
+		s.expression(argument.Value)
+	}
+	return &mir.UntypedType{}
+}
+
+func (s *checker) block(block frontend.Block, scoped bool) {
+	if scoped {
+		s.openScope(block.Span)
+		defer s.closeScope()
+	}
+	for _, statement := range block.Statements {
+		s.statement(statement)
+	}
+}
+
+func (s *checker) statement(statement frontend.Statement) {
+	switch statement := statement.(type) {
+	case *frontend.VarStatement:
+		symbol := &Symbol{
+			Name:       statement.Name.Identifier(),
+			Kind:       LocalSymbol,
+			Definition: &statement.Name.Span,
+		}
+		if statement.TypeExpression != nil {
+			symbol.TypeView.Type = s.resolveType(statement.TypeExpression)
+		}
+		if statement.Value != nil {
+			valueType := s.expression(statement.Value)
+			if statement.TypeExpression == nil {
+				symbol.TypeView.Type = valueType
+			} else {
+				s.expectType(statement.Value.GetSpan(), symbol.TypeView.Type, valueType, fmt.Sprintf("value of `%s`", symbol.Name))
+			}
+		}
+		s.declareLocal(symbol)
+	case *frontend.DeclareAssignStatement:
+		symbol := &Symbol{
+			Name:       statement.Name.Identifier(),
+			Kind:       LocalSymbol,
+			Definition: &statement.Name.Span,
+			TypeView:   mir.TypeView{Type: s.expression(statement.Value)},
+		}
+		s.declareLocal(symbol)
+	case *frontend.AssignStatement:
+		targetType := s.assignTarget(statement.Target)
+		valueType := s.expression(statement.Value)
+		s.expectType(statement.Value.GetSpan(), targetType, valueType, "assigned value")
+	case *frontend.ReturnStatement:
+		returnType := s.checking.TypeView.Type
+		hasReturnType := s.checking.Function != nil && s.checking.Function.ReturnType != nil
+		switch {
+		case statement.Value == nil && hasReturnType:
+			s.report(statement.Span, "missing return value of `%s`", FormatType(returnType))
+		case statement.Value != nil && !hasReturnType:
+			s.expression(statement.Value)
+			if s.checking.Function == nil {
+				s.report(statement.Value.GetSpan(), "event handlers return nothing")
+			} else {
+				s.report(statement.Value.GetSpan(), "function `%s` returns nothing", s.checking.Name)
+			}
+		case statement.Value != nil:
+			s.expectType(statement.Value.GetSpan(), returnType, s.expression(statement.Value), "return value")
+		}
+	case *frontend.IfStatement:
+		s.condition(statement.Condition)
+		s.block(statement.Then, true)
+		if statement.ElseIf != nil {
+			s.statement(statement.ElseIf)
+		}
+		if statement.Else != nil {
+			s.block(*statement.Else, true)
+		}
+	case *frontend.ForStatement:
+		if statement.Condition != nil {
+			s.condition(statement.Condition)
+		}
+		s.block(statement.Body, true)
+	case *frontend.RawStatement:
+		s.raw(statement.Block)
+		for _, substack := range statement.Substacks {
+			s.block(substack.Body, true)
+		}
+	case *frontend.ExpressionStatement:
+		if call, ok := statement.Expression.(*frontend.CallExpression); ok {
+			s.call(call, false)
+		} else {
+			s.expression(statement.Expression)
+		}
+	}
+}
+
+func (s *checker) condition(condition frontend.Expression) {
+	conditionType := s.expression(condition)
+	if !isType(conditionType, mir.TypeBoolean) {
+		s.report(condition.GetSpan(), "condition must be a bool, found `%s`", FormatType(conditionType))
+	}
+}
+
+// only variables, fields and elements can be assigned
+func (s *checker) assignTarget(target frontend.Expression) mir.Type {
+	if identifier, ok := target.(*frontend.IdentifierExpression); ok {
+		symbol := s.lookup(identifier.Name.Identifier())
+		if symbol != nil && (symbol.Kind == FunctionSymbol || symbol.Kind == BuiltinSymbol) {
+			s.reference(identifier.Name.Span, symbol)
+			s.report(identifier.Name.Span, "cannot assign to function `%s`", symbol.Name)
+			return nil
+		}
+	}
+	switch target.(type) {
+	case *frontend.IdentifierExpression, *frontend.MemberExpression, *frontend.IndexExpression:
+		return s.expression(target)
+	}
+	s.expression(target)
+	s.report(target.GetSpan(), "cannot assign to this expression")
+	return nil
+}
+
+// returns nil for broken values, their problems are reported
+func (s *checker) expression(expression frontend.Expression) mir.Type {
+	theType := s.typeOf(expression)
+	s.info.Types[expression] = theType
+	return theType
+}
+
+func (s *checker) typeOf(expression frontend.Expression) mir.Type {
+	switch expression := expression.(type) {
+	case *frontend.LiteralExpression:
+		switch expression.Literal.Type {
+		case frontend.TokenLiteralNumber:
+			return &mir.NumberType{}
+		case frontend.TokenLiteralString:
+			return &mir.StringType{}
+		case frontend.TokenLiteralTrue, frontend.TokenLiteralFalse:
+			return &mir.BooleanType{}
+		}
+	case *frontend.IdentifierExpression:
+		name := expression.Name.Identifier()
+		symbol := s.lookup(name)
+		if symbol == nil {
+			s.report(expression.Name.Span, "`%s` is not declared", name)
+			return nil
+		}
+		s.reference(expression.Name.Span, symbol)
+		if symbol.Kind == FunctionSymbol || symbol.Kind == BuiltinSymbol {
+			s.report(expression.Name.Span, "function `%s` must be called", name)
+			return nil
+		}
+		return symbol.TypeView.Type
+	case *frontend.ParenExpression:
+		return s.expression(expression.Value)
+	case *frontend.UnaryExpression:
+		valueType := s.expression(expression.Value)
+		operator := expression.Operator.Span.String()
+		expected := mir.Type(&mir.NumberType{})
+		if expression.Operator.Type == frontend.TokenOpNot {
+			expected = &mir.BooleanType{}
+		}
+		s.expectType(expression.Value.GetSpan(), expected, valueType, fmt.Sprintf("operand of `%s`", operator))
+		return expected
+	case *frontend.BinaryExpression:
+		return s.binary(expression)
+	case *frontend.RawExpression:
+		return s.raw(expression)
+	case *frontend.CallExpression:
+		return s.call(expression, true)
+	case *frontend.IndexExpression:
+		valueType := s.expression(expression.Value)
+		s.expectType(expression.Index.GetSpan(), &mir.NumberType{}, s.expression(expression.Index), "index")
+		switch valueType := valueType.(type) {
+		case nil:
+			return nil
+		case *mir.ArrayType:
+			return valueType.Inner
+		case *mir.DynArrayType:
+			return valueType.Inner
+		}
+		s.report(expression.Value.GetSpan(), "cannot index `%s`", FormatType(valueType))
+	case *frontend.MemberExpression:
+		valueType := s.expression(expression.Value)
+		name := expression.Member.Identifier()
+		if valueType == nil {
+			return nil
+		}
+		structType, ok := valueType.(*mir.StructType)
+		if !ok {
+			s.report(expression.Member.Span, "`%s` has no fields", FormatType(valueType))
+			return nil
+		}
+		symbol, ok := s.info.Fields[structType][name]
+		if !ok {
+			s.report(expression.Member.Span, "`%s` has no field `%s`", FormatType(valueType), name)
+			return nil
+		}
+		s.reference(expression.Member.Span, symbol)
+		return symbol.TypeView.Type
+	}
+	return nil
+}
+
+func (s *checker) binary(expression *frontend.BinaryExpression) mir.Type {
+	lhs := s.expression(expression.Lhs)
+	rhs := s.expression(expression.Rhs)
+	operator := expression.Operator.Span.String()
+	mismatch := func() {
+		s.report(expression.Operator.Span, "operator `%s` cannot take `%s` and `%s`", operator, FormatType(lhs), FormatType(rhs))
+	}
+	switch expression.Operator.Type {
+	case frontend.TokenOpAdd:
+		// `+` joins strings
+		if lhs != nil && rhs != nil && lhs.Type() == mir.TypeString && rhs.Type() == mir.TypeString {
+			return &mir.StringType{}
+		}
+		if !isType(lhs, mir.TypeNumber) || !isType(rhs, mir.TypeNumber) {
+			mismatch()
+		}
+		return &mir.NumberType{}
+	case frontend.TokenOpSub, frontend.TokenOpMul, frontend.TokenOpDiv:
+		if !isType(lhs, mir.TypeNumber) || !isType(rhs, mir.TypeNumber) {
+			mismatch()
+		}
+		return &mir.NumberType{}
+	case frontend.TokenOpLes, frontend.TokenOpGes, frontend.TokenOpLte, frontend.TokenOpGte:
+		if !isType(lhs, mir.TypeNumber) || !isType(rhs, mir.TypeNumber) {
+			mismatch()
+		}
+	case frontend.TokenOpEqu, frontend.TokenOpNeq:
+		if !mir.SameType(lhs, rhs) || (lhs != nil && lhs.GetSize() != nil && *lhs.GetSize() != 1) {
+			mismatch()
+		}
+	case frontend.TokenOpAnd, frontend.TokenOpOr:
+		if !isType(lhs, mir.TypeBoolean) || !isType(rhs, mir.TypeBoolean) {
+			mismatch()
+		}
+	}
+	return &mir.BooleanType{}
+}
+
+// a call whose result is used must return a value
+func (s *checker) call(call *frontend.CallExpression, used bool) mir.Type {
+	callee, ok := call.Callee.(*frontend.IdentifierExpression)
+	if !ok {
+		s.expression(call.Callee)
+		s.report(call.Callee.GetSpan(), "only functions can be called")
+		for _, argument := range call.Arguments {
+			s.expression(argument)
+		}
+		return nil
+	}
+	name := callee.Name.Identifier()
+	symbol := s.lookup(name)
+	if symbol == nil {
+		s.report(callee.Name.Span, "function `%s` is not declared", name)
+	} else {
+		s.reference(callee.Name.Span, symbol)
+		if symbol.Kind != FunctionSymbol && symbol.Kind != BuiltinSymbol {
+			s.report(callee.Name.Span, "`%s` is not a function", name)
+			symbol = nil
+		}
+	}
+	for idx, argument := range call.Arguments {
+		argumentType := s.expression(argument)
+		if symbol != nil && idx < len(symbol.Arguments) {
+			s.expectType(argument.GetSpan(), symbol.Arguments[idx].TypeView.Type, argumentType, fmt.Sprintf("argument `%s`", symbol.Arguments[idx].Name))
+		}
+	}
+	if symbol == nil {
+		return nil
+	}
+	if len(call.Arguments) != len(symbol.Arguments) {
+		s.report(call.Span, "`%s` takes %s, found %d", name, span.Pluralize(uint(len(symbol.Arguments)), "argument", "arguments"), len(call.Arguments))
+	}
+	returnsNothing := (symbol.Function != nil && symbol.Function.ReturnType == nil) || (symbol.Builtin != nil && symbol.Builtin.Result == nil)
+	if used && returnsNothing {
+		s.report(call.Span, "`%s` returns nothing", name)
+	}
+	return symbol.TypeView.Type
+}
@@ -0,0 +1,45 @@
+package checker
+
+// a Scratch hat block, handled by `when name(arguments) { ... }`
+type Event struct {
+	Name string
+	// the fields of the hat, given as string literals
+	Arguments []EventArgument
+	Opcode    string
+	Doc       string
+}
+
+type EventArgument struct {
+	Name string
+	// the field of the hat block taking the value
+	Field string
+}
+
+var Events = []Event{
+	{Name: "flagClicked", Opcode: "event_whenflagclicked", Doc: "when the green flag is clicked"},
+	{Name: "clicked", Opcode: "event_whenthisspriteclicked", Doc: "when the sprite is clicked"},
+	{Name: "stageClicked", Opcode: "event_whenstageclicked", Doc: "when the stage is clicked"},
+	{Name: "cloned", Opcode: "control_start_as_clone", Doc: "when the sprite starts as a clone"},
+	{Name: "keyPressed", Arguments: []EventArgument{{Name: "key", Field: "KEY_OPTION"}}, Opcode: "event_whenkeypressed", Doc: "when the key is pressed"},
+	{Name: "received", Arguments: []EventArgument{{Name: "message", Field: "BROADCAST_OPTION"}}, Opcode: "event_whenbroadcastreceived", Doc: "when the message is broadcast"},
+	{Name: "backdropSwitched", Arguments: []EventArgument{{Name: "backdrop", Field: "BACKDROP"}}, Opcode: "event_whenbackdropswitchesto", Doc: "when the stage switches to the backdrop"},
+}
+
+func LookupEvent(name string) *Event {
+	for idx := range Events {
+		if Events[idx].Name == name {
+			return &Events[idx]
+		}
+	}
+	return nil
+}
+
+// the event handled by the hat block with the opcode
+func EventOfOpcode(opcode string) *Event {
+	for idx := range Events {
+		if Events[idx].Opcode == opcode {
+			return &Events[idx]
+		}
+	}
+	return nil
+}
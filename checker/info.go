@@ -0,0 +1,71 @@
+package checker
+
+import (
+	"sort"
+
+	"yummy-go.com/m/v2/mir"
+)
+
+// the reference whose name contains the source index
+func (s *Info) ReferenceAt(index uint) *Reference {
+	for idx := range s.References {
+		reference := &s.References[idx]
+		if reference.Span.From.Index <= index && index <= reference.Span.To.Index {
+			return reference
+		}
+	}
+	return nil
+}
+
+// the symbols visible at the source index, inner ones first, builtins last
+func (s *Info) Visible(index uint) []*Symbol {
+	symbols := make([]*Symbol, 0)
+	seen := make(map[string]bool)
+	add := func(symbol *Symbol) {
+		if !seen[symbol.Name] {
+			seen[symbol.Name] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	// scopes are recorded outer first, nested ones later
+	for idx := len(s.Scopes) - 1; idx >= 0; idx -= 1 {
+		scope := s.Scopes[idx]
+		if index < scope.Span.From.Index || index > scope.Span.To.Index {
+			continue
+		}
+		for idx2 := len(scope.Symbols) - 1; idx2 >= 0; idx2 -= 1 {
+			symbol := scope.Symbols[idx2]
+			if symbol.Kind == ArgumentSymbol || symbol.Definition.To.Index <= index {
+				add(symbol)
+			}
+		}
+	}
+	names := make([]string, 0, len(s.Globals))
+	for name := range s.Globals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(s.Globals[name])
+	}
+	for idx := range Builtins {
+		add(BuiltinSymbolOf(&Builtins[idx]))
+	}
+	return symbols
+}
+
+// the field symbols of a struct type ordered by offset, nil for other types
+func (s *Info) FieldsOf(theType mir.Type) []*Symbol {
+	structType, ok := theType.(*mir.StructType)
+	if !ok {
+		return nil
+	}
+	fields := make([]*Symbol, 0, len(s.Fields[structType]))
+	for _, field := range s.Fields[structType] {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].TypeView.Offset < fields[j].TypeView.Offset
+	})
+	return fields
+}
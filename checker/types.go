@@ -0,0 +1,122 @@
+package checker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"yummy-go.com/m/v2/frontend"
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/span"
+)
+
+// returns nil when the type is broken, the problem is reported
+func (s *checker) resolveType(typeExpression frontend.TypeExpression) mir.Type {
+	switch typeExpression := typeExpression.(type) {
+	case *frontend.NamedTypeExpression:
+		switch typeExpression.Name.Type {
+		case frontend.TokenTypeNumber:
+			return &mir.NumberType{}
+		case frontend.TokenTypeString:
+			return &mir.StringType{}
+		case frontend.TokenTypeBool:
+			return &mir.BooleanType{}
+		}
+	case *frontend.ArrayTypeExpression:
+		element := s.resolveType(typeExpression.Element)
+		if typeExpression.Length == nil {
+			return &mir.DynArrayType{Inner: element}
+		}
+		length, err := strconv.ParseUint(typeExpression.Length.Span.String(), 10, 32)
+		if err != nil {
+			s.report(typeExpression.Length.Span, "array length `%s` is not a whole number", typeExpression.Length.Span.String())
+			return nil
+		}
+		if element != nil && element.GetSize() == nil {
+			s.report(typeExpression.Element.GetSpan(), "array elements of `%s` have no fixed size", FormatType(element))
+		}
+		return &mir.ArrayType{Inner: element, N: uint(length)}
+	case *frontend.StructTypeExpression:
+		structType := &mir.StructType{Fields: make(map[string]mir.StructField)}
+		symbols := make(map[string]*Symbol)
+		for _, field := range typeExpression.Fields {
+			name := field.Name.Identifier()
+			fieldType := s.resolveType(field.TypeExpression)
+			if _, ok := structType.Fields[name]; ok {
+				s.report(field.Name.Span, "field `%s` is already declared", name)
+				continue
+			}
+			var size uint
+			if fieldType != nil {
+				if fieldSize := fieldType.GetSize(); fieldSize != nil {
+					size = *fieldSize
+				} else {
+					s.report(field.TypeExpression.GetSpan(), "field `%s` of `%s` has no fixed size", name, FormatType(fieldType))
+				}
+			}
+			structType.Fields[name] = mir.StructField{Type: fieldType, Offset: structType.Size}
+			symbol := &Symbol{
+				Name:       name,
+				Kind:       FieldSymbol,
+				Definition: &field.Name.Span,
+				TypeView:   mir.TypeView{Type: fieldType, Offset: structType.Size},
+			}
+			symbols[name] = symbol
+			s.reference(field.Name.Span, symbol)
+			structType.Size += size
+		}
+		s.info.Fields[structType] = symbols
+		return structType
+	}
+	return nil
+}
+
+// the type in the syntax of the sources
+func FormatType(theType mir.Type) string {
+	switch theType := theType.(type) {
+	case nil:
+		return "invalid"
+	case *mir.UntypedType:
+		return "any"
+	case *mir.NumberType:
+		return "number"
+	case *mir.StringType:
+		return "string"
+	case *mir.BooleanType:
+		return "bool"
+	case *mir.ArrayType:
+		return fmt.Sprintf("[%d]%s", theType.N, FormatType(theType.Inner))
+	case *mir.DynArrayType:
+		return "[]" + FormatType(theType.Inner)
+	case *mir.StructType:
+		names := make([]string, 0, len(theType.Fields))
+		for name := range theType.Fields {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return theType.Fields[names[i]].Offset < theType.Fields[names[j]].Offset
+		})
+		fields := make([]string, 0, len(names))
+		for _, name := range names {
+			fields = append(fields, name+" "+FormatType(theType.Fields[name].Type))
+		}
+		if len(fields) == 0 {
+			return "struct {}"
+		}
+		return "struct { " + strings.Join(fields, ", ") + " }"
+	}
+	return "untyped"
+}
+
+// broken and untyped values, like the result of raw blocks, pass
+func isType(theType mir.Type, typeType mir.TypeType) bool {
+	return theType == nil || theType.Type() == mir.Untyped || theType.Type() == typeType
+}
+
+// reports a value of the wrong type, broken values are not reported again
+func (s *checker) expectType(theSpan span.Span, expected, found mir.Type, what string) {
+	if !mir.SameType(expected, found) {
+		s.report(theSpan, "%s: expected `%s`, found `%s`", what, FormatType(expected), FormatType(found))
+	}
+}
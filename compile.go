@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"yummy-go.com/m/v2/checker"
+	"yummy-go.com/m/v2/frontend"
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/mir/opt"
+	"yummy-go.com/m/v2/span"
+)
+
+func parseSource(sourcePath string) (frontend.Program, bool) {
+	sourceCode, err := os.ReadFile(sourcePath)
+	if err != nil {
+		span.ReportNoSpan(span.Error, "%s", err.Error())
+		return frontend.Program{}, false
+	}
+	lexer := frontend.NewLexer(sourcePath, string(sourceCode))
+	parser := frontend.NewParser(lexer)
+	ast, err := parser.ParseProgram()
+	if err != nil {
+		return frontend.Program{}, false
+	}
+	return ast, true
+}
+
+// parses the source and checks its names and types
+func checkSource(sourcePath string) (frontend.Program, bool) {
+	ast, ok := parseSource(sourcePath)
+	if !ok {
+		return frontend.Program{}, false
+	}
+	if _, err := checker.Check(ast); err != nil {
+		return frontend.Program{}, false
+	}
+	return ast, true
+}
+
+// a .mir source is read as the text form of mir, others are compiled
+func loadProgram(sourcePath string) (mir.Program, bool) {
+	program, _, ok := loadSource(sourcePath)
+	return program, ok
+}
+
+// like loadProgram, also returning the checked ast of .yum sources
+func loadSource(sourcePath string) (mir.Program, *frontend.Program, bool) {
+	if filepath.Ext(sourcePath) == ".mir" {
+		program, err := mir.ParseFile(sourcePath)
+		return program, nil, err == nil
+	}
+	ast, ok := checkSource(sourcePath)
+	if !ok {
+		return mir.Program{}, nil, false
+	}
+	program, err := mir.GenerateMir(ast)
+	if err != nil {
+		span.ReportNoSpan(span.Error, "%s: %s", sourcePath, err.Error())
+		return mir.Program{}, nil, false
+	}
+	return program, &ast, true
+}
+
+func reportSummary(sourcePath string) bool {
+	errorCount := span.GetStats(span.Error)
+	if errorCount == 0 {
+		return true
+	}
+	span.ReportNoSpan(span.Error, "%s: %s generated", sourcePath, span.Pluralize(errorCount, "error", "errors"))
+	return false
+}
+
+type optimizationFlags struct {
+	level    *string
+	dumpPass *bool
+}
+
+func addOptimizationFlags(flags *flag.FlagSet) optimizationFlags {
+	return optimizationFlags{
+		level:    flags.String("O", "1", "optimization level: 0, 1 or 2"),
+		dumpPass: flags.Bool("dump-passes", false, "print the mir after each optimization pass"),
+	}
+}
+
+var shortLevelFlag = regexp.MustCompile(`^--?O([0-9])$`)
+
+// the flag package wants `-O 2` or `-O=2`, so `-O2` is rewritten
+func expandOptimizationFlags(args []string) []string {
+	expanded := make([]string, 0, len(args))
+	for _, arg := range args {
+		if match := shortLevelFlag.FindStringSubmatch(arg); match != nil {
+			arg = "-O=" + match[1]
+		}
+		expanded = append(expanded, arg)
+	}
+	return expanded
+}
+
+func optimize(program *mir.Program, flags optimizationFlags) bool {
+	level, err := opt.ParseLevel(*flags.level)
+	if err != nil {
+		span.ReportNoSpan(span.Error, "%s", err.Error())
+		return false
+	}
+	pipeline := opt.New(level)
+	if *flags.dumpPass {
+		pipeline.Dump = os.Stderr
+	}
+	pipeline.Run(program)
+	return true
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"yummy-go.com/m/v2/decompiler"
+	"yummy-go.com/m/v2/scir"
+	"yummy-go.com/m/v2/span"
+)
+
+func runDecompile(args []string) int {
+	flags := flag.NewFlagSet("decompile", flag.ExitOnError)
+	outputDir := flags.String("o", "", "the directory of the sources, named after the project by default")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: yummy decompile [-o directory] <project.sb3>")
+		return 2
+	}
+	projectPath := flags.Arg(0)
+	if *outputDir == "" {
+		*outputDir = projectPath[:len(projectPath)-len(filepath.Ext(projectPath))]
+	}
+
+	span.ResetStats()
+	project, err := scir.LoadSb3(projectPath, nil)
+	if err != nil {
+		span.ReportNoSpan(span.Error, "%s", err.Error())
+		return 1
+	}
+	sources, err := decompiler.Decompile(&project)
+	if err != nil {
+		span.ReportNoSpan(span.Error, "%s", err.Error())
+		return 1
+	}
+	status := 0
+	for _, source := range sources {
+		for path, content := range source.Assets {
+			if content == nil {
+				span.ReportNoSpan(span.Warn, "target `%s`: `%s` is missing from the project", source.Target, filepath.Base(path))
+				continue
+			}
+			if err := writeOutput(filepath.Join(*outputDir, filepath.FromSlash(path)), content); err != nil {
+				span.ReportNoSpan(span.Error, "%s", err.Error())
+				status = 1
+			}
+		}
+		if err := writeOutput(filepath.Join(*outputDir, source.Name), []byte(source.Code)); err != nil {
+			span.ReportNoSpan(span.Error, "%s", err.Error())
+			status = 1
+		}
+	}
+	return status
+}
+
+func writeOutput(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
+}
@@ -0,0 +1,464 @@
+package decompiler
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"yummy-go.com/m/v2/checker"
+	"yummy-go.com/m/v2/frontend"
+	"yummy-go.com/m/v2/scir"
+	"yummy-go.com/m/v2/span"
+)
+
+// the directory of the assets, relative to the sources
+const AssetDir = "assets"
+
+// the .yum source of a target
+type Source struct {
+	Target string
+	// the file name of the source
+	Name string
+	Code string
+	// the contents of the assets it declares, by their path
+	Assets map[string][]byte
+}
+
+// a custom block, called like a function
+type procedure struct {
+	name          string
+	argumentIds   []string
+	argumentNames []string
+	argumentTypes []string
+}
+
+type decompiler struct {
+	project    *scir.Scir
+	target     *scir.Target
+	procedures map[string]*procedure
+	// the procedure whose definition is being lifted
+	defining *procedure
+	// the comments attached to blocks, by block id
+	comments map[string]string
+	// the variables of the stage used by a sprite
+	stageVariables map[string]bool
+	builder        *strings.Builder
+	indent         int
+}
+
+// lifts the scripts of every target into a formatted source
+func Decompile(project *scir.Scir) ([]Source, error) {
+	sources := make([]Source, 0, len(project.Ir.Targets))
+	fileNames := make(map[string]bool)
+	for idx := range project.Ir.Targets {
+		target := &project.Ir.Targets[idx]
+		source, err := decompileTarget(project, target)
+		if err != nil {
+			return nil, err
+		}
+		source.Name = uniqueName(fileName(target.Name), fileNames) + ".yum"
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// keeps file names portable
+func fileName(name string) string {
+	name = strings.Map(func(char rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, char) || char < ' ' {
+			return '_'
+		}
+		return char
+	}, name)
+	if strings.Trim(name, ". ") == "" {
+		return "target"
+	}
+	return name
+}
+
+func decompileTarget(project *scir.Scir, target *scir.Target) (Source, error) {
+	s := decompiler{
+		project:        project,
+		target:         target,
+		procedures:     make(map[string]*procedure),
+		comments:       make(map[string]string),
+		stageVariables: make(map[string]bool),
+		builder:        &strings.Builder{},
+	}
+	for _, comment := range target.Comments {
+		if comment.BlockId != nil {
+			s.comments[*comment.BlockId] = comment.Text
+		}
+	}
+	source := Source{Target: target.Name, Assets: make(map[string][]byte)}
+	s.variables()
+	s.assets(source.Assets)
+
+	scripts := s.scripts()
+	s.declareProcedures(scripts)
+	loose := make([]string, 0)
+	for _, id := range scripts {
+		block := target.Blocks[id]
+		switch {
+		case block.Opcode == "procedures_definition":
+			s.procedureDefinition(id, block)
+		case isHat(block.Opcode):
+			s.event(id, block)
+		default:
+			loose = append(loose, id)
+		}
+	}
+	for _, id := range loose {
+		s.looseScript(id)
+	}
+
+	// the variables of the stage it uses are declared too, so the source
+	// checks on its own
+	header := "target " + identifier(target.Name) + "\n"
+	if len(s.stageVariables) > 0 {
+		header += "\n// variables of the stage\n"
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.stageVariables)) {
+		value := "0"
+		if variable := s.stageVariable(name); variable != nil {
+			value = valueLiteral(variable.Value)
+		}
+		header += "var " + identifier(name) + " = " + value + "\n"
+	}
+
+	// the source is parsed back, so whatever is lifted stays valid
+	path := fileName(target.Name) + ".yum"
+	parser := frontend.NewParser(frontend.NewLexer(path, header+s.builder.String()))
+	program, err := parser.ParseProgram()
+	if err != nil {
+		return Source{}, fmt.Errorf("target `%s`: the lifted source does not parse: %s", target.Name, err.Error())
+	}
+	source.Code = frontend.Format(program)
+	return source, nil
+}
+
+func (s *decompiler) line(text string) {
+	s.builder.WriteString(strings.Repeat("\t", s.indent))
+	s.builder.WriteString(text)
+	s.builder.WriteByte('\n')
+}
+
+func (s *decompiler) blankLine() {
+	s.builder.WriteByte('\n')
+}
+
+// a comment of several lines
+func (s *decompiler) comment(text string) {
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		s.line(strings.TrimRight("// "+line, " "))
+	}
+}
+
+// the values of variables are kept as numbers when they look like one
+func (s *decompiler) variables() {
+	if len(s.target.Variables) > 0 {
+		s.blankLine()
+	}
+	for _, variable := range sortedValues(s.target.Variables, func(variable scir.Variable) string { return variable.Name }) {
+		if variable.IsCloud {
+			s.line("// a cloud variable")
+		}
+		s.line("var " + identifier(variable.Name) + " = " + valueLiteral(variable.Value))
+	}
+	if len(s.target.Lists) > 0 {
+		s.blankLine()
+	}
+	for _, list := range sortedValues(s.target.Lists, func(list scir.List) string { return list.Name }) {
+		if len(list.Value) > 0 {
+			items := make([]string, 0, len(list.Value))
+			for _, item := range list.Value {
+				items = append(items, quote(item))
+			}
+			s.comment(fmt.Sprintf("starts with %s: %s", span.Pluralize(uint(len(items)), "item", "items"), strings.Join(items, ", ")))
+		}
+		s.line("var " + identifier(list.Name) + " []string")
+	}
+}
+
+func sortedValues[T any](values map[string]T, key func(T) string) []T {
+	sorted := make([]T, 0, len(values))
+	for _, value := range values {
+		sorted = append(sorted, value)
+	}
+	slices.SortFunc(sorted, func(lhs, rhs T) int {
+		return cmp.Compare(key(lhs), key(rhs))
+	})
+	return sorted
+}
+
+// the assets are kept under their md5 names, the declarations name them
+func (s *decompiler) assets(assets map[string][]byte) {
+	if len(s.target.Costumes)+len(s.target.Sounds) > 0 {
+		s.blankLine()
+	}
+	for _, costume := range s.target.Costumes {
+		path := AssetDir + "/" + costume.Md5ext
+		assets[path] = s.project.Assets[costume.Md5ext]
+		s.line(fmt.Sprintf("costume %s from %s center(%s, %s)", quote(costume.Name), quote(path), numberLiteral(costume.RotationCenterX), numberLiteral(costume.RotationCenterY)))
+	}
+	for _, sound := range s.target.Sounds {
+		path := AssetDir + "/" + sound.Md5ext
+		assets[path] = s.project.Assets[sound.Md5ext]
+		s.line(fmt.Sprintf("sound %s from %s", quote(sound.Name), quote(path)))
+	}
+}
+
+// the first blocks of the scripts, from the top left of the code area
+func (s *decompiler) scripts() []string {
+	scripts := make([]string, 0)
+	for id, block := range s.target.Blocks {
+		if block.TopLevel && !block.Shadow {
+			scripts = append(scripts, id)
+		}
+	}
+	position := func(value *float64) float64 {
+		if value == nil {
+			return 0
+		}
+		return *value
+	}
+	slices.SortFunc(scripts, func(lhs, rhs string) int {
+		lhsBlock, rhsBlock := s.target.Blocks[lhs], s.target.Blocks[rhs]
+		return cmp.Or(
+			cmp.Compare(position(lhsBlock.Y), position(rhsBlock.Y)),
+			cmp.Compare(position(lhsBlock.X), position(rhsBlock.X)),
+			cmp.Compare(lhs, rhs),
+		)
+	})
+	return scripts
+}
+
+// hats of extensions are named like `videoSensing_whenMotionGreaterThan`
+func isHat(opcode string) bool {
+	return checker.EventOfOpcode(opcode) != nil || strings.Contains(opcode, "_when")
+}
+
+// names the custom blocks before any call to them is lifted
+func (s *decompiler) declareProcedures(scripts []string) {
+	taken := reservedNames()
+	for _, event := range checker.Events {
+		taken[event.Name] = true
+	}
+	for _, variable := range s.target.Variables {
+		taken[variable.Name] = true
+	}
+	for _, list := range s.target.Lists {
+		taken[list.Name] = true
+	}
+	if !s.target.IsStage {
+		for _, variable := range s.project.StageTarget.Variables {
+			taken[variable.Name] = true
+		}
+	}
+	for _, id := range scripts {
+		prototype := s.prototype(s.target.Blocks[id])
+		if prototype == nil || prototype.Mutation.ProcCode == nil {
+			continue
+		}
+		procCode := *prototype.Mutation.ProcCode
+		if _, ok := s.procedures[procCode]; ok {
+			continue
+		}
+		theProcedure := &procedure{
+			name:          uniqueName(procedureName(procCode), taken),
+			argumentIds:   jsonStrings(prototype.Mutation.ArgumentIds),
+			argumentNames: jsonStrings(prototype.Mutation.ArgumentNames),
+			argumentTypes: procedureArgumentTypes(procCode),
+		}
+		s.procedures[procCode] = theProcedure
+	}
+	s.textArguments()
+}
+
+// a `%s` argument becomes a string when text that is not a number is
+// passed to it or compared with it
+func (s *decompiler) textArguments() {
+	for id, block := range s.target.Blocks {
+		if block.Opcode == "procedures_call" && block.Mutation != nil && block.Mutation.ProcCode != nil {
+			theProcedure, ok := s.procedures[*block.Mutation.ProcCode]
+			if !ok {
+				continue
+			}
+			for idx, argumentId := range theProcedure.argumentIds {
+				if s.isText(block, argumentId) {
+					theProcedure.text(idx)
+				}
+			}
+			continue
+		}
+		if _, ok := comparisons[block.Opcode]; !ok {
+			continue
+		}
+		for _, operands := range [][2]string{{"OPERAND1", "OPERAND2"}, {"OPERAND2", "OPERAND1"}} {
+			argument := s.inputBlock(block, operands[0])
+			if argument == nil || argument.Opcode != "argument_reporter_string_number" || !s.isText(block, operands[1]) {
+				continue
+			}
+			if theProcedure := s.definitionOf(id); theProcedure != nil {
+				theProcedure.text(slices.Index(theProcedure.argumentNames, argument.Fields["VALUE"].Value))
+			}
+		}
+	}
+}
+
+// whether the input holds text that Scratch does not read as a number
+func (s *decompiler) isText(block *scir.Block, name string) bool {
+	input, ok := block.Inputs[name]
+	if !ok {
+		return false
+	}
+	for _, value := range []scir.Input{input.ObscuredInput, input.ShadowedInput} {
+		switch value := value.(type) {
+		case *scir.BlockInput:
+			if inner, ok := s.target.Blocks[string(*value)]; ok {
+				switch inner.Opcode {
+				case "operator_join":
+					return true
+				case "data_variable":
+					return s.isTextVariable(inner.Fields["VARIABLE"].Value)
+				}
+				return false
+			}
+		case *scir.StringInput:
+			return strings.HasPrefix(valueLiteral(value.Value), `"`)
+		case *scir.VariableOrListInput:
+			return value.Type == scir.InputVariable && s.isTextVariable(value.Value)
+		}
+	}
+	return false
+}
+
+// variables starting with text are declared as strings
+func (s *decompiler) isTextVariable(name string) bool {
+	for _, variable := range s.target.Variables {
+		if variable.Name == name {
+			return strings.HasPrefix(valueLiteral(variable.Value), `"`)
+		}
+	}
+	if variable := s.stageVariable(name); variable != nil {
+		return strings.HasPrefix(valueLiteral(variable.Value), `"`)
+	}
+	return false
+}
+
+func (s *decompiler) stageVariable(name string) *scir.Variable {
+	for _, variable := range s.project.StageTarget.Variables {
+		if variable.Name == name {
+			return &variable
+		}
+	}
+	return nil
+}
+
+// the custom block whose definition holds the block, if any
+func (s *decompiler) definitionOf(id string) *procedure {
+	block := s.target.Blocks[id]
+	for block != nil && block.Parent != nil {
+		block = s.target.Blocks[*block.Parent]
+	}
+	if block == nil {
+		return nil
+	}
+	prototype := s.prototype(block)
+	if prototype == nil || prototype.Mutation.ProcCode == nil {
+		return nil
+	}
+	return s.procedures[*prototype.Mutation.ProcCode]
+}
+
+// types the argument at the index as a string, `%b` arguments stay booleans
+func (s *procedure) text(idx int) {
+	if idx >= 0 && idx < len(s.argumentTypes) && s.argumentTypes[idx] == "number" {
+		s.argumentTypes[idx] = "string"
+	}
+}
+
+// the prototype of a definition, nil for other blocks
+func (s *decompiler) prototype(block *scir.Block) *scir.Block {
+	if block.Opcode != "procedures_definition" {
+		return nil
+	}
+	prototype := s.inputBlock(block, "custom_block")
+	if prototype == nil || prototype.Mutation == nil {
+		return nil
+	}
+	return prototype
+}
+
+func (s *decompiler) procedureDefinition(id string, block *scir.Block) {
+	prototype := s.prototype(block)
+	if prototype == nil || prototype.Mutation.ProcCode == nil {
+		s.looseScript(id)
+		return
+	}
+	theProcedure := s.procedures[*prototype.Mutation.ProcCode]
+	s.blankLine()
+	if text, ok := s.comments[id]; ok {
+		s.comment(text)
+	}
+	s.comment("custom block `" + *prototype.Mutation.ProcCode + "`")
+	if warp := prototype.Mutation.Warp; warp != nil && *warp == "true" {
+		s.line("// runs without screen refresh")
+	}
+	arguments := make([]string, 0, len(theProcedure.argumentNames))
+	for idx, name := range theProcedure.argumentNames {
+		argumentType := "number"
+		if idx < len(theProcedure.argumentTypes) {
+			argumentType = theProcedure.argumentTypes[idx]
+		}
+		arguments = append(arguments, identifier(name)+" "+argumentType)
+	}
+	s.line("func " + identifier(theProcedure.name) + "(" + strings.Join(arguments, ", ") + ") {")
+	s.defining = theProcedure
+	s.body(block.Next)
+	s.defining = nil
+	s.line("}")
+}
+
+// known hats become events, the others raw events
+func (s *decompiler) event(id string, block *scir.Block) {
+	s.blankLine()
+	if text, ok := s.comments[id]; ok {
+		s.comment(text)
+	}
+	header := "when "
+	if event := checker.EventOfOpcode(block.Opcode); event != nil {
+		header += event.Name
+		if len(event.Arguments) > 0 {
+			arguments := make([]string, 0, len(event.Arguments))
+			for _, argument := range event.Arguments {
+				arguments = append(arguments, quote(block.Fields[argument.Field].Value))
+			}
+			header += "(" + strings.Join(arguments, ", ") + ")"
+		}
+	} else {
+		header += s.raw(block).text
+	}
+	s.line(header + " {")
+	s.body(block.Next)
+	s.line("}")
+}
+
+// scripts without a hat never run, they are kept as comments
+func (s *decompiler) looseScript(id string) {
+	outer := s.builder
+	outerIndent := s.indent
+	s.builder = &strings.Builder{}
+	s.indent = 0
+	s.stack(&id)
+	lifted := strings.TrimSuffix(s.builder.String(), "\n")
+	s.builder = outer
+	s.indent = outerIndent
+	s.blankLine()
+	s.line("// a script without a hat:")
+	for _, line := range strings.Split(lifted, "\n") {
+		s.line("// " + strings.ReplaceAll(line, "\t", "  "))
+	}
+}
@@ -0,0 +1,148 @@
+package decompiler_test
+
+import (
+	"strings"
+	"testing"
+
+	"yummy-go.com/m/v2/checker"
+	"yummy-go.com/m/v2/decompiler"
+	"yummy-go.com/m/v2/frontend"
+	"yummy-go.com/m/v2/scir"
+)
+
+// a project whose scripts are added by the tests
+type project struct {
+	scir.Scir
+}
+
+func newProject() *project {
+	return &project{scir.NewProject()}
+}
+
+func (s *project) block(opcode string, inputs map[string]scir.MaybeShadowedInput, fields map[string]scir.Field) string {
+	if inputs == nil {
+		inputs = make(map[string]scir.MaybeShadowedInput)
+	}
+	if fields == nil {
+		fields = make(map[string]scir.Field)
+	}
+	return s.InsertBlock(&scir.Block{Opcode: opcode, Inputs: inputs, Fields: fields})
+}
+
+func (s *project) hat(opcode string, y float64) string {
+	theBlock := s.block(opcode, nil, nil)
+	x := 0.0
+	s.EditingTarget.Blocks[theBlock].TopLevel = true
+	s.EditingTarget.Blocks[theBlock].X = &x
+	s.EditingTarget.Blocks[theBlock].Y = &y
+	return theBlock
+}
+
+func text(value string) scir.MaybeShadowedInput {
+	return scir.MaybeShadowedInput{
+		Type:          scir.Shadow,
+		ShadowedInput: &scir.StringInput{Type: scir.InputString, Value: value},
+	}
+}
+
+func (s *project) stack(blocks ...string) {
+	for idx := 0; idx+1 < len(blocks); idx += 1 {
+		s.ConnectBlocks(blocks[idx], blocks[idx+1])
+	}
+}
+
+// `procCode` with a single `%s` argument named `name`
+func (s *project) definition(procCode, name string, y float64) string {
+	ids, names := `["`+name+`Id"]`, `["`+name+`"]`
+	definition := s.hat("procedures_definition", y)
+	prototype := s.block("procedures_prototype", nil, nil)
+	s.EditingTarget.Blocks[prototype].Shadow = true
+	s.EditingTarget.Blocks[prototype].Mutation = &scir.Mutation{
+		TagName:       "mutation",
+		Children:      make([]any, 0),
+		ProcCode:      &procCode,
+		ArgumentIds:   &ids,
+		ArgumentNames: &names,
+	}
+	s.LinkShadowInput(definition, "custom_block", prototype)
+	return definition
+}
+
+func (s *project) call(procCode, name string, argument string) string {
+	ids := `["` + name + `Id"]`
+	call := s.block("procedures_call", map[string]scir.MaybeShadowedInput{name + "Id": text(argument)}, nil)
+	s.EditingTarget.Blocks[call].Mutation = &scir.Mutation{
+		TagName:     "mutation",
+		Children:    make([]any, 0),
+		ProcCode:    &procCode,
+		ArgumentIds: &ids,
+	}
+	return call
+}
+
+func (s *project) argument(name string) string {
+	return s.block("argument_reporter_string_number", nil, map[string]scir.Field{"VALUE": {Value: name}})
+}
+
+// the lifted sources declare what they use and pass the checker
+func TestDecompiledSourcesCheck(t *testing.T) {
+	theProject := newProject()
+	scoreId := "scoreId"
+	theProject.StageTarget.Variables[scoreId] = scir.Variable{Name: "score", Value: "0"}
+	theProject.SetEditingTarget("Cat")
+
+	// `jump %s` compares its argument with a number, `greet %s` with text
+	jump := theProject.definition("jump %s", "height", 200)
+	condition := theProject.block("operator_gt", nil, nil)
+	theProject.LinkInput(condition, "OPERAND1", theProject.block("motion_yposition", nil, nil))
+	theProject.LinkInput(condition, "OPERAND2", theProject.argument("height"))
+	jumped := theProject.block("control_if", nil, nil)
+	theProject.LinkInput(jumped, "CONDITION", condition)
+	reset := theProject.block("data_setvariableto", map[string]scir.MaybeShadowedInput{"VALUE": text("0")}, map[string]scir.Field{
+		"VARIABLE": {Value: "score", Id: &scoreId},
+	})
+	theProject.LinkInput(jumped, "SUBSTACK", reset)
+	theProject.stack(jump, jumped)
+
+	greet := theProject.definition("greet %s", "name", 400)
+	equals := theProject.block("operator_equals", map[string]scir.MaybeShadowedInput{"OPERAND2": text("world")}, nil)
+	theProject.LinkInput(equals, "OPERAND1", theProject.argument("name"))
+	greeted := theProject.block("control_if", nil, nil)
+	theProject.LinkInput(greeted, "CONDITION", equals)
+	say := theProject.block("looks_say", nil, nil)
+	theProject.LinkInput(say, "MESSAGE", theProject.argument("name"))
+	theProject.LinkInput(greeted, "SUBSTACK", say)
+	theProject.stack(greet, greeted)
+
+	flag := theProject.hat("event_whenflagclicked", 0)
+	change := theProject.block("data_changevariableby", map[string]scir.MaybeShadowedInput{"VALUE": text("1")}, map[string]scir.Field{
+		"VARIABLE": {Value: "score", Id: &scoreId},
+	})
+	theProject.stack(flag, change, theProject.call("jump %s", "height", "10"), theProject.call("greet %s", "name", "world"))
+
+	if err := scir.Validate(&theProject.Scir); err != nil {
+		t.Fatal(err)
+	}
+	sources, err := decompiler.Decompile(&theProject.Scir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range sources {
+		parser := frontend.NewParser(frontend.NewLexer(source.Name, source.Code))
+		program, err := parser.ParseProgram()
+		if err != nil {
+			t.Fatalf("%s: %s\n%s", source.Name, err, source.Code)
+		}
+		if _, err := checker.Check(program); err != nil {
+			t.Errorf("%s: %s\n%s", source.Name, err, source.Code)
+		}
+		if source.Target != "Cat" {
+			continue
+		}
+		for _, expected := range []string{"var score = 0", "func jump(height number)", "func greet(name string)", "jump(10)"} {
+			if !strings.Contains(source.Code, expected) {
+				t.Errorf("%s: expected `%s` in\n%s", source.Name, expected, source.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package decompiler
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"yummy-go.com/m/v2/checker"
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/scir"
+)
+
+// what a lifted value is known to be
+type valueKind uint
+
+const (
+	kindUnknown valueKind = iota
+	kindNumber
+	kindString
+	kindBool
+)
+
+var argumentKinds = map[string]valueKind{
+	"number": kindNumber,
+	"string": kindString,
+	"bool":   kindBool,
+}
+
+func kindOf(theType mir.Type) valueKind {
+	switch theType.(type) {
+	case *mir.NumberType:
+		return kindNumber
+	case *mir.StringType:
+		return kindString
+	case *mir.BooleanType:
+		return kindBool
+	}
+	return kindUnknown
+}
+
+// the binding of the operators, as the parser reads them
+const (
+	precedenceOr = iota + 1
+	precedenceAnd
+	precedenceCompare
+	precedenceAdd
+	precedenceMultiply
+	precedenceUnary
+	precedencePrimary
+)
+
+var binaryPrecedences = map[string]int{
+	"||": precedenceOr,
+	"&&": precedenceAnd,
+	"==": precedenceCompare,
+	"<":  precedenceCompare,
+	">":  precedenceCompare,
+	"+":  precedenceAdd,
+	"-":  precedenceAdd,
+	"*":  precedenceMultiply,
+	"/":  precedenceMultiply,
+}
+
+type expression struct {
+	text       string
+	precedence int
+	kind       valueKind
+}
+
+func parenthesize(value *expression) string {
+	return "(" + value.text + ")"
+}
+
+// operators are left associative, so a right operand binding as loose
+// as the operator is parenthesized
+func binary(operator string, lhs, rhs *expression, kind valueKind) *expression {
+	precedence := binaryPrecedences[operator]
+	lhsText, rhsText := lhs.text, rhs.text
+	if lhs.precedence < precedence {
+		lhsText = parenthesize(lhs)
+	}
+	if rhs.precedence <= precedence {
+		rhsText = parenthesize(rhs)
+	}
+	return &expression{
+		text:       lhsText + " " + operator + " " + rhsText,
+		precedence: precedence,
+		kind:       kind,
+	}
+}
+
+func unary(operator string, value *expression) *expression {
+	text := value.text
+	if value.precedence < precedenceUnary {
+		text = parenthesize(value)
+	}
+	return &expression{text: operator + text, precedence: precedenceUnary, kind: value.kind}
+}
+
+func literal(text string, kind valueKind) *expression {
+	return &expression{text: text, precedence: precedencePrimary, kind: kind}
+}
+
+// Scratch compares and stores text that looks like a number as a number
+func numeric(value *expression) *expression {
+	if value.kind != kindString || !strings.HasPrefix(value.text, `"`) {
+		return value
+	}
+	text, err := strconv.Unquote(value.text)
+	if err != nil {
+		return value
+	}
+	if number := valueLiteral(text); !strings.HasPrefix(number, `"`) {
+		return literal(number, kindNumber)
+	}
+	return value
+}
+
+func isStringValue(value *expression) bool {
+	isLiteral := value.precedence == precedencePrimary && strings.HasPrefix(value.text, `"`)
+	return value.kind == kindString && !isLiteral
+}
+
+// the initial value of a variable
+func valueLiteral(value string) string {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || strings.ContainsAny(value, "xX_") {
+		return quote(value)
+	}
+	return numberLiteral(number)
+}
+
+// the id of the block in the input, nil for empty inputs and values
+func (s *decompiler) inputBlockId(block *scir.Block, name string) *string {
+	input, ok := block.Inputs[name]
+	if !ok {
+		return nil
+	}
+	for _, value := range []scir.Input{input.ObscuredInput, input.ShadowedInput} {
+		if blockInput, ok := value.(*scir.BlockInput); ok {
+			if _, ok := s.target.Blocks[string(*blockInput)]; ok {
+				id := string(*blockInput)
+				return &id
+			}
+		}
+	}
+	return nil
+}
+
+func (s *decompiler) inputBlock(block *scir.Block, name string) *scir.Block {
+	id := s.inputBlockId(block, name)
+	if id == nil {
+		return nil
+	}
+	return s.target.Blocks[*id]
+}
+
+// the value in the input, a block covering a shadow wins over it
+func (s *decompiler) input(block *scir.Block, name string) *expression {
+	input, ok := block.Inputs[name]
+	if !ok {
+		return nil
+	}
+	for _, value := range []scir.Input{input.ObscuredInput, input.ShadowedInput} {
+		if theExpression := s.inputValue(value); theExpression != nil {
+			return theExpression
+		}
+	}
+	return nil
+}
+
+func (s *decompiler) inputValue(value scir.Input) *expression {
+	switch value := value.(type) {
+	case *scir.BlockInput:
+		if block, ok := s.target.Blocks[string(*value)]; ok {
+			return s.reporter(block)
+		}
+	case *scir.NumberalInput:
+		return literal(numberLiteral(value.Value), kindNumber)
+	case *scir.StringInput:
+		return literal(quote(value.Value), kindString)
+	case *scir.BroadcastInput:
+		return literal(quote(value.Value), kindString)
+	case *scir.VariableOrListInput:
+		if value.Type == scir.InputList {
+			return literal("raw data_listcontents(LIST = "+quote(value.Value)+")", kindString)
+		}
+		return s.variable(value.Value)
+	}
+	return nil
+}
+
+// variables of the stage are noted, they are declared in its source
+func (s *decompiler) variable(name string) *expression {
+	local := s.target.IsStage
+	for _, variable := range s.target.Variables {
+		local = local || variable.Name == name
+	}
+	if !local {
+		s.stageVariables[name] = true
+	}
+	return literal(identifier(name), kindUnknown)
+}
+
+// the option of a menu shadow in the input, as a string
+func (s *decompiler) menu(block *scir.Block, name string) *expression {
+	menu := s.inputBlock(block, name)
+	if menu == nil || !menu.Shadow || len(menu.Inputs) != 0 || len(menu.Fields) != 1 {
+		return nil
+	}
+	for _, field := range menu.Fields {
+		return literal(quote(field.Value), kindString)
+	}
+	return nil
+}
+
+// the operand in the input, or the value Scratch takes for an empty one
+func (s *decompiler) operand(block *scir.Block, name string, empty *expression) *expression {
+	if operand := s.input(block, name); operand != nil {
+		return operand
+	}
+	return empty
+}
+
+var (
+	arithmetics = map[string]string{
+		"operator_add":      "+",
+		"operator_subtract": "-",
+		"operator_multiply": "*",
+		"operator_divide":   "/",
+	}
+	comparisons = map[string]string{
+		"operator_lt":     "<",
+		"operator_gt":     ">",
+		"operator_equals": "==",
+	}
+	logicals = map[string]string{
+		"operator_and": "&&",
+		"operator_or":  "||",
+	}
+)
+
+func (s *decompiler) reporter(block *scir.Block) *expression {
+	zero, empty, no := literal("0", kindNumber), literal(`""`, kindString), literal("false", kindBool)
+	if operator, ok := arithmetics[block.Opcode]; ok {
+		return binary(operator, s.operand(block, "NUM1", zero), s.operand(block, "NUM2", zero), kindNumber)
+	}
+	if operator, ok := comparisons[block.Opcode]; ok {
+		lhs, rhs := s.operand(block, "OPERAND1", empty), s.operand(block, "OPERAND2", empty)
+		// text compared with a string that is not a literal stays text
+		if !isStringValue(lhs) && !isStringValue(rhs) {
+			lhs, rhs = numeric(lhs), numeric(rhs)
+		}
+		return binary(operator, lhs, rhs, kindBool)
+	}
+	if operator, ok := logicals[block.Opcode]; ok {
+		return binary(operator, s.operand(block, "OPERAND1", no), s.operand(block, "OPERAND2", no), kindBool)
+	}
+	switch block.Opcode {
+	case "operator_not":
+		return unary("!", s.operand(block, "OPERAND", no))
+	case "operator_join":
+		// `+` joins only strings, numbers would be added
+		lhs, rhs := s.operand(block, "STRING1", empty), s.operand(block, "STRING2", empty)
+		if lhs.kind == kindString && rhs.kind == kindString {
+			return binary("+", lhs, rhs, kindString)
+		}
+	case "data_variable":
+		return s.variable(block.Fields["VARIABLE"].Value)
+	case "argument_reporter_string_number", "argument_reporter_boolean":
+		name := block.Fields["VALUE"].Value
+		if s.defining != nil && slices.Contains(s.defining.argumentNames, name) {
+			kind := kindUnknown
+			if idx := slices.Index(s.defining.argumentNames, name); idx < len(s.defining.argumentTypes) {
+				kind = argumentKinds[s.defining.argumentTypes[idx]]
+			}
+			return literal(identifier(name), kind)
+		}
+	case "procedures_call":
+		if call := s.procedureCall(block); call != nil {
+			return call
+		}
+	}
+	if builtin := checker.BuiltinOfOpcode(block.Opcode); builtin != nil && builtin.Result != nil {
+		if call := s.builtinCall(builtin, block); call != nil {
+			return call
+		}
+	}
+	return s.raw(block)
+}
+
+// `raw opcode(INPUT: value, FIELD = "value")`, the inputs and the fields
+// in the order of their names
+func (s *decompiler) raw(block *scir.Block) *expression {
+	arguments := make([]string, 0, len(block.Inputs)+len(block.Fields))
+	inputs := make([]string, 0, len(block.Inputs))
+	for name := range block.Inputs {
+		if !strings.HasPrefix(name, "SUBSTACK") {
+			inputs = append(inputs, name)
+		}
+	}
+	slices.Sort(inputs)
+	for _, name := range inputs {
+		if value := s.input(block, name); value != nil {
+			arguments = append(arguments, identifier(name)+": "+value.text)
+		}
+	}
+	fields := make([]string, 0, len(block.Fields))
+	for name := range block.Fields {
+		fields = append(fields, name)
+	}
+	slices.Sort(fields)
+	for _, name := range fields {
+		arguments = append(arguments, identifier(name)+" = "+quote(block.Fields[name].Value))
+	}
+	return literal("raw "+identifier(block.Opcode)+"("+strings.Join(arguments, ", ")+")", kindUnknown)
+}
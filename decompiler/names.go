@@ -0,0 +1,122 @@
+package decompiler
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"yummy-go.com/m/v2/checker"
+)
+
+var keywords = map[string]bool{
+	"target": true, "func": true, "var": true, "return": true, "if": true,
+	"else": true, "for": true, "struct": true, "costume": true, "sound": true,
+	"from": true, "true": true, "false": true, "number": true, "string": true,
+	"bool": true, "when": true, "raw": true,
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for idx := 0; idx < len(name); idx += 1 {
+		char := name[idx]
+		alpha := ('a' <= char && char <= 'z') || ('A' <= char && char <= 'Z') || char == '_'
+		if !alpha && (idx == 0 || char < '0' || char > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// a name as it is written in source, other names become raw identifiers
+func identifier(name string) string {
+	if isIdentifier(name) && !keywords[name] {
+		return name
+	}
+	return "#" + strconv.Quote(name)
+}
+
+func quote(value string) string {
+	return strconv.Quote(value)
+}
+
+// numbers the lexer cannot read are kept as strings
+func numberLiteral(value float64) string {
+	text := strconv.FormatFloat(value, 'f', -1, 64)
+	if strings.ContainsAny(text, "NI") {
+		return quote(text)
+	}
+	return text
+}
+
+// `jump %s times` becomes `jumpTimes`, names that are not ascii stay as
+// they are in a raw identifier
+func procedureName(procCode string) string {
+	words := make([]string, 0)
+	for _, word := range strings.Fields(procCode) {
+		if len(word) == 2 && word[0] == '%' && strings.ContainsRune("snb", rune(word[1])) {
+			continue
+		}
+		words = append(words, word)
+	}
+	camel := ""
+	for _, word := range words {
+		for _, part := range strings.FieldsFunc(word, func(char rune) bool {
+			return char > unicode.MaxASCII || (!unicode.IsLetter(char) && !unicode.IsDigit(char))
+		}) {
+			if camel == "" {
+				camel = strings.ToLower(part[:1]) + part[1:]
+			} else {
+				camel += strings.ToUpper(part[:1]) + part[1:]
+			}
+		}
+	}
+	if isIdentifier(camel) {
+		return camel
+	}
+	if len(words) > 0 {
+		return strings.Join(words, " ")
+	}
+	return "block"
+}
+
+// the types of the arguments in the proccode, `%b` takes booleans. `%s`
+// takes text and numbers alike, it is a number until text is passed to it
+func procedureArgumentTypes(procCode string) []string {
+	types := make([]string, 0)
+	for idx := 0; idx+1 < len(procCode); idx += 1 {
+		if procCode[idx] != '%' {
+			continue
+		}
+		switch procCode[idx+1] {
+		case 's', 'n':
+			types = append(types, "number")
+		case 'b':
+			types = append(types, "bool")
+		default:
+			continue
+		}
+		idx += 1
+	}
+	return types
+}
+
+// names already taken in every target
+func reservedNames() map[string]bool {
+	names := make(map[string]bool)
+	for _, builtin := range checker.Builtins {
+		names[builtin.Name] = true
+	}
+	return names
+}
+
+// the name itself, or the name with the first free number appended
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for idx := 2; taken[unique]; idx += 1 {
+		unique = name + strconv.Itoa(idx)
+	}
+	taken[unique] = true
+	return unique
+}
@@ -0,0 +1,193 @@
+package decompiler
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"yummy-go.com/m/v2/checker"
+	"yummy-go.com/m/v2/scir"
+)
+
+// the statements of a stack, one level deeper
+func (s *decompiler) body(first *string) {
+	s.indent += 1
+	s.stack(first)
+	s.indent -= 1
+}
+
+func (s *decompiler) stack(first *string) {
+	for id := first; id != nil; {
+		block, ok := s.target.Blocks[*id]
+		if !ok {
+			return
+		}
+		if text, ok := s.comments[*id]; ok {
+			s.comment(text)
+		}
+		s.statement(block)
+		id = block.Next
+	}
+}
+
+func (s *decompiler) statement(block *scir.Block) {
+	switch block.Opcode {
+	case "control_if", "control_if_else":
+		s.line("if " + s.condition(block, "CONDITION").text + " {")
+		s.substack(block, "SUBSTACK")
+		if block.Opcode == "control_if_else" {
+			s.line("} else {")
+			s.substack(block, "SUBSTACK2")
+		}
+		s.line("}")
+		return
+	case "control_forever":
+		s.line("for {")
+		s.substack(block, "SUBSTACK")
+		s.line("}")
+		return
+	case "control_while", "control_repeat_until":
+		condition := s.condition(block, "CONDITION")
+		if block.Opcode == "control_repeat_until" {
+			condition = unary("!", condition)
+		}
+		s.line("for " + condition.text + " {")
+		s.substack(block, "SUBSTACK")
+		s.line("}")
+		return
+	case "control_stop":
+		if block.Fields["STOP_OPTION"].Value == "this script" {
+			s.line("return")
+			return
+		}
+	case "data_setvariableto", "data_changevariableby":
+		variable := s.variable(block.Fields["VARIABLE"].Value)
+		value := s.input(block, "VALUE")
+		if value == nil {
+			value = literal(`""`, kindString)
+		}
+		value = numeric(value)
+		if block.Opcode == "data_changevariableby" {
+			value = binary("+", variable, value, kindNumber)
+		}
+		s.line(variable.text + " = " + value.text)
+		return
+	case "procedures_call":
+		if call := s.procedureCall(block); call != nil {
+			s.line(call.text)
+			return
+		}
+	}
+	if builtin := checker.BuiltinOfOpcode(block.Opcode); builtin != nil && builtin.Result == nil {
+		if call := s.builtinCall(builtin, block); call != nil {
+			s.line(call.text)
+			return
+		}
+	}
+	s.rawStatement(block)
+}
+
+// blocks that are not lifted keep their opcode, inputs and fields, the
+// stacks in their inputs follow them
+func (s *decompiler) rawStatement(block *scir.Block) {
+	substacks := make([]string, 0)
+	for name := range block.Inputs {
+		if strings.HasPrefix(name, "SUBSTACK") && s.inputBlock(block, name) != nil {
+			substacks = append(substacks, name)
+		}
+	}
+	header := s.raw(block).text
+	if len(substacks) == 0 {
+		s.line(header)
+		return
+	}
+	slices.Sort(substacks)
+	for idx, name := range substacks {
+		if idx > 0 {
+			header = "}"
+		}
+		s.line(header + " " + identifier(name) + " {")
+		s.substack(block, name)
+	}
+	s.line("}")
+}
+
+func (s *decompiler) substack(block *scir.Block, name string) {
+	if id := s.inputBlockId(block, name); id != nil {
+		s.body(id)
+	}
+}
+
+// a missing condition is false, as in Scratch
+func (s *decompiler) condition(block *scir.Block, name string) *expression {
+	if condition := s.input(block, name); condition != nil {
+		return condition
+	}
+	return literal("false", kindBool)
+}
+
+// arguments are found by their ids, missing ones get the default values
+// of Scratch
+func (s *decompiler) procedureCall(block *scir.Block) *expression {
+	if block.Mutation == nil || block.Mutation.ProcCode == nil {
+		return nil
+	}
+	theProcedure, ok := s.procedures[*block.Mutation.ProcCode]
+	if !ok {
+		return nil
+	}
+	arguments := make([]string, 0, len(theProcedure.argumentIds))
+	for idx, id := range theProcedure.argumentIds {
+		argumentType := "number"
+		if idx < len(theProcedure.argumentTypes) {
+			argumentType = theProcedure.argumentTypes[idx]
+		}
+		argument := s.input(block, id)
+		switch {
+		case argument != nil && argumentType == "number":
+			arguments = append(arguments, numeric(argument).text)
+		case argument != nil:
+			arguments = append(arguments, argument.text)
+		case argumentType == "bool":
+			arguments = append(arguments, "false")
+		case argumentType == "number":
+			// Scratch reads an empty argument as 0
+			arguments = append(arguments, "0")
+		default:
+			arguments = append(arguments, `""`)
+		}
+	}
+	return &expression{
+		text:       identifier(theProcedure.name) + "(" + strings.Join(arguments, ", ") + ")",
+		precedence: precedencePrimary,
+	}
+}
+
+// menus become strings, blocks missing an input stay raw
+func (s *decompiler) builtinCall(builtin *checker.Builtin, block *scir.Block) *expression {
+	arguments := make([]string, 0, len(builtin.Arguments))
+	for _, argument := range builtin.Arguments {
+		value := s.menu(block, argument.Input)
+		if value == nil {
+			value = s.input(block, argument.Input)
+		}
+		if value == nil {
+			return nil
+		}
+		arguments = append(arguments, value.text)
+	}
+	return &expression{
+		text:       builtin.Name + "(" + strings.Join(arguments, ", ") + ")",
+		precedence: precedencePrimary,
+		kind:       kindOf(builtin.Result),
+	}
+}
+
+// the strings of a JSON array kept in a mutation
+func jsonStrings(value *string) []string {
+	strings := make([]string, 0)
+	if value != nil {
+		json.Unmarshal([]byte(*value), &strings)
+	}
+	return strings
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"yummy-go.com/m/v2/frontend"
+	"yummy-go.com/m/v2/span"
+)
+
+func runDumpAst(args []string) int {
+	flags := flag.NewFlagSet("dump-ast", flag.ExitOnError)
+	asJson := flags.Bool("json", false, "print the tree as JSON instead of text")
+	outputPath := flags.String("o", "", "write the tree to a file instead of stdout")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: yummy dump-ast [-json] [-o output] <source.yum>")
+		return 2
+	}
+	sourcePath := flags.Arg(0)
+
+	span.ResetStats()
+	ast, ok := parseSource(sourcePath)
+	if !ok {
+		reportSummary(sourcePath)
+		return 1
+	}
+	if !*asJson {
+		if *outputPath != "" {
+			fmt.Fprintln(os.Stderr, "yummy dump-ast: -o needs -json, the text form is colored for terminals")
+			return 2
+		}
+		ast.Display(0)
+		return 0
+	}
+	content, err := frontend.MarshalIndentJSON(&ast, "", "  ")
+	if err != nil {
+		span.ReportNoSpan(span.Error, "%s", err.Error())
+		return 1
+	}
+	content = append(content, '\n')
+	if *outputPath == "" {
+		os.Stdout.Write(content)
+		return 0
+	}
+	if err := os.WriteFile(*outputPath, content, 0o644); err != nil {
+		span.ReportNoSpan(span.Error, "%s", err.Error())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// the JSON encoding of the tree is stable, tools read it. the tree of the
+// syntax example is kept in testdata, `go test -update` rewrites it
+func TestDumpAstJson(t *testing.T) {
+	goldenPath := filepath.Join("testdata", "syntax.ast.json")
+	outputPath := filepath.Join(t.TempDir(), "syntax.ast.json")
+	if code := runDumpAst([]string{"-json", "-o", outputPath, filepath.Join("examples", "syntax.yum")}); code != 0 {
+		t.Fatalf("dump-ast exited with %d", code)
+	}
+	actual, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.WriteFile(goldenPath, actual, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != string(expected) {
+		t.Errorf("%s differs, found:\n%s", goldenPath, actual)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/span"
+)
+
+func runDumpMir(args []string) int {
+	flags := flag.NewFlagSet("dump-mir", flag.ExitOnError)
+	outputPath := flags.String("o", "", "write the mir to a file instead of stdout")
+	optimization := addOptimizationFlags(flags)
+	flags.Parse(expandOptimizationFlags(args))
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: yummy dump-mir [-o output.mir] [-O0|-O1|-O2] [-dump-passes] <source.yum|source.mir>")
+		return 2
+	}
+	sourcePath := flags.Arg(0)
+
+	span.ResetStats()
+	program, ok := loadProgram(sourcePath)
+	if !ok {
+		reportSummary(sourcePath)
+		return 1
+	}
+	if !optimize(&program, optimization) {
+		return 1
+	}
+	// the offsets and stack sizes printed are the ones the omitter uses
+	if err := mir.AllocateFrames(&program); err != nil {
+		span.ReportNoSpan(span.Error, "%s: %s", sourcePath, err.Error())
+		return 1
+	}
+	if *outputPath == "" {
+		mir.Fprint(os.Stdout, &program)
+		return 0
+	}
+	if err := os.WriteFile(*outputPath, []byte(mir.Sprint(&program)), 0o644); err != nil {
+		span.ReportNoSpan(span.Error, "%s", err.Error())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"yummy-go.com/m/v2/frontend"
+	"yummy-go.com/m/v2/span"
+)
+
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result to the source files instead of stdout")
+	check := flags.Bool("check", false, "list the files that are not formatted and fail if there are any")
+	flags.Parse(args)
+	if flags.NArg() == 0 || (*write && *check) {
+		fmt.Fprintln(os.Stderr, "usage: yummy fmt [-w | -check] <source.yum | directory>...")
+		return 2
+	}
+	sourcePaths, ok := collectSources(flags.Args())
+	if !ok {
+		return 1
+	}
+
+	span.ResetStats()
+	status := 0
+	for _, sourcePath := range sourcePaths {
+		sourceCode, err := os.ReadFile(sourcePath)
+		if err != nil {
+			span.ReportNoSpan(span.Error, "%s", err.Error())
+			status = 1
+			continue
+		}
+		ast, ok := parseSource(sourcePath)
+		if !ok {
+			status = 1
+			continue
+		}
+		formatted := frontend.Format(ast)
+		switch {
+		case *check:
+			if formatted != string(sourceCode) {
+				fmt.Println(sourcePath)
+				status = 1
+			}
+		case *write:
+			if formatted == string(sourceCode) {
+				continue
+			}
+			if err := os.WriteFile(sourcePath, []byte(formatted), 0o644); err != nil {
+				span.ReportNoSpan(span.Error, "%s", err.Error())
+				status = 1
+			}
+		default:
+			fmt.Print(formatted)
+		}
+	}
+	return status
+}
+
+// the .yum files of the arguments, directories are searched recursively
+func collectSources(args []string) ([]string, bool) {
+	sourcePaths := make([]string, 0)
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			span.ReportNoSpan(span.Error, "%s", err.Error())
+			return nil, false
+		}
+		if !info.IsDir() {
+			sourcePaths = append(sourcePaths, arg)
+			continue
+		}
+		found := make([]string, 0)
+		err = filepath.WalkDir(arg, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && filepath.Ext(path) == ".yum" {
+				found = append(found, path)
+			}
+			return nil
+		})
+		if err != nil {
+			span.ReportNoSpan(span.Error, "%s", err.Error())
+			return nil, false
+		}
+		slices.Sort(found)
+		sourcePaths = append(sourcePaths, found...)
+	}
+	return sourcePaths, true
+}
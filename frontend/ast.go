@@ -5,7 +5,18 @@ import "yummy-go.com/m/v2/span"
 type Program struct {
 	Target       Token
 	Declarations []Declaration
-	Span         span.Span
+	// every line comment of the source, in order
+	Comments []*Token
+	Span     span.Span
+}
+
+// every part of the tree, see Walk
+type Node interface {
+	GetSpan() span.Span
+}
+
+func (s *Program) GetSpan() span.Span {
+	return s.Span
 }
 
 type DeclarationType uint
@@ -13,33 +24,523 @@ type DeclarationType uint
 const (
 	GlobalVariableDeclarationType DeclarationType = iota
 	FunctionDeclarationType
+	CostumeDeclarationType
+	SoundDeclarationType
+	EventDeclarationType
 )
 
 type Declaration interface {
 	Display
 	Type() DeclarationType
+	GetSpan() span.Span
 }
 
-type FunctionDeclaration struct {
+// `var name type = value` outside functions
+type GlobalVariableDeclaration struct {
 	Name Token
-	Body Block
+	// nil when the type comes from the value
+	TypeExpression TypeExpression
+	// nil when the variable starts empty
+	Value Expression
+	Doc   string
+	Span  span.Span
+}
+
+func (s *GlobalVariableDeclaration) Type() DeclarationType {
+	return GlobalVariableDeclarationType
+}
+
+func (s *GlobalVariableDeclaration) GetSpan() span.Span {
+	return s.Span
+}
+
+type FunctionDeclaration struct {
+	// the `inline` before `func`, nil when the function is not marked
+	Inline    *Token
+	Name      Token
+	Arguments []Argument
+	// nil when the function returns nothing
+	ReturnType TypeExpression
+	Body       Block
+	// the comments right above the declaration
+	Doc  string
 	Span span.Span
 }
 
+type Argument struct {
+	Name           Token
+	TypeExpression TypeExpression
+	Span           span.Span
+}
+
+func (s *Argument) GetSpan() span.Span {
+	return s.Span
+}
+
 func (s *FunctionDeclaration) Type() DeclarationType {
 	return FunctionDeclarationType
 }
 
+func (s *FunctionDeclaration) GetSpan() span.Span {
+	return s.Span
+}
+
+type CostumeDeclaration struct {
+	Name   Token
+	Path   Token
+	Center *CostumeCenter
+	Span   span.Span
+}
+
+type CostumeCenter struct {
+	X, Y Token
+	// the leading `-` of negative coordinates, nil otherwise
+	XSign, YSign *Token
+	Span         span.Span
+}
+
+// the coordinates as written, without the space after a sign
+func (s *CostumeCenter) Coordinates() (string, string) {
+	coordinate := func(sign *Token, number Token) string {
+		if sign != nil {
+			return "-" + number.Span.String()
+		}
+		return number.Span.String()
+	}
+	return coordinate(s.XSign, s.X), coordinate(s.YSign, s.Y)
+}
+
+func (s *CostumeCenter) GetSpan() span.Span {
+	return s.Span
+}
+
+func (s *CostumeDeclaration) Type() DeclarationType {
+	return CostumeDeclarationType
+}
+
+func (s *CostumeDeclaration) GetSpan() span.Span {
+	return s.Span
+}
+
+type SoundDeclaration struct {
+	Name Token
+	Path Token
+	Span span.Span
+}
+
+func (s *SoundDeclaration) Type() DeclarationType {
+	return SoundDeclarationType
+}
+
+func (s *SoundDeclaration) GetSpan() span.Span {
+	return s.Span
+}
+
+// `when event(arguments) { }`, a script started by a hat block
+type EventDeclaration struct {
+	Event Token
+	// the arguments are literals, they fill the fields of the hat
+	Arguments []Expression
+	// set instead of the event for hats without a name
+	Raw  *RawExpression
+	Body Block
+	Doc  string
+	Span span.Span
+}
+
+func (s *EventDeclaration) Type() DeclarationType {
+	return EventDeclarationType
+}
+
+func (s *EventDeclaration) GetSpan() span.Span {
+	return s.Span
+}
+
+type TypeExpressionType uint
+
+const (
+	NamedTypeExpressionType TypeExpressionType = iota
+	ArrayTypeExpressionType
+	StructTypeExpressionType
+)
+
+type TypeExpression interface {
+	Display
+	Type() TypeExpressionType
+	GetSpan() span.Span
+}
+
+// `number`, `string` or `bool`
+type NamedTypeExpression struct {
+	Name Token
+}
+
+func (s *NamedTypeExpression) Type() TypeExpressionType {
+	return NamedTypeExpressionType
+}
+
+func (s *NamedTypeExpression) GetSpan() span.Span {
+	return s.Name.Span
+}
+
+// `[N]element`, or `[]element` for dyn-sized arrays
+type ArrayTypeExpression struct {
+	// nil for dyn-sized arrays
+	Length  *Token
+	Element TypeExpression
+	Span    span.Span
+}
+
+func (s *ArrayTypeExpression) Type() TypeExpressionType {
+	return ArrayTypeExpressionType
+}
+
+func (s *ArrayTypeExpression) GetSpan() span.Span {
+	return s.Span
+}
+
+// `struct { name type, ... }`
+type StructTypeExpression struct {
+	Fields []StructField
+	Span   span.Span
+}
+
+type StructField struct {
+	Name           Token
+	TypeExpression TypeExpression
+	Span           span.Span
+}
+
+func (s *StructField) GetSpan() span.Span {
+	return s.Span
+}
+
+func (s *StructTypeExpression) Type() TypeExpressionType {
+	return StructTypeExpressionType
+}
+
+func (s *StructTypeExpression) GetSpan() span.Span {
+	return s.Span
+}
+
 type Block struct {
 	Statements []Statement
 	Span       span.Span
 }
 
+func (s *Block) GetSpan() span.Span {
+	return s.Span
+}
+
 type StatementType uint
 
-const ()
+const (
+	VarStatementType StatementType = iota
+	DeclareAssignStatementType
+	AssignStatementType
+	ReturnStatementType
+	IfStatementType
+	ForStatementType
+	ExpressionStatementType
+	RawStatementType
+)
 
 type Statement interface {
 	Display
 	Type() StatementType
+	GetSpan() span.Span
+}
+
+// `var name type = value` in a function
+type VarStatement struct {
+	Name Token
+	// nil when the type comes from the value
+	TypeExpression TypeExpression
+	// nil when the variable starts empty
+	Value Expression
+	Span  span.Span
+}
+
+func (s *VarStatement) Type() StatementType {
+	return VarStatementType
+}
+
+func (s *VarStatement) GetSpan() span.Span {
+	return s.Span
+}
+
+// `name := value`
+type DeclareAssignStatement struct {
+	Name  Token
+	Value Expression
+	Span  span.Span
+}
+
+func (s *DeclareAssignStatement) Type() StatementType {
+	return DeclareAssignStatementType
+}
+
+func (s *DeclareAssignStatement) GetSpan() span.Span {
+	return s.Span
+}
+
+// `target = value`, the target is a name, a member or an index
+type AssignStatement struct {
+	Target Expression
+	Value  Expression
+	Span   span.Span
+}
+
+func (s *AssignStatement) Type() StatementType {
+	return AssignStatementType
+}
+
+func (s *AssignStatement) GetSpan() span.Span {
+	return s.Span
+}
+
+type ReturnStatement struct {
+	// nil for a bare `return`
+	Value Expression
+	Span  span.Span
+}
+
+func (s *ReturnStatement) Type() StatementType {
+	return ReturnStatementType
+}
+
+func (s *ReturnStatement) GetSpan() span.Span {
+	return s.Span
+}
+
+type IfStatement struct {
+	Condition Expression
+	Then      Block
+	// `else if`, at most one of ElseIf and Else is set
+	ElseIf *IfStatement
+	Else   *Block
+	Span   span.Span
+}
+
+func (s *IfStatement) Type() StatementType {
+	return IfStatementType
+}
+
+func (s *IfStatement) GetSpan() span.Span {
+	return s.Span
+}
+
+// `for condition { }`, or `for { }` to loop forever
+type ForStatement struct {
+	// nil when the loop runs forever
+	Condition Expression
+	Body      Block
+	Span      span.Span
+}
+
+func (s *ForStatement) Type() StatementType {
+	return ForStatementType
+}
+
+func (s *ForStatement) GetSpan() span.Span {
+	return s.Span
+}
+
+// a call whose result is ignored
+type ExpressionStatement struct {
+	Expression Expression
+	Span       span.Span
+}
+
+func (s *ExpressionStatement) Type() StatementType {
+	return ExpressionStatementType
+}
+
+func (s *ExpressionStatement) GetSpan() span.Span {
+	return s.Span
+}
+
+// a raw block with substacks, `raw opcode(arguments) NAME { } NAME { }`
+type RawStatement struct {
+	Block     *RawExpression
+	Substacks []RawSubstack
+	Span      span.Span
+}
+
+type RawSubstack struct {
+	Name Token
+	Body Block
+	Span span.Span
+}
+
+func (s *RawStatement) Type() StatementType {
+	return RawStatementType
+}
+
+func (s *RawStatement) GetSpan() span.Span {
+	return s.Span
+}
+
+func (s *RawSubstack) GetSpan() span.Span {
+	return s.Span
+}
+
+type ExpressionType uint
+
+const (
+	LiteralExpressionType ExpressionType = iota
+	IdentifierExpressionType
+	ParenExpressionType
+	UnaryExpressionType
+	BinaryExpressionType
+	CallExpressionType
+	IndexExpressionType
+	MemberExpressionType
+	RawExpressionType
+)
+
+type Expression interface {
+	Display
+	Type() ExpressionType
+	GetSpan() span.Span
+}
+
+// a number, string, `true` or `false`
+type LiteralExpression struct {
+	Literal Token
+}
+
+func (s *LiteralExpression) Type() ExpressionType {
+	return LiteralExpressionType
+}
+
+func (s *LiteralExpression) GetSpan() span.Span {
+	return s.Literal.Span
+}
+
+type IdentifierExpression struct {
+	Name Token
+}
+
+func (s *IdentifierExpression) Type() ExpressionType {
+	return IdentifierExpressionType
+}
+
+func (s *IdentifierExpression) GetSpan() span.Span {
+	return s.Name.Span
+}
+
+// parentheses written in the source, kept so they can be printed back
+type ParenExpression struct {
+	Value Expression
+	Span  span.Span
+}
+
+func (s *ParenExpression) Type() ExpressionType {
+	return ParenExpressionType
+}
+
+func (s *ParenExpression) GetSpan() span.Span {
+	return s.Span
+}
+
+type UnaryExpression struct {
+	Operator Token
+	Value    Expression
+	Span     span.Span
+}
+
+func (s *UnaryExpression) Type() ExpressionType {
+	return UnaryExpressionType
+}
+
+func (s *UnaryExpression) GetSpan() span.Span {
+	return s.Span
+}
+
+type BinaryExpression struct {
+	Lhs      Expression
+	Operator Token
+	Rhs      Expression
+	Span     span.Span
+}
+
+func (s *BinaryExpression) Type() ExpressionType {
+	return BinaryExpressionType
+}
+
+func (s *BinaryExpression) GetSpan() span.Span {
+	return s.Span
+}
+
+type CallExpression struct {
+	Callee    Expression
+	Arguments []Expression
+	Span      span.Span
+}
+
+func (s *CallExpression) Type() ExpressionType {
+	return CallExpressionType
+}
+
+func (s *CallExpression) GetSpan() span.Span {
+	return s.Span
+}
+
+// `value[index]`
+type IndexExpression struct {
+	Value Expression
+	Index Expression
+	Span  span.Span
+}
+
+func (s *IndexExpression) Type() ExpressionType {
+	return IndexExpressionType
+}
+
+func (s *IndexExpression) GetSpan() span.Span {
+	return s.Span
+}
+
+// `value.member`
+type MemberExpression struct {
+	Value  Expression
+	Member Token
+	Span   span.Span
+}
+
+func (s *MemberExpression) Type() ExpressionType {
+	return MemberExpressionType
+}
+
+func (s *MemberExpression) GetSpan() span.Span {
+	return s.Span
+}
+
+// `raw opcode(INPUT: value, FIELD = "value")`, a Scratch block written out
+// by its opcode, inputs take values and fields take strings
+type RawExpression struct {
+	Opcode    Token
+	Arguments []RawArgument
+	Span      span.Span
+}
+
+type RawArgument struct {
+	Name Token
+	// `NAME = "value"` sets a field, `NAME: value` an input
+	Field bool
+	Value Expression
+	Span  span.Span
+}
+
+func (s *RawExpression) Type() ExpressionType {
+	return RawExpressionType
+}
+
+func (s *RawExpression) GetSpan() span.Span {
+	return s.Span
+}
+
+func (s *RawArgument) GetSpan() span.Span {
+	return s.Span
 }
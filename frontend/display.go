@@ -69,13 +69,233 @@ func (s Program) Display(indent uint) {
 	displayKVList(indent+1, "declarations", s.Declarations)
 }
 
+func displayDoc(indent uint, doc string) {
+	if doc == "" {
+		return
+	}
+	displayIndent(indent)
+	colorKey.Print("doc")
+	fmt.Printf(": %q\n", doc)
+}
+
+// optional children are only displayed when present
+func displayOptional[T Display](indent uint, key string, v T, present bool) {
+	if present {
+		displayKV(indent, key, v)
+	}
+}
+
+func (s GlobalVariableDeclaration) Display(indent uint) {
+	displayTitle("GlobalVariableDeclaration", s.Span)
+	displayKV(indent+1, "name", s.Name)
+	displayDoc(indent+1, s.Doc)
+	displayOptional(indent+1, "type", s.TypeExpression, s.TypeExpression != nil)
+	displayOptional(indent+1, "value", s.Value, s.Value != nil)
+}
+
 func (s FunctionDeclaration) Display(indent uint) {
 	displayTitle("FunctionDeclaration", s.Span)
+	if s.Inline != nil {
+		displayKV(indent+1, "inline", *s.Inline)
+	}
 	displayKV(indent+1, "name", s.Name)
+	displayDoc(indent+1, s.Doc)
+	displayKVList(indent+1, "arguments", s.Arguments)
+	displayOptional(indent+1, "return type", s.ReturnType, s.ReturnType != nil)
 	displayKV(indent+1, "body", s.Body)
 }
 
+func (s Argument) Display(indent uint) {
+	displayTitle("Argument", s.Span)
+	displayKV(indent+1, "name", s.Name)
+	displayKV(indent+1, "type", s.TypeExpression)
+}
+
+func (s NamedTypeExpression) Display(indent uint) {
+	displayTitle("NamedTypeExpression", s.Name.Span)
+	displayKV(indent+1, "name", s.Name)
+}
+
+func (s ArrayTypeExpression) Display(indent uint) {
+	displayTitle("ArrayTypeExpression", s.Span)
+	if s.Length != nil {
+		displayKV(indent+1, "length", *s.Length)
+	}
+	displayKV(indent+1, "element", s.Element)
+}
+
+func (s StructTypeExpression) Display(indent uint) {
+	displayTitle("StructTypeExpression", s.Span)
+	displayKVList(indent+1, "fields", s.Fields)
+}
+
+func (s StructField) Display(indent uint) {
+	displayTitle("StructField", s.Span)
+	displayKV(indent+1, "name", s.Name)
+	displayKV(indent+1, "type", s.TypeExpression)
+}
+
 func (s Block) Display(indent uint) {
-	displayTitle("FunctionDeclaration", s.Span)
+	displayTitle("Block", s.Span)
 	displayKVList(indent+1, "statements", s.Statements)
 }
+
+func (s VarStatement) Display(indent uint) {
+	displayTitle("VarStatement", s.Span)
+	displayKV(indent+1, "name", s.Name)
+	displayOptional(indent+1, "type", s.TypeExpression, s.TypeExpression != nil)
+	displayOptional(indent+1, "value", s.Value, s.Value != nil)
+}
+
+func (s DeclareAssignStatement) Display(indent uint) {
+	displayTitle("DeclareAssignStatement", s.Span)
+	displayKV(indent+1, "name", s.Name)
+	displayKV(indent+1, "value", s.Value)
+}
+
+func (s AssignStatement) Display(indent uint) {
+	displayTitle("AssignStatement", s.Span)
+	displayKV(indent+1, "target", s.Target)
+	displayKV(indent+1, "value", s.Value)
+}
+
+func (s ReturnStatement) Display(indent uint) {
+	displayTitle("ReturnStatement", s.Span)
+	displayOptional(indent+1, "value", s.Value, s.Value != nil)
+}
+
+func (s IfStatement) Display(indent uint) {
+	displayTitle("IfStatement", s.Span)
+	displayKV(indent+1, "condition", s.Condition)
+	displayKV(indent+1, "then", s.Then)
+	if s.ElseIf != nil {
+		displayKV(indent+1, "else if", *s.ElseIf)
+	}
+	if s.Else != nil {
+		displayKV(indent+1, "else", *s.Else)
+	}
+}
+
+func (s ForStatement) Display(indent uint) {
+	displayTitle("ForStatement", s.Span)
+	displayOptional(indent+1, "condition", s.Condition, s.Condition != nil)
+	displayKV(indent+1, "body", s.Body)
+}
+
+func (s ExpressionStatement) Display(indent uint) {
+	displayTitle("ExpressionStatement", s.Span)
+	displayKV(indent+1, "expression", s.Expression)
+}
+
+func (s LiteralExpression) Display(indent uint) {
+	displayTitle("LiteralExpression", s.Literal.Span)
+	displayKV(indent+1, "literal", s.Literal)
+}
+
+func (s IdentifierExpression) Display(indent uint) {
+	displayTitle("IdentifierExpression", s.Name.Span)
+	displayKV(indent+1, "name", s.Name)
+}
+
+func (s ParenExpression) Display(indent uint) {
+	displayTitle("ParenExpression", s.Span)
+	displayKV(indent+1, "value", s.Value)
+}
+
+func (s UnaryExpression) Display(indent uint) {
+	displayTitle("UnaryExpression", s.Span)
+	displayKV(indent+1, "operator", s.Operator)
+	displayKV(indent+1, "value", s.Value)
+}
+
+func (s BinaryExpression) Display(indent uint) {
+	displayTitle("BinaryExpression", s.Span)
+	displayKV(indent+1, "lhs", s.Lhs)
+	displayKV(indent+1, "operator", s.Operator)
+	displayKV(indent+1, "rhs", s.Rhs)
+}
+
+func (s CallExpression) Display(indent uint) {
+	displayTitle("CallExpression", s.Span)
+	displayKV(indent+1, "callee", s.Callee)
+	displayKVList(indent+1, "arguments", s.Arguments)
+}
+
+func (s IndexExpression) Display(indent uint) {
+	displayTitle("IndexExpression", s.Span)
+	displayKV(indent+1, "value", s.Value)
+	displayKV(indent+1, "index", s.Index)
+}
+
+func (s MemberExpression) Display(indent uint) {
+	displayTitle("MemberExpression", s.Span)
+	displayKV(indent+1, "value", s.Value)
+	displayKV(indent+1, "member", s.Member)
+}
+
+func (s CostumeDeclaration) Display(indent uint) {
+	displayTitle("CostumeDeclaration", s.Span)
+	displayKV(indent+1, "name", s.Name)
+	displayKV(indent+1, "path", s.Path)
+	if s.Center != nil {
+		displayKV(indent+1, "center", *s.Center)
+	}
+}
+
+func (s CostumeCenter) Display(indent uint) {
+	displayTitle("CostumeCenter", s.Span)
+	if s.XSign != nil {
+		displayKV(indent+1, "xSign", *s.XSign)
+	}
+	displayKV(indent+1, "x", s.X)
+	if s.YSign != nil {
+		displayKV(indent+1, "ySign", *s.YSign)
+	}
+	displayKV(indent+1, "y", s.Y)
+}
+
+func (s SoundDeclaration) Display(indent uint) {
+	displayTitle("SoundDeclaration", s.Span)
+	displayKV(indent+1, "name", s.Name)
+	displayKV(indent+1, "path", s.Path)
+}
+
+func (s EventDeclaration) Display(indent uint) {
+	displayTitle("EventDeclaration", s.Span)
+	if s.Raw != nil {
+		displayKV(indent+1, "raw", *s.Raw)
+	} else {
+		displayKV(indent+1, "event", s.Event)
+		displayKVList(indent+1, "arguments", s.Arguments)
+	}
+	displayDoc(indent+1, s.Doc)
+	displayKV(indent+1, "body", s.Body)
+}
+
+func (s RawStatement) Display(indent uint) {
+	displayTitle("RawStatement", s.Span)
+	displayKV(indent+1, "block", *s.Block)
+	displayKVList(indent+1, "substacks", s.Substacks)
+}
+
+func (s RawSubstack) Display(indent uint) {
+	displayTitle("RawSubstack", s.Span)
+	displayKV(indent+1, "name", s.Name)
+	displayKV(indent+1, "body", s.Body)
+}
+
+func (s RawExpression) Display(indent uint) {
+	displayTitle("RawExpression", s.Span)
+	displayKV(indent+1, "opcode", s.Opcode)
+	displayKVList(indent+1, "arguments", s.Arguments)
+}
+
+func (s RawArgument) Display(indent uint) {
+	displayTitle("RawArgument", s.Span)
+	displayKV(indent+1, "name", s.Name)
+	if s.Field {
+		displayKV(indent+1, "field", s.Value)
+	} else {
+		displayKV(indent+1, "input", s.Value)
+	}
+}
@@ -0,0 +1,319 @@
+package frontend
+
+import "yummy-go.com/m/v2/span"
+
+// the operators binding the loosest come first
+var binaryPrecedences = map[TokenType]int{
+	TokenOpOr:  1,
+	TokenOpAnd: 2,
+	TokenOpEqu: 3,
+	TokenOpNeq: 3,
+	TokenOpLes: 3,
+	TokenOpGes: 3,
+	TokenOpLte: 3,
+	TokenOpGte: 3,
+	TokenOpAdd: 4,
+	TokenOpSub: 4,
+	TokenOpMul: 5,
+	TokenOpDiv: 5,
+}
+
+func (s *Parser) ParseExpression() (Expression, error) {
+	return s.parseBinaryExpression(1)
+}
+
+func (s *Parser) parseBinaryExpression(minPrecedence int) (Expression, error) {
+	lhs, err := s.parseUnaryExpression()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator := s.peek()
+		if operator == nil || !s.onSameLine(operator) {
+			return lhs, nil
+		}
+		precedence, ok := binaryPrecedences[operator.Type]
+		if !ok || precedence < minPrecedence {
+			return lhs, nil
+		}
+		s.consume()
+		rhs, err := s.parseBinaryExpression(precedence + 1)
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryExpression{
+			Lhs:      lhs,
+			Operator: *operator,
+			Rhs:      rhs,
+			Span:     span.Merge(lhs.GetSpan(), rhs.GetSpan()),
+		}
+	}
+}
+
+func (s *Parser) parseUnaryExpression() (Expression, error) {
+	operator, ok := s.expect(TokenOpSub, TokenOpNot)
+	if !ok {
+		return s.parsePostfixExpression()
+	}
+	value, err := s.parseUnaryExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &UnaryExpression{
+		Operator: *operator,
+		Value:    value,
+		Span:     operator.Span.Merge(value.GetSpan()),
+	}, nil
+}
+
+// calls, indices and members
+func (s *Parser) parsePostfixExpression() (Expression, error) {
+	expression, err := s.parsePrimaryExpression()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		next := s.peek()
+		if next == nil || !s.onSameLine(next) {
+			return expression, nil
+		}
+		switch next.Type {
+		case TokenOpenParen:
+			s.consume()
+			arguments, closeParen, err := s.parseArguments()
+			if err != nil {
+				return nil, err
+			}
+			expression = &CallExpression{
+				Callee:    expression,
+				Arguments: arguments,
+				Span:      span.Merge(expression.GetSpan(), closeParen.Span),
+			}
+		case TokenOpenBracket:
+			s.consume()
+			index, err := s.ParseExpression()
+			if err != nil {
+				return nil, err
+			}
+			closeBracket, ok := s.expect(TokenCloseBracket)
+			if !ok {
+				return nil, s.reportExpectToken(closeBracket, TokenCloseBracket)
+			}
+			expression = &IndexExpression{
+				Value: expression,
+				Index: index,
+				Span:  span.Merge(expression.GetSpan(), closeBracket.Span),
+			}
+		case TokenOpMember:
+			dot := s.consume()
+			// the name of an unfinished `value.` is not taken from the next line
+			if next := s.peek(); next != nil && !s.onSameLine(next) {
+				return nil, s.reportToken(dot, span.Error, "missing field name after `.`")
+			}
+			member, ok := s.expect(TokenIdentifier, TokenRawIdentifier)
+			if !ok {
+				return nil, s.reportExpectToken(member, TokenIdentifier, TokenRawIdentifier)
+			}
+			expression = &MemberExpression{
+				Value:  expression,
+				Member: *member,
+				Span:   span.Merge(expression.GetSpan(), member.Span),
+			}
+		default:
+			return expression, nil
+		}
+	}
+}
+
+// parses the arguments of a call after its `(`, a trailing comma is allowed
+func (s *Parser) parseArguments() ([]Expression, *Token, error) {
+	arguments := make([]Expression, 0)
+	for {
+		if closeParen, ok := s.expect(TokenCloseParen); ok {
+			return arguments, closeParen, nil
+		}
+		if len(arguments) > 0 {
+			if comma, ok := s.expect(TokenComma); !ok {
+				return nil, nil, s.reportExpectToken(comma, TokenComma, TokenCloseParen)
+			}
+			if closeParen, ok := s.expect(TokenCloseParen); ok {
+				return arguments, closeParen, nil
+			}
+		}
+		argument, err := s.ParseExpression()
+		if err != nil {
+			return nil, nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+}
+
+// parses `opcode(NAME: value, NAME = "value")` after `raw`, a trailing comma
+// is allowed
+func (s *Parser) parseRawExpression(token *Token) (*RawExpression, error) {
+	opcode, ok := s.expect(TokenIdentifier, TokenRawIdentifier)
+	if !ok {
+		return nil, s.reportExpectToken(opcode, TokenIdentifier, TokenRawIdentifier)
+	}
+	if openParen, ok := s.expect(TokenOpenParen); !ok {
+		return nil, s.reportExpectToken(openParen, TokenOpenParen)
+	}
+	expression := &RawExpression{
+		Opcode:    *opcode,
+		Arguments: make([]RawArgument, 0),
+	}
+	for {
+		if closeParen, ok := s.expect(TokenCloseParen); ok {
+			expression.Span = token.Span.Merge(closeParen.Span)
+			return expression, nil
+		}
+		if len(expression.Arguments) > 0 {
+			if comma, ok := s.expect(TokenComma); !ok {
+				return nil, s.reportExpectToken(comma, TokenComma, TokenCloseParen)
+			}
+			if closeParen, ok := s.expect(TokenCloseParen); ok {
+				expression.Span = token.Span.Merge(closeParen.Span)
+				return expression, nil
+			}
+		}
+		name, ok := s.expect(TokenIdentifier, TokenRawIdentifier)
+		if !ok {
+			return nil, s.reportExpectToken(name, TokenIdentifier, TokenRawIdentifier, TokenCloseParen)
+		}
+		separator, ok := s.expect(TokenColon, TokenAssign)
+		if !ok {
+			return nil, s.reportExpectToken(separator, TokenColon, TokenAssign)
+		}
+		argument := RawArgument{
+			Name:  *name,
+			Field: separator.Type == TokenAssign,
+		}
+		if argument.Field {
+			value, ok := s.expect(TokenLiteralString)
+			if !ok {
+				return nil, s.reportExpectToken(value, TokenLiteralString)
+			}
+			argument.Value = &LiteralExpression{Literal: *value}
+		} else {
+			value, err := s.ParseExpression()
+			if err != nil {
+				return nil, err
+			}
+			argument.Value = value
+		}
+		argument.Span = name.Span.Merge(argument.Value.GetSpan())
+		expression.Arguments = append(expression.Arguments, argument)
+	}
+}
+
+func (s *Parser) parsePrimaryExpression() (Expression, error) {
+	token := s.consume()
+	if token == nil {
+		return nil, s.reportExpectToken(nil, TokenLiteralNumber, TokenLiteralString, TokenIdentifier, TokenOpenParen)
+	}
+	switch token.Type {
+	case TokenLiteralNumber, TokenLiteralString, TokenLiteralTrue, TokenLiteralFalse:
+		return &LiteralExpression{
+			Literal: *token,
+		}, nil
+	case TokenIdentifier, TokenRawIdentifier:
+		return &IdentifierExpression{
+			Name: *token,
+		}, nil
+	case TokenKeywordRaw:
+		raw, err := s.parseRawExpression(token)
+		if err != nil {
+			return nil, err
+		}
+		return raw, nil
+	case TokenOpenParen:
+		value, err := s.ParseExpression()
+		if err != nil {
+			return nil, err
+		}
+		closeParen, ok := s.expect(TokenCloseParen)
+		if !ok {
+			return nil, s.reportExpectToken(closeParen, TokenCloseParen)
+		}
+		return &ParenExpression{
+			Value: value,
+			Span:  token.Span.Merge(closeParen.Span),
+		}, nil
+	}
+	return nil, s.reportExpectToken(token, TokenLiteralNumber, TokenLiteralString, TokenIdentifier, TokenOpenParen)
+}
+
+func (s *Parser) ParseTypeExpression() (TypeExpression, error) {
+	token := s.consume()
+	if token == nil {
+		return nil, s.reportExpectToken(nil, TokenTypeNumber, TokenTypeString, TokenTypeBool, TokenOpenBracket, TokenKeywordStruct)
+	}
+	switch token.Type {
+	case TokenTypeNumber, TokenTypeString, TokenTypeBool:
+		return &NamedTypeExpression{
+			Name: *token,
+		}, nil
+	case TokenOpenBracket:
+		// `[]element` is dyn-sized
+		var length *Token
+		if _, ok := s.expect(TokenCloseBracket); !ok {
+			theLength, ok := s.expect(TokenLiteralNumber)
+			if !ok {
+				return nil, s.reportExpectToken(theLength, TokenLiteralNumber, TokenCloseBracket)
+			}
+			length = theLength
+			if closeBracket, ok := s.expect(TokenCloseBracket); !ok {
+				return nil, s.reportExpectToken(closeBracket, TokenCloseBracket)
+			}
+		}
+		element, err := s.ParseTypeExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &ArrayTypeExpression{
+			Length:  length,
+			Element: element,
+			Span:    token.Span.Merge(element.GetSpan()),
+		}, nil
+	case TokenKeywordStruct:
+		return s.parseStructTypeExpression(token)
+	}
+	return nil, s.reportExpectToken(token, TokenTypeNumber, TokenTypeString, TokenTypeBool, TokenOpenBracket, TokenKeywordStruct)
+}
+
+// `struct { name type, ... }`, fields are separated by commas, semicolons
+// or line breaks
+func (s *Parser) parseStructTypeExpression(token *Token) (TypeExpression, error) {
+	openBrace, ok := s.expect(TokenOpenBrace)
+	if !ok {
+		return nil, s.reportExpectToken(openBrace, TokenOpenBrace)
+	}
+	fields := make([]StructField, 0)
+	for {
+		if closeBrace, ok := s.expect(TokenCloseBrace); ok {
+			return &StructTypeExpression{
+				Fields: fields,
+				Span:   token.Span.Merge(closeBrace.Span),
+			}, nil
+		}
+		name, ok := s.expect(TokenIdentifier, TokenRawIdentifier)
+		if !ok {
+			return nil, s.reportExpectToken(name, TokenIdentifier, TokenRawIdentifier, TokenCloseBrace)
+		}
+		fieldType, err := s.ParseTypeExpression()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, StructField{
+			Name:           *name,
+			TypeExpression: fieldType,
+			Span:           name.Span.Merge(fieldType.GetSpan()),
+		})
+		if _, ok := s.expect(TokenComma, TokenSemi); ok {
+			continue
+		}
+		if next := s.peek(); next != nil && next.Type != TokenCloseBrace && s.onSameLine(next) {
+			return nil, s.reportExpectToken(next, TokenComma, TokenCloseBrace)
+		}
+	}
+}
@@ -0,0 +1,476 @@
+package frontend
+
+import (
+	"strings"
+
+	"yummy-go.com/m/v2/span"
+)
+
+// the arguments of a call are put on their own lines past this column
+const formatLineWidth = 100
+
+// the width of an indentation when measuring lines
+const formatTabWidth = 4
+
+// prints a program back as canonical source. comments are kept, each one
+// before the declaration or statement it precedes, or at the end of the
+// line it follows. a comment inside the arguments of a call stays there, the
+// call is then printed with one argument per line. blank lines between
+// statements are kept, at most one
+func Format(program Program) string {
+	formatter := formatter{
+		comments: program.Comments,
+		lastLine: -1,
+		limit:    ^uint(0),
+	}
+	formatter.program(program)
+	return formatter.builder.String()
+}
+
+type formatter struct {
+	builder     strings.Builder
+	indent      int
+	comments    []*Token
+	nextComment int
+	// the source line the last printed node ends on
+	lastLine int
+	// nothing was printed since the last `{`
+	atBlockStart bool
+	// the last printed line is blank
+	blank bool
+	// the index of the `}` of the block being printed, the comments past it
+	// follow the block
+	limit uint
+}
+
+func (s *formatter) line(text string) {
+	s.builder.WriteString(strings.Repeat("\t", s.indent))
+	s.builder.WriteString(text)
+	s.builder.WriteString("\n")
+	s.blank = false
+	s.atBlockStart = false
+}
+
+// puts a blank line before something starting on `lineno` if the source
+// has one, or if it is forced
+func (s *formatter) separate(lineno uint, force bool) {
+	if s.atBlockStart || s.blank || s.lastLine < 0 {
+		return
+	}
+	if force || int(lineno) > s.lastLine+1 {
+		s.builder.WriteString("\n")
+		s.blank = true
+	}
+}
+
+// the next comment if it starts before `index`
+func (s *formatter) pendingComment(index uint) *Token {
+	if s.nextComment >= len(s.comments) {
+		return nil
+	}
+	comment := s.comments[s.nextComment]
+	if comment.Span.From.Index >= index {
+		return nil
+	}
+	return comment
+}
+
+func commentText(comment *Token) string {
+	return strings.TrimRight(comment.Span.String(), " \t\r")
+}
+
+// prints the comments starting before `index` on their own lines
+func (s *formatter) flushComments(index uint) {
+	for comment := s.pendingComment(index); comment != nil; comment = s.pendingComment(index) {
+		s.separate(comment.Span.From.Lineno, false)
+		s.line(commentText(comment))
+		s.lastLine = int(comment.Span.From.Lineno)
+		s.nextComment += 1
+	}
+}
+
+// the comment following a node on its last line, if any
+func (s *formatter) trailing(theSpan span.Span) string {
+	return s.trailingBefore(theSpan, s.limit)
+}
+
+// the comment following a node on its last line and starting before
+// `limit`, a comment further on the line belongs to what comes next
+func (s *formatter) trailingBefore(theSpan span.Span, limit uint) string {
+	comment := s.pendingComment(limit)
+	if comment == nil || comment.Span.From.Lineno != theSpan.To.Lineno || comment.Span.From.Index < theSpan.To.Index {
+		return ""
+	}
+	s.nextComment += 1
+	return " " + commentText(comment)
+}
+
+// the comments starting before `index` as lines of an expression, each one
+// ending with a line break
+func (s *formatter) commentLines(index uint) string {
+	lines := ""
+	for comment := s.pendingComment(index); comment != nil; comment = s.pendingComment(index) {
+		lines += strings.Repeat("\t", s.indent) + commentText(comment) + "\n"
+		s.nextComment += 1
+	}
+	return lines
+}
+
+// prints a declaration or a statement with the comments before it
+func (s *formatter) item(theSpan span.Span, force bool, print func()) {
+	first := theSpan.From.Lineno
+	if comment := s.pendingComment(theSpan.From.Index); comment != nil {
+		first = comment.Span.From.Lineno
+	}
+	s.separate(first, force)
+	s.flushComments(theSpan.From.Index)
+	s.separate(theSpan.From.Lineno, false)
+	print()
+	s.lastLine = int(theSpan.To.Lineno)
+}
+
+func (s *formatter) program(program Program) {
+	targetSpan := span.Merge(program.Span, program.Target.Span)
+	s.item(targetSpan, false, func() {
+		s.line("target " + program.Target.Span.String() + s.trailing(targetSpan))
+	})
+	var previous Declaration
+	for _, declaration := range program.Declarations {
+		// functions are always set apart
+		_, isFunction := declaration.(*FunctionDeclaration)
+		_, previousIsFunction := previous.(*FunctionDeclaration)
+		force := previous == nil || isFunction || previousIsFunction
+		s.item(declaration.GetSpan(), force, func() {
+			s.declaration(declaration)
+		})
+		previous = declaration
+	}
+	if comment := s.pendingComment(^uint(0)); comment != nil {
+		s.separate(comment.Span.From.Lineno, false)
+		s.flushComments(^uint(0))
+	}
+}
+
+func (s *formatter) declaration(declaration Declaration) {
+	theSpan := declaration.GetSpan()
+	switch declaration := declaration.(type) {
+	case *GlobalVariableDeclaration:
+		text := s.variable(declaration.Name, declaration.TypeExpression, declaration.Value)
+		s.line(text + s.trailing(theSpan))
+	case *FunctionDeclaration:
+		arguments := make([]string, 0, len(declaration.Arguments))
+		for _, argument := range declaration.Arguments {
+			arguments = append(arguments, argument.Name.Span.String()+" "+formatTypeExpression(argument.TypeExpression))
+		}
+		header := "func " + declaration.Name.Span.String() + "(" + strings.Join(arguments, ", ") + ")"
+		if declaration.Inline != nil {
+			header = "inline " + header
+		}
+		if declaration.ReturnType != nil {
+			header += " " + formatTypeExpression(declaration.ReturnType)
+		}
+		s.blockStatement(header, declaration.Body, theSpan)
+	case *EventDeclaration:
+		header := "when "
+		if declaration.Raw != nil {
+			header += s.expression(declaration.Raw, s.column(len(header)))
+		} else {
+			header += declaration.Event.Span.String()
+			if len(declaration.Arguments) > 0 {
+				arguments := make([]string, 0, len(declaration.Arguments))
+				for _, argument := range declaration.Arguments {
+					arguments = append(arguments, flatExpression(argument))
+				}
+				header += "(" + strings.Join(arguments, ", ") + ")"
+			}
+		}
+		s.blockStatement(header, declaration.Body, theSpan)
+	case *CostumeDeclaration:
+		text := "costume " + declaration.Name.Span.String() + " from " + declaration.Path.Span.String()
+		if center := declaration.Center; center != nil {
+			x, y := center.Coordinates()
+			text += " center(" + x + ", " + y + ")"
+		}
+		s.line(text + s.trailing(theSpan))
+	case *SoundDeclaration:
+		s.line("sound " + declaration.Name.Span.String() + " from " + declaration.Path.Span.String() + s.trailing(theSpan))
+	}
+}
+
+// `var name type = value`
+func (s *formatter) variable(name Token, typeExpression TypeExpression, value Expression) string {
+	text := "var " + name.Span.String()
+	if typeExpression != nil {
+		text += " " + formatTypeExpression(typeExpression)
+	}
+	if value != nil {
+		text += " = " + s.expression(value, s.column(len(text)+3))
+	}
+	return text
+}
+
+// the column after `width` characters on a new line
+func (s *formatter) column(width int) int {
+	return s.indent*formatTabWidth + width
+}
+
+// prints `header {`, the block and `}`. an empty block is printed `{}`
+func (s *formatter) blockStatement(header string, block Block, theSpan span.Span) {
+	if len(block.Statements) == 0 && s.pendingComment(block.Span.To.Index) == nil {
+		s.line(header + " {}" + s.trailing(theSpan))
+		return
+	}
+	s.line(header + " {" + s.openBraceComment(block))
+	s.block(block)
+	s.line("}" + s.trailing(theSpan))
+}
+
+// the comment following the `{` of a block, before its first statement
+func (s *formatter) openBraceComment(block Block) string {
+	theSpan := block.Span
+	theSpan.To = theSpan.From
+	theSpan.To.Index += 1
+	limit := block.Span.To.Index - 1
+	if len(block.Statements) > 0 {
+		limit = block.Statements[0].GetSpan().From.Index
+	}
+	return s.trailingBefore(theSpan, limit)
+}
+
+// the statements of a block and the comments before its `}`, indented
+func (s *formatter) block(block Block) {
+	s.indent += 1
+	s.atBlockStart = true
+	limit := s.limit
+	// the `}` is the last char of the block
+	s.limit = block.Span.To.Index - 1
+	for _, statement := range block.Statements {
+		s.item(statement.GetSpan(), false, func() {
+			s.statement(statement)
+		})
+	}
+	s.flushComments(s.limit)
+	s.limit = limit
+	s.atBlockStart = false
+	s.indent -= 1
+}
+
+func (s *formatter) statement(statement Statement) {
+	theSpan := statement.GetSpan()
+	switch statement := statement.(type) {
+	case *VarStatement:
+		text := s.variable(statement.Name, statement.TypeExpression, statement.Value)
+		s.line(text + s.trailing(theSpan))
+	case *DeclareAssignStatement:
+		text := statement.Name.Span.String() + " := "
+		s.line(text + s.expression(statement.Value, s.column(len(text))) + s.trailing(theSpan))
+	case *AssignStatement:
+		text := flatExpression(statement.Target) + " = "
+		s.line(text + s.expression(statement.Value, s.column(len(text))) + s.trailing(theSpan))
+	case *ReturnStatement:
+		text := "return"
+		if statement.Value != nil {
+			text += " " + s.expression(statement.Value, s.column(len(text)+1))
+		}
+		s.line(text + s.trailing(theSpan))
+	case *IfStatement:
+		s.ifStatement("if ", statement, theSpan)
+	case *ForStatement:
+		header := "for"
+		if statement.Condition != nil {
+			header += " " + s.expression(statement.Condition, s.column(len(header)+1))
+		}
+		s.blockStatement(header, statement.Body, theSpan)
+	case *ExpressionStatement:
+		s.line(s.expression(statement.Expression, s.column(0)) + s.trailing(theSpan))
+	case *RawStatement:
+		s.rawStatement(statement, theSpan)
+	}
+}
+
+// prints `raw opcode(arguments) NAME {`, then `} NAME {` for each next
+// substack
+func (s *formatter) rawStatement(statement *RawStatement, theSpan span.Span) {
+	header := s.expression(statement.Block, s.column(0))
+	if len(statement.Substacks) == 0 {
+		s.line(header + s.trailing(theSpan))
+		return
+	}
+	for idx, substack := range statement.Substacks {
+		if idx > 0 {
+			header = "}"
+		}
+		header += " " + substack.Name.Span.String()
+		last := idx == len(statement.Substacks)-1
+		if last && len(substack.Body.Statements) == 0 && s.pendingComment(substack.Body.Span.To.Index) == nil {
+			s.line(header + " {}" + s.trailing(theSpan))
+			return
+		}
+		s.line(header + " {" + s.openBraceComment(substack.Body))
+		s.block(substack.Body)
+	}
+	s.line("}" + s.trailing(theSpan))
+}
+
+// prints an `if` and its `else if`s, `theSpan` is the span of the whole
+// chain for the comment after its last `}`
+func (s *formatter) ifStatement(prefix string, statement *IfStatement, theSpan span.Span) {
+	header := prefix + s.expression(statement.Condition, s.column(len(prefix))) + " {"
+	s.line(header + s.openBraceComment(statement.Then))
+	s.block(statement.Then)
+	switch {
+	case statement.ElseIf != nil:
+		s.ifStatement("} else if ", statement.ElseIf, theSpan)
+	case statement.Else != nil:
+		s.line("} else {" + s.openBraceComment(*statement.Else))
+		s.block(*statement.Else)
+		s.line("}" + s.trailing(theSpan))
+	default:
+		s.line("}" + s.trailing(theSpan))
+	}
+}
+
+// an expression starting at `column`, calls that would go past the line
+// width have one argument per line
+func (s *formatter) expression(expression Expression, column int) string {
+	flat := flatExpression(expression)
+	// a comment inside the expression keeps it broken over lines
+	commented := s.pendingComment(expression.GetSpan().To.Index) != nil
+	if !commented && column+len(flat) <= formatLineWidth {
+		return flat
+	}
+	switch expression := expression.(type) {
+	case *ParenExpression:
+		return "(" + s.expression(expression.Value, column+1) + ")"
+	case *UnaryExpression:
+		operator := expression.Operator.Span.String()
+		return operator + s.expression(expression.Value, column+len(operator))
+	case *BinaryExpression:
+		lhs := s.expression(expression.Lhs, column)
+		separator := " " + expression.Operator.Span.String() + " "
+		return lhs + separator + s.expression(expression.Rhs, lastLineColumn(lhs, column)+len(separator))
+	case *CallExpression:
+		if len(expression.Arguments) == 0 {
+			return flat
+		}
+		callee := s.expression(expression.Callee, column)
+		s.indent += 1
+		lines := make([]string, 0, len(expression.Arguments)+1)
+		for idx, argument := range expression.Arguments {
+			// the `)` is the last char of the call
+			limit := expression.Span.To.Index - 1
+			if idx+1 < len(expression.Arguments) {
+				limit = expression.Arguments[idx+1].GetSpan().From.Index
+			}
+			lines = append(lines, s.argumentLine("", argument, limit))
+		}
+		lines = append(lines, s.commentLines(expression.Span.To.Index-1))
+		s.indent -= 1
+		return callee + "(\n" + strings.Join(lines, "") + strings.Repeat("\t", s.indent) + ")"
+	case *RawExpression:
+		if len(expression.Arguments) == 0 {
+			return flat
+		}
+		s.indent += 1
+		lines := make([]string, 0, len(expression.Arguments)+1)
+		for idx, argument := range expression.Arguments {
+			limit := expression.Span.To.Index - 1
+			if idx+1 < len(expression.Arguments) {
+				limit = expression.Arguments[idx+1].Span.From.Index
+			}
+			lines = append(lines, s.argumentLine(rawArgumentPrefix(argument), argument.Value, limit))
+		}
+		lines = append(lines, s.commentLines(expression.Span.To.Index-1))
+		s.indent -= 1
+		return "raw " + expression.Opcode.Span.String() + "(\n" + strings.Join(lines, "") + strings.Repeat("\t", s.indent) + ")"
+	case *IndexExpression:
+		value := s.expression(expression.Value, column)
+		return value + "[" + s.expression(expression.Index, lastLineColumn(value, column)+1) + "]"
+	case *MemberExpression:
+		return s.expression(expression.Value, column) + "." + expression.Member.Span.String()
+	}
+	return flat
+}
+
+// an argument on its own line, after the comments before it and with the
+// comment following it up to `limit`
+func (s *formatter) argumentLine(prefix string, argument Expression, limit uint) string {
+	comments := s.commentLines(argument.GetSpan().From.Index)
+	text := strings.Repeat("\t", s.indent) + prefix + s.expression(argument, s.column(len(prefix))) + ","
+	return comments + text + s.trailingBefore(argument.GetSpan(), limit) + "\n"
+}
+
+// the column at the end of a text starting at `column`
+func lastLineColumn(text string, column int) int {
+	idx := strings.LastIndex(text, "\n")
+	if idx < 0 {
+		return column + len(text)
+	}
+	last := text[idx+1:]
+	tabs := len(last) - len(strings.TrimLeft(last, "\t"))
+	return tabs*formatTabWidth + len(last) - tabs
+}
+
+// an expression on a single line
+func flatExpression(expression Expression) string {
+	switch expression := expression.(type) {
+	case *LiteralExpression:
+		return expression.Literal.Span.String()
+	case *IdentifierExpression:
+		return expression.Name.Span.String()
+	case *ParenExpression:
+		return "(" + flatExpression(expression.Value) + ")"
+	case *UnaryExpression:
+		return expression.Operator.Span.String() + flatExpression(expression.Value)
+	case *BinaryExpression:
+		return flatExpression(expression.Lhs) + " " + expression.Operator.Span.String() + " " + flatExpression(expression.Rhs)
+	case *CallExpression:
+		arguments := make([]string, 0, len(expression.Arguments))
+		for _, argument := range expression.Arguments {
+			arguments = append(arguments, flatExpression(argument))
+		}
+		return flatExpression(expression.Callee) + "(" + strings.Join(arguments, ", ") + ")"
+	case *IndexExpression:
+		return flatExpression(expression.Value) + "[" + flatExpression(expression.Index) + "]"
+	case *MemberExpression:
+		return flatExpression(expression.Value) + "." + expression.Member.Span.String()
+	case *RawExpression:
+		arguments := make([]string, 0, len(expression.Arguments))
+		for _, argument := range expression.Arguments {
+			arguments = append(arguments, rawArgumentPrefix(argument)+flatExpression(argument.Value))
+		}
+		return "raw " + expression.Opcode.Span.String() + "(" + strings.Join(arguments, ", ") + ")"
+	}
+	return ""
+}
+
+// `NAME: ` for an input, `NAME = ` for a field
+func rawArgumentPrefix(argument RawArgument) string {
+	if argument.Field {
+		return argument.Name.Span.String() + " = "
+	}
+	return argument.Name.Span.String() + ": "
+}
+
+func formatTypeExpression(typeExpression TypeExpression) string {
+	switch typeExpression := typeExpression.(type) {
+	case *NamedTypeExpression:
+		return typeExpression.Name.Span.String()
+	case *ArrayTypeExpression:
+		length := ""
+		if typeExpression.Length != nil {
+			length = typeExpression.Length.Span.String()
+		}
+		return "[" + length + "]" + formatTypeExpression(typeExpression.Element)
+	case *StructTypeExpression:
+		if len(typeExpression.Fields) == 0 {
+			return "struct {}"
+		}
+		fields := make([]string, 0, len(typeExpression.Fields))
+		for _, field := range typeExpression.Fields {
+			fields = append(fields, field.Name.Span.String()+" "+formatTypeExpression(field.TypeExpression))
+		}
+		return "struct { " + strings.Join(fields, ", ") + " }"
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package frontend_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"yummy-go.com/m/v2/frontend"
+)
+
+func format(t *testing.T, path, source string) string {
+	t.Helper()
+	parser := frontend.NewParser(frontend.NewLexer(path, source))
+	program, err := parser.ParseProgram()
+	if err != nil {
+		t.Fatalf("parse %s: %s", path, err)
+	}
+	return frontend.Format(program)
+}
+
+// the examples are kept formatted, and formatting them again changes nothing
+func TestFormatExamples(t *testing.T) {
+	paths, err := filepath.Glob("../examples/*.yum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no examples")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			once := format(t, path, string(source))
+			if once != string(source) {
+				t.Errorf("not formatted, found:\n%s", once)
+			}
+			if twice := format(t, path, once); twice != once {
+				t.Errorf("formatting twice differs:\n%s", twice)
+			}
+		})
+	}
+}
+
+// comments stay on the line of the node they follow
+func TestFormatComments(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name: "call arguments",
+			source: `target Stage
+
+func f() {
+	f2(1, // first arg
+		2)
+}
+`,
+			expected: `target Stage
+
+func f() {
+	f2(
+		1, // first arg
+		2,
+	)
+}
+`,
+		},
+		{
+			name: "comments between arguments",
+			source: `target Stage
+
+func f() {
+	raw looks_say(
+		// the message
+		MESSAGE: "hi",
+		SECS: f3(4, // inner
+			5),
+		// before the paren
+	) // after the call
+}
+`,
+			expected: `target Stage
+
+func f() {
+	raw looks_say(
+		// the message
+		MESSAGE: "hi",
+		SECS: f3(
+			4, // inner
+			5,
+		),
+		// before the paren
+	) // after the call
+}
+`,
+		},
+		{
+			name: "after a one line if",
+			source: `target Stage
+
+func f() {
+	if x > 0 { f() } // after the if
+	if x > 0 { // opening
+		f()
+	} else { f() } // after the else
+}
+`,
+			expected: `target Stage
+
+func f() {
+	if x > 0 {
+		f()
+	} // after the if
+	if x > 0 { // opening
+		f()
+	} else {
+		f()
+	} // after the else
+}
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			once := format(t, "comments.yum", test.source)
+			if once != test.expected {
+				t.Errorf("expected:\n%s\nfound:\n%s", test.expected, once)
+			}
+			if twice := format(t, "comments.yum", once); twice != once {
+				t.Errorf("formatting twice differs:\n%s", twice)
+			}
+		})
+	}
+}
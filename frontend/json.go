@@ -0,0 +1,391 @@
+package frontend
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"yummy-go.com/m/v2/span"
+)
+
+// the names of the token types in the JSON encoding, kept stable when the
+// messages of the token types change
+var tokenTypeNames = map[TokenType]string{
+	TokenLiteralNumber: "LiteralNumber",
+	TokenLiteralTrue:   "LiteralTrue",
+	TokenLiteralFalse:  "LiteralFalse",
+	TokenLiteralString: "LiteralString",
+	TokenIdentifier:    "Identifier",
+	TokenComment:       "Comment",
+	TokenRawIdentifier: "RawIdentifier",
+	TokenOpenParen:     "OpenParen",
+	TokenCloseParen:    "CloseParen",
+	TokenOpenBracket:   "OpenBracket",
+	TokenCloseBracket:  "CloseBracket",
+	TokenOpenBrace:     "OpenBrace",
+	TokenCloseBrace:    "CloseBrace",
+	TokenComma:         "Comma",
+	TokenSemi:          "Semi",
+	TokenColon:         "Colon",
+	TokenAssign:        "Assign",
+	TokenDeclareAssign: "DeclareAssign",
+	TokenOpAdd:         "OpAdd",
+	TokenOpSub:         "OpSub",
+	TokenOpMul:         "OpMul",
+	TokenOpDiv:         "OpDiv",
+	TokenOpEqu:         "OpEqu",
+	TokenOpNeq:         "OpNeq",
+	TokenOpLes:         "OpLes",
+	TokenOpGes:         "OpGes",
+	TokenOpLte:         "OpLte",
+	TokenOpGte:         "OpGte",
+	TokenOpAnd:         "OpAnd",
+	TokenOpOr:          "OpOr",
+	TokenOpNot:         "OpNot",
+	TokenOpMember:      "OpMember",
+	TokenKeywordFor:    "KeywordFor",
+	TokenKeywordVar:    "KeywordVar",
+	TokenKeywordReturn: "KeywordReturn",
+	TokenKeywordIf:     "KeywordIf",
+	TokenKeywordElse:   "KeywordElse",
+	TokenKeywordTarget: "KeywordTarget",
+	TokenKeywordFunc:   "KeywordFunc",
+	TokenKeywordStruct: "KeywordStruct",
+	TokenKeywordWhen:   "KeywordWhen",
+	TokenKeywordRaw:    "KeywordRaw",
+	TokenTypeString:    "TypeString",
+	TokenTypeNumber:    "TypeNumber",
+	TokenTypeBool:      "TypeBool",
+}
+
+// an object whose keys keep their order
+type jsonObject []jsonField
+
+type jsonField struct {
+	Key   string
+	Value any
+}
+
+func (s jsonObject) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for idx, field := range s {
+		if idx > 0 {
+			buffer.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// encodes the program as JSON, every node is an object with its `kind` and
+// `span` first, the positions of spans are 0-based like span.Position
+func MarshalJSON(program *Program) ([]byte, error) {
+	return json.Marshal(encodeNode(program))
+}
+
+func MarshalIndentJSON(program *Program, prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(encodeNode(program), prefix, indent)
+}
+
+func encodeSpan(theSpan span.Span) jsonObject {
+	encodePosition := func(position span.Position) jsonObject {
+		return jsonObject{
+			{"index", position.Index},
+			{"lineno", position.Lineno},
+			{"lineIndex", position.LineIndex},
+		}
+	}
+	return jsonObject{
+		{"from", encodePosition(theSpan.From)},
+		{"to", encodePosition(theSpan.To)},
+	}
+}
+
+func encodeToken(token *Token) any {
+	if token == nil {
+		return nil
+	}
+	name, ok := tokenTypeNames[token.Type]
+	if !ok {
+		name = "Broken"
+	}
+	return jsonObject{
+		{"type", name},
+		{"text", token.Span.String()},
+		{"span", encodeSpan(token.Span)},
+	}
+}
+
+func encodeNodes[T Node](nodes []T) []any {
+	encoded := make([]any, 0, len(nodes))
+	for _, node := range nodes {
+		encoded = append(encoded, encodeNode(node))
+	}
+	return encoded
+}
+
+// nil interfaces and nil pointers become null
+func encodeNode(node Node) any {
+	var fields jsonObject
+	kind := ""
+	switch node := node.(type) {
+	case nil:
+		return nil
+	case *Program:
+		kind = "Program"
+		comments := make([]any, 0, len(node.Comments))
+		for _, comment := range node.Comments {
+			comments = append(comments, encodeToken(comment))
+		}
+		fields = jsonObject{
+			{"target", encodeToken(&node.Target)},
+			{"declarations", encodeNodes(node.Declarations)},
+			{"comments", comments},
+		}
+	case *GlobalVariableDeclaration:
+		kind = "GlobalVariableDeclaration"
+		fields = jsonObject{
+			{"name", encodeToken(&node.Name)},
+			{"typeExpression", encodeNode(node.TypeExpression)},
+			{"value", encodeNode(node.Value)},
+			{"doc", node.Doc},
+		}
+	case *FunctionDeclaration:
+		kind = "FunctionDeclaration"
+		arguments := make([]any, 0, len(node.Arguments))
+		for idx := range node.Arguments {
+			arguments = append(arguments, encodeNode(&node.Arguments[idx]))
+		}
+		fields = jsonObject{
+			{"inline", node.Inline != nil},
+			{"name", encodeToken(&node.Name)},
+			{"arguments", arguments},
+			{"returnType", encodeNode(node.ReturnType)},
+			{"body", encodeNode(&node.Body)},
+			{"doc", node.Doc},
+		}
+	case *Argument:
+		kind = "Argument"
+		fields = jsonObject{
+			{"name", encodeToken(&node.Name)},
+			{"typeExpression", encodeNode(node.TypeExpression)},
+		}
+	case *EventDeclaration:
+		kind = "EventDeclaration"
+		var raw any
+		if node.Raw != nil {
+			raw = encodeNode(node.Raw)
+		}
+		var event any
+		if node.Raw == nil {
+			event = encodeToken(&node.Event)
+		}
+		fields = jsonObject{
+			{"event", event},
+			{"arguments", encodeNodes(node.Arguments)},
+			{"raw", raw},
+			{"body", encodeNode(&node.Body)},
+			{"doc", node.Doc},
+		}
+	case *CostumeDeclaration:
+		kind = "CostumeDeclaration"
+		var center any
+		if node.Center != nil {
+			center = encodeNode(node.Center)
+		}
+		fields = jsonObject{
+			{"name", encodeToken(&node.Name)},
+			{"path", encodeToken(&node.Path)},
+			{"center", center},
+		}
+	case *CostumeCenter:
+		kind = "CostumeCenter"
+		fields = jsonObject{
+			{"x", encodeToken(&node.X)},
+			{"y", encodeToken(&node.Y)},
+			{"xNegative", node.XSign != nil},
+			{"yNegative", node.YSign != nil},
+		}
+	case *SoundDeclaration:
+		kind = "SoundDeclaration"
+		fields = jsonObject{
+			{"name", encodeToken(&node.Name)},
+			{"path", encodeToken(&node.Path)},
+		}
+	case *NamedTypeExpression:
+		kind = "NamedTypeExpression"
+		fields = jsonObject{
+			{"name", encodeToken(&node.Name)},
+		}
+	case *ArrayTypeExpression:
+		kind = "ArrayTypeExpression"
+		fields = jsonObject{
+			{"length", encodeToken(node.Length)},
+			{"element", encodeNode(node.Element)},
+		}
+	case *StructTypeExpression:
+		kind = "StructTypeExpression"
+		structFields := make([]any, 0, len(node.Fields))
+		for idx := range node.Fields {
+			structFields = append(structFields, encodeNode(&node.Fields[idx]))
+		}
+		fields = jsonObject{
+			{"fields", structFields},
+		}
+	case *StructField:
+		kind = "StructField"
+		fields = jsonObject{
+			{"name", encodeToken(&node.Name)},
+			{"typeExpression", encodeNode(node.TypeExpression)},
+		}
+	case *Block:
+		kind = "Block"
+		fields = jsonObject{
+			{"statements", encodeNodes(node.Statements)},
+		}
+	case *VarStatement:
+		kind = "VarStatement"
+		fields = jsonObject{
+			{"name", encodeToken(&node.Name)},
+			{"typeExpression", encodeNode(node.TypeExpression)},
+			{"value", encodeNode(node.Value)},
+		}
+	case *DeclareAssignStatement:
+		kind = "DeclareAssignStatement"
+		fields = jsonObject{
+			{"name", encodeToken(&node.Name)},
+			{"value", encodeNode(node.Value)},
+		}
+	case *AssignStatement:
+		kind = "AssignStatement"
+		fields = jsonObject{
+			{"target", encodeNode(node.Target)},
+			{"value", encodeNode(node.Value)},
+		}
+	case *ReturnStatement:
+		kind = "ReturnStatement"
+		fields = jsonObject{
+			{"value", encodeNode(node.Value)},
+		}
+	case *IfStatement:
+		kind = "IfStatement"
+		var elseIf, elseBlock any
+		if node.ElseIf != nil {
+			elseIf = encodeNode(node.ElseIf)
+		}
+		if node.Else != nil {
+			elseBlock = encodeNode(node.Else)
+		}
+		fields = jsonObject{
+			{"condition", encodeNode(node.Condition)},
+			{"then", encodeNode(&node.Then)},
+			{"elseIf", elseIf},
+			{"else", elseBlock},
+		}
+	case *ForStatement:
+		kind = "ForStatement"
+		fields = jsonObject{
+			{"condition", encodeNode(node.Condition)},
+			{"body", encodeNode(&node.Body)},
+		}
+	case *ExpressionStatement:
+		kind = "ExpressionStatement"
+		fields = jsonObject{
+			{"expression", encodeNode(node.Expression)},
+		}
+	case *RawStatement:
+		kind = "RawStatement"
+		substacks := make([]any, 0, len(node.Substacks))
+		for idx := range node.Substacks {
+			substacks = append(substacks, encodeNode(&node.Substacks[idx]))
+		}
+		fields = jsonObject{
+			{"block", encodeNode(node.Block)},
+			{"substacks", substacks},
+		}
+	case *RawSubstack:
+		kind = "RawSubstack"
+		fields = jsonObject{
+			{"name", encodeToken(&node.Name)},
+			{"body", encodeNode(&node.Body)},
+		}
+	case *RawExpression:
+		kind = "RawExpression"
+		arguments := make([]any, 0, len(node.Arguments))
+		for idx := range node.Arguments {
+			arguments = append(arguments, encodeNode(&node.Arguments[idx]))
+		}
+		fields = jsonObject{
+			{"opcode", encodeToken(&node.Opcode)},
+			{"arguments", arguments},
+		}
+	case *RawArgument:
+		kind = "RawArgument"
+		fields = jsonObject{
+			{"name", encodeToken(&node.Name)},
+			{"field", node.Field},
+			{"value", encodeNode(node.Value)},
+		}
+	case *LiteralExpression:
+		kind = "LiteralExpression"
+		fields = jsonObject{
+			{"literal", encodeToken(&node.Literal)},
+		}
+	case *IdentifierExpression:
+		kind = "IdentifierExpression"
+		fields = jsonObject{
+			{"name", encodeToken(&node.Name)},
+		}
+	case *ParenExpression:
+		kind = "ParenExpression"
+		fields = jsonObject{
+			{"value", encodeNode(node.Value)},
+		}
+	case *UnaryExpression:
+		kind = "UnaryExpression"
+		fields = jsonObject{
+			{"operator", encodeToken(&node.Operator)},
+			{"value", encodeNode(node.Value)},
+		}
+	case *BinaryExpression:
+		kind = "BinaryExpression"
+		fields = jsonObject{
+			{"lhs", encodeNode(node.Lhs)},
+			{"operator", encodeToken(&node.Operator)},
+			{"rhs", encodeNode(node.Rhs)},
+		}
+	case *CallExpression:
+		kind = "CallExpression"
+		fields = jsonObject{
+			{"callee", encodeNode(node.Callee)},
+			{"arguments", encodeNodes(node.Arguments)},
+		}
+	case *IndexExpression:
+		kind = "IndexExpression"
+		fields = jsonObject{
+			{"value", encodeNode(node.Value)},
+			{"index", encodeNode(node.Index)},
+		}
+	case *MemberExpression:
+		kind = "MemberExpression"
+		fields = jsonObject{
+			{"value", encodeNode(node.Value)},
+			{"member", encodeToken(&node.Member)},
+		}
+	}
+	return append(jsonObject{
+		{"kind", kind},
+		{"span", encodeSpan(node.GetSpan())},
+	}, fields...)
+}
@@ -1,6 +1,7 @@
 package frontend
 
 import (
+	"slices"
 	"strings"
 
 	"yummy-go.com/m/v2/span"
@@ -12,6 +13,9 @@ type Lexer struct {
 	path        string
 	current     lexerState
 	mark        lexerState
+	// line comments are skipped by NextToken but kept for doc comments
+	comments []*Token
+	previous *Token
 }
 
 type lexerState struct {
@@ -88,6 +92,28 @@ func isValidIdentifierLeading(char byte) bool {
 }
 
 func (s *Lexer) NextToken() *Token {
+	token := s.nextToken()
+	if token != nil {
+		s.previous = token
+	}
+	return token
+}
+
+// whether the previous token ends a value, a minus after a value is the
+// operator so `a -1` is `a - 1`
+func (s *Lexer) afterValue() bool {
+	if s.previous == nil {
+		return false
+	}
+	switch s.previous.Type {
+	case TokenIdentifier, TokenRawIdentifier, TokenLiteralNumber, TokenLiteralString,
+		TokenLiteralTrue, TokenLiteralFalse, TokenCloseParen, TokenCloseBracket:
+		return true
+	}
+	return false
+}
+
+func (s *Lexer) nextToken() *Token {
 	s.setMark()
 	current := s.consume()
 	for current == '\n' || current == ' ' || current == '\t' {
@@ -120,6 +146,13 @@ func (s *Lexer) NextToken() *Token {
 	case '*':
 		return s.token(TokenOpMul)
 	case '/':
+		if s.peek() == '/' {
+			for s.peek() != '\n' && s.peek() != '\u0000' {
+				s.consume()
+			}
+			s.comments = append(s.comments, s.token(TokenComment))
+			return s.nextToken()
+		}
 		return s.token(TokenOpDiv)
 	case ':':
 		if s.peek() == '=' {
@@ -193,17 +226,19 @@ func (s *Lexer) NextToken() *Token {
 		}
 		return s.token(TokenRawIdentifier)
 	case '-':
-		if isNumberic(s.peek()) {
-			current = s.consume()
-		} else {
+		if !isNumberic(s.peek()) || s.afterValue() {
 			return s.token(TokenOpSub)
 		}
+		current = s.consume()
 		fallthrough
 	default:
 		if isNumberic(current) {
 			for isNumberic(s.peek()) {
 				s.consume()
-				if s.peek() == '.' {
+			}
+			if s.peek() == '.' {
+				s.consume()
+				for isNumberic(s.peek()) {
 					s.consume()
 				}
 			}
@@ -236,6 +271,10 @@ func (s *Lexer) NextToken() *Token {
 				return s.token(TokenTypeBool)
 			case "struct":
 				return s.token(TokenKeywordStruct)
+			case "when":
+				return s.token(TokenKeywordWhen)
+			case "raw":
+				return s.token(TokenKeywordRaw)
 			case "true":
 				return s.token(TokenLiteralTrue)
 			case "false":
@@ -248,6 +287,32 @@ func (s *Lexer) NextToken() *Token {
 	return nil // Error
 }
 
+func (s *Lexer) Comments() []*Token {
+	return s.comments
+}
+
+// returns the text of the comments on the lines right above `lineno`,
+// comments following code on the same line are not doc comments
+func (s *Lexer) DocComment(lineno uint) string {
+	lines := make([]string, 0)
+	for idx := len(s.comments) - 1; idx >= 0; idx -= 1 {
+		comment := s.comments[idx]
+		if comment.Span.From.Lineno >= lineno {
+			continue
+		}
+		if comment.Span.From.Lineno+uint(len(lines))+1 != lineno {
+			break
+		}
+		sourceLine := s.sourceLines[comment.Span.From.Lineno]
+		if strings.TrimSpace(sourceLine[:comment.Span.From.LineIndex]) != "" {
+			break
+		}
+		lines = append(lines, comment.CommentText())
+	}
+	slices.Reverse(lines)
+	return strings.Join(lines, "\n")
+}
+
 func NewLexer(path string, source string) Lexer {
 	sourceLines := strings.Split(source, "\n")
 	return Lexer{
@@ -5,6 +5,8 @@ import "yummy-go.com/m/v2/span"
 type Parser struct {
 	lexer       Lexer
 	peekedToken *Token
+	// the last consumed token, statements end at line breaks after it
+	lastToken *Token
 }
 
 func NewParser(lexer Lexer) Parser {
@@ -23,12 +25,21 @@ func (s *Parser) peek() *Token {
 }
 
 func (s *Parser) consume() *Token {
-	if s.peekedToken != nil {
-		token := s.peekedToken
+	token := s.peekedToken
+	if token != nil {
 		s.peekedToken = nil
-		return token
+	} else {
+		token = s.lexer.NextToken()
+	}
+	if token != nil {
+		s.lastToken = token
 	}
-	return s.lexer.NextToken()
+	return token
+}
+
+// whether the token starts on the line the last consumed token ends on
+func (s *Parser) onSameLine(token *Token) bool {
+	return token != nil && s.lastToken != nil && token.Span.From.Lineno == s.lastToken.Span.To.Lineno
 }
 
 func (s *Parser) expect(types ...TokenType) (*Token, bool) {
@@ -80,8 +91,13 @@ func (s *Parser) RestoreFromError() {
 		if token == nil {
 			return
 		}
-		switch token.Type {
-		case TokenKeywordFunc:
+		switch {
+		case token.Type == TokenKeywordFunc, token.Type == TokenKeywordWhen:
+			return
+		// a `var` inside a function is indented
+		case token.Type == TokenKeywordVar && token.Span.From.LineIndex == 0:
+			return
+		case (isContextual(token, "costume") || isContextual(token, "sound") || isContextual(token, "inline")) && token.Span.From.LineIndex == 0:
 			return
 		default:
 			s.consume()
@@ -105,17 +121,24 @@ func (s *Parser) ParseProgram() (Program, error) {
 	var theErr error
 	declarations := make([]Declaration, 0)
 	for s.peek() != nil {
+		if _, ok := s.expect(TokenSemi); ok {
+			continue
+		}
 		declaration, err := s.ParseDeclaration()
+		if declaration != nil {
+			declarations = append(declarations, declaration)
+		}
 		if err != nil {
 			theErr = err
-			s.RestoreFromError()
-			continue
+			if declaration == nil {
+				s.RestoreFromError()
+			}
 		}
-		declarations = append(declarations, declaration)
 	}
 	return Program{
 		Target:       *target,
 		Declarations: declarations,
+		Comments:     s.lexer.Comments(),
 		Span:         tokenTarget.Span,
 	}, theErr
 }
@@ -131,30 +154,256 @@ func (s *Parser) ParseDeclaration() (Declaration, error) {
 	}
 	switch token.Type {
 	case TokenKeywordFunc:
-		name, ok := s.expect(TokenIdentifier, TokenRawIdentifier)
-		if !ok {
-			return nil, s.reportExpectToken(name, TokenIdentifier, TokenRawIdentifier)
+		return s.parseFunctionDeclaration(nil, token)
+	case TokenKeywordWhen:
+		return s.parseEventDeclaration(token)
+	case TokenKeywordVar:
+		name, typeExpression, value, err := s.parseVariable()
+		if err != nil {
+			return nil, err
 		}
-		openParen, ok := s.expect(TokenOpenParen)
+		return &GlobalVariableDeclaration{
+			Name:           *name,
+			TypeExpression: typeExpression,
+			Value:          value,
+			Doc:            s.lexer.DocComment(token.Span.From.Lineno),
+			Span:           token.Span.Merge(s.lastToken.Span),
+		}, nil
+	}
+	switch {
+	case isContextual(token, "costume"):
+		return s.parseCostumeDeclaration(token)
+	case isContextual(token, "sound"):
+		return s.parseSoundDeclaration(token)
+	case isContextual(token, "inline"):
+		funcToken, ok := s.expect(TokenKeywordFunc)
 		if !ok {
-			return nil, s.reportExpectToken(openParen, TokenOpenParen)
+			return nil, s.reportExpectToken(funcToken, TokenKeywordFunc)
+		}
+		return s.parseFunctionDeclaration(token, funcToken)
+	}
+	return nil, s.reportExpectToken(token, TokenKeywordFunc, TokenKeywordWhen, TokenKeywordVar, "costume", "sound", "inline")
+}
+
+// `costume`, `sound`, `from`, `center` and `inline` are identifiers anywhere
+// else
+func isContextual(token *Token, word string) bool {
+	return token != nil && token.Type == TokenIdentifier && token.Span.String() == word
+}
+
+// parses `<name> from <path>` of asset declarations
+func (s *Parser) parseAssetSource() (*Token, *Token, error) {
+	name, ok := s.expect(TokenLiteralString)
+	if !ok {
+		return nil, nil, s.reportExpectToken(name, TokenLiteralString)
+	}
+	if from := s.consume(); !isContextual(from, "from") {
+		return nil, nil, s.reportExpectToken(from, "from")
+	}
+	path, ok := s.expect(TokenLiteralString)
+	if !ok {
+		return nil, nil, s.reportExpectToken(path, TokenLiteralString)
+	}
+	return name, path, nil
+}
+
+func (s *Parser) parseCostumeDeclaration(token *Token) (Declaration, error) {
+	name, path, err := s.parseAssetSource()
+	if err != nil {
+		return nil, err
+	}
+	declaration := CostumeDeclaration{
+		Name: *name,
+		Path: *path,
+		Span: token.Span.Merge(path.Span),
+	}
+	// the rotation center is optional: `center(x, y)`
+	center := s.peek()
+	if !isContextual(center, "center") {
+		return &declaration, nil
+	}
+	s.consume()
+	openParen, ok := s.expect(TokenOpenParen)
+	if !ok {
+		return nil, s.reportExpectToken(openParen, TokenOpenParen)
+	}
+	xSign, x, err := s.parseCenterCoordinate()
+	if err != nil {
+		return nil, err
+	}
+	comma, ok := s.expect(TokenComma)
+	if !ok {
+		return nil, s.reportExpectToken(comma, TokenComma)
+	}
+	ySign, y, err := s.parseCenterCoordinate()
+	if err != nil {
+		return nil, err
+	}
+	closeParen, ok := s.expect(TokenCloseParen)
+	if !ok {
+		return nil, s.reportExpectToken(closeParen, TokenCloseParen)
+	}
+	declaration.Center = &CostumeCenter{
+		X:     *x,
+		Y:     *y,
+		XSign: xSign,
+		YSign: ySign,
+		Span:  center.Span.Merge(closeParen.Span),
+	}
+	declaration.Span = token.Span.Merge(closeParen.Span)
+	return &declaration, nil
+}
+
+// a number with an optional leading `-`
+func (s *Parser) parseCenterCoordinate() (*Token, *Token, error) {
+	sign, ok := s.expect(TokenOpSub)
+	if !ok {
+		sign = nil
+	}
+	number, ok := s.expect(TokenLiteralNumber)
+	if !ok {
+		return nil, nil, s.reportExpectToken(number, TokenLiteralNumber)
+	}
+	return sign, number, nil
+}
+
+// `func name(arguments) type { }`, `inline` is the token before `func` if any
+func (s *Parser) parseFunctionDeclaration(inline *Token, token *Token) (Declaration, error) {
+	name, ok := s.expect(TokenIdentifier, TokenRawIdentifier)
+	if !ok {
+		return nil, s.reportExpectToken(name, TokenIdentifier, TokenRawIdentifier)
+	}
+	openParen, ok := s.expect(TokenOpenParen)
+	if !ok {
+		return nil, s.reportExpectToken(openParen, TokenOpenParen)
+	}
+	arguments := make([]Argument, 0)
+	for {
+		if _, ok := s.expect(TokenCloseParen); ok {
+			break
+		}
+		if len(arguments) > 0 {
+			if comma, ok := s.expect(TokenComma); !ok {
+				return nil, s.reportExpectToken(comma, TokenComma, TokenCloseParen)
+			}
+			// a trailing comma
+			if _, ok := s.expect(TokenCloseParen); ok {
+				break
+			}
 		}
-		closeParen, ok := s.expect(TokenCloseParen)
+		argumentName, ok := s.expect(TokenIdentifier, TokenRawIdentifier)
 		if !ok {
-			return nil, s.reportExpectToken(closeParen, TokenCloseParen)
+			return nil, s.reportExpectToken(argumentName, TokenIdentifier, TokenRawIdentifier)
 		}
-		body, err := s.ParseBlock()
+		typeExpression, err := s.ParseTypeExpression()
 		if err != nil {
 			return nil, err
 		}
-		return &FunctionDeclaration{
-			Name: *name,
-			Span: token.Span.Merge(body.Span),
-		}, nil
-	case TokenKeywordVar:
-		return nil, s.todo(token)
+		arguments = append(arguments, Argument{
+			Name:           *argumentName,
+			TypeExpression: typeExpression,
+			Span:           argumentName.Span.Merge(typeExpression.GetSpan()),
+		})
+	}
+	var returnType TypeExpression
+	if next := s.peek(); next != nil && next.Type != TokenOpenBrace {
+		var err error
+		if returnType, err = s.ParseTypeExpression(); err != nil {
+			return nil, err
+		}
+	}
+	body, err := s.ParseBlock()
+	if body.Statements == nil {
+		return nil, err
+	}
+	first := token
+	if inline != nil {
+		first = inline
+	}
+	// the function is kept when only some of its statements are broken
+	return &FunctionDeclaration{
+		Inline:     inline,
+		Name:       *name,
+		Arguments:  arguments,
+		ReturnType: returnType,
+		Body:       body,
+		Doc:        s.lexer.DocComment(first.Span.From.Lineno),
+		Span:       first.Span.Merge(body.Span),
+	}, err
+}
+
+// `when event(arguments) { }` or `when raw opcode(arguments) { }`
+func (s *Parser) parseEventDeclaration(token *Token) (Declaration, error) {
+	declaration := &EventDeclaration{
+		Arguments: make([]Expression, 0),
+		Doc:       s.lexer.DocComment(token.Span.From.Lineno),
+	}
+	if rawToken, ok := s.expect(TokenKeywordRaw); ok {
+		raw, err := s.parseRawExpression(rawToken)
+		if err != nil {
+			return nil, err
+		}
+		declaration.Raw = raw
+	} else {
+		event, ok := s.expect(TokenIdentifier)
+		if !ok {
+			return nil, s.reportExpectToken(event, TokenIdentifier, TokenKeywordRaw)
+		}
+		declaration.Event = *event
+		if _, ok := s.expect(TokenOpenParen); ok {
+			arguments, _, err := s.parseArguments()
+			if err != nil {
+				return nil, err
+			}
+			declaration.Arguments = arguments
+		}
+	}
+	body, err := s.ParseBlock()
+	if body.Statements == nil {
+		return nil, err
+	}
+	declaration.Body = body
+	declaration.Span = token.Span.Merge(body.Span)
+	return declaration, err
+}
+
+// parses `<name> [type] [= value]` of variable declarations, at least one
+// of the type and the value is given
+func (s *Parser) parseVariable() (*Token, TypeExpression, Expression, error) {
+	name, ok := s.expect(TokenIdentifier, TokenRawIdentifier)
+	if !ok {
+		return nil, nil, nil, s.reportExpectToken(name, TokenIdentifier, TokenRawIdentifier)
+	}
+	var typeExpression TypeExpression
+	if next := s.peek(); next != nil && next.Type != TokenAssign {
+		var err error
+		if typeExpression, err = s.ParseTypeExpression(); err != nil {
+			return nil, nil, nil, err
+		}
 	}
-	return nil, s.reportExpectToken(token, TokenKeywordFunc, TokenKeywordVar)
+	if _, ok := s.expect(TokenAssign); !ok {
+		if typeExpression == nil {
+			return nil, nil, nil, s.reportToken(name, span.Error, "variable `%s` needs a type or a value", name.Identifier())
+		}
+		return name, typeExpression, nil, nil
+	}
+	value, err := s.ParseExpression()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return name, typeExpression, value, nil
+}
+
+func (s *Parser) parseSoundDeclaration(token *Token) (Declaration, error) {
+	name, path, err := s.parseAssetSource()
+	if err != nil {
+		return nil, err
+	}
+	return &SoundDeclaration{
+		Name: *name,
+		Path: *path,
+		Span: token.Span.Merge(path.Span),
+	}, nil
 }
 
 func (s *Parser) ParseBlock() (Block, error) {
@@ -163,12 +412,55 @@ func (s *Parser) ParseBlock() (Block, error) {
 		return Block{}, s.reportExpectToken(openBrace, TokenOpenBrace)
 	}
 	statements := make([]Statement, 0)
-	closeBrace, ok := s.expect(TokenCloseBrace)
-	if !ok {
-		return Block{}, s.reportExpectToken(closeBrace, TokenCloseBrace)
+	// a broken statement is skipped, the block is returned with the error
+	var theErr error
+	for {
+		if closeBrace, ok := s.expect(TokenCloseBrace); ok {
+			return Block{
+				Statements: statements,
+				Span:       openBrace.Span.Merge(closeBrace.Span),
+			}, theErr
+		}
+		if s.peek() == nil {
+			return Block{}, s.reportExpectToken(nil, TokenCloseBrace)
+		}
+		statement, err := s.ParseStatement()
+		if err != nil {
+			theErr = err
+			s.restoreFromStatementError()
+			continue
+		}
+		statements = append(statements, statement)
+		// statements end at a line break, a `;` or the end of the block
+		if _, ok := s.expect(TokenSemi); ok {
+			continue
+		}
+		if next := s.peek(); next != nil && next.Type != TokenCloseBrace && s.onSameLine(next) {
+			theErr = s.reportExpectToken(next, TokenSemi, TokenCloseBrace)
+			s.restoreFromStatementError()
+		}
+	}
+}
+
+// skips the rest of a broken statement, up to the next line or the end of
+// the block, so the statements after it are still parsed
+func (s *Parser) restoreFromStatementError() {
+	depth := 0
+	for {
+		token := s.peek()
+		if token == nil {
+			return
+		}
+		switch {
+		case token.Type == TokenCloseBrace && depth == 0:
+			return
+		case depth == 0 && !s.onSameLine(token):
+			return
+		case token.Type == TokenOpenBrace:
+			depth += 1
+		case token.Type == TokenCloseBrace:
+			depth -= 1
+		}
+		s.consume()
 	}
-	return Block{
-		Statements: statements,
-		Span:       openBrace.Span.Merge(closeBrace.Span),
-	}, nil
 }
@@ -0,0 +1,171 @@
+package frontend
+
+import "yummy-go.com/m/v2/span"
+
+func (s *Parser) ParseStatement() (Statement, error) {
+	token := s.peek()
+	switch token.Type {
+	case TokenKeywordVar:
+		s.consume()
+		name, typeExpression, value, err := s.parseVariable()
+		if err != nil {
+			return nil, err
+		}
+		return &VarStatement{
+			Name:           *name,
+			TypeExpression: typeExpression,
+			Value:          value,
+			Span:           token.Span.Merge(s.lastToken.Span),
+		}, nil
+	case TokenKeywordReturn:
+		s.consume()
+		statement := &ReturnStatement{
+			Span: token.Span,
+		}
+		// a value on the next line is another statement
+		next := s.peek()
+		if next == nil || next.Type == TokenCloseBrace || next.Type == TokenSemi || !s.onSameLine(next) {
+			return statement, nil
+		}
+		value, err := s.ParseExpression()
+		if err != nil {
+			return nil, err
+		}
+		statement.Value = value
+		statement.Span = token.Span.Merge(value.GetSpan())
+		return statement, nil
+	case TokenKeywordIf:
+		return s.parseIfStatement()
+	case TokenKeywordRaw:
+		return s.parseRawStatement()
+	case TokenKeywordFor:
+		s.consume()
+		statement := &ForStatement{}
+		if next := s.peek(); next != nil && next.Type != TokenOpenBrace {
+			condition, err := s.ParseExpression()
+			if err != nil {
+				return nil, err
+			}
+			statement.Condition = condition
+		}
+		body, err := s.ParseBlock()
+		if err != nil {
+			return nil, err
+		}
+		statement.Body = body
+		statement.Span = token.Span.Merge(body.Span)
+		return statement, nil
+	}
+	expression, err := s.ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if declareAssign, ok := s.expect(TokenDeclareAssign); ok {
+		identifier, ok := expression.(*IdentifierExpression)
+		if !ok {
+			return nil, s.reportToken(declareAssign, span.Error, "only a name can be declared with `:=`")
+		}
+		value, err := s.ParseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &DeclareAssignStatement{
+			Name:  identifier.Name,
+			Value: value,
+			Span:  span.Merge(expression.GetSpan(), value.GetSpan()),
+		}, nil
+	}
+	if assign, ok := s.expect(TokenAssign); ok {
+		switch expression.(type) {
+		case *IdentifierExpression, *MemberExpression, *IndexExpression:
+		default:
+			return nil, s.reportToken(assign, span.Error, "cannot assign to this expression")
+		}
+		value, err := s.ParseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &AssignStatement{
+			Target: expression,
+			Value:  value,
+			Span:   span.Merge(expression.GetSpan(), value.GetSpan()),
+		}, nil
+	}
+	if _, ok := expression.(*CallExpression); !ok {
+		theSpan := expression.GetSpan()
+		return nil, span.Report(theSpan, span.Error, "the value of the expression is not used")
+	}
+	return &ExpressionStatement{
+		Expression: expression,
+		Span:       expression.GetSpan(),
+	}, nil
+}
+
+// `if condition { } else if condition { } else { }`
+func (s *Parser) parseIfStatement() (Statement, error) {
+	token := s.consume()
+	condition, err := s.ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+	then, err := s.ParseBlock()
+	if err != nil {
+		return nil, err
+	}
+	statement := &IfStatement{
+		Condition: condition,
+		Then:      then,
+		Span:      token.Span.Merge(then.Span),
+	}
+	if _, ok := s.expect(TokenKeywordElse); !ok {
+		return statement, nil
+	}
+	if next := s.peek(); next != nil && next.Type == TokenKeywordIf {
+		elseIf, err := s.parseIfStatement()
+		if err != nil {
+			return nil, err
+		}
+		statement.ElseIf = elseIf.(*IfStatement)
+		statement.Span = statement.Span.Merge(elseIf.GetSpan())
+		return statement, nil
+	}
+	elseBlock, err := s.ParseBlock()
+	if err != nil {
+		return nil, err
+	}
+	statement.Else = &elseBlock
+	statement.Span = statement.Span.Merge(elseBlock.Span)
+	return statement, nil
+}
+
+// `raw opcode(arguments)`, followed by the substacks of the block on the
+// same line: `NAME { } NAME { }`
+func (s *Parser) parseRawStatement() (Statement, error) {
+	token := s.consume()
+	block, err := s.parseRawExpression(token)
+	if err != nil {
+		return nil, err
+	}
+	statement := &RawStatement{
+		Block:     block,
+		Substacks: make([]RawSubstack, 0),
+		Span:      block.Span,
+	}
+	for {
+		next := s.peek()
+		if next == nil || !s.onSameLine(next) || (next.Type != TokenIdentifier && next.Type != TokenRawIdentifier) {
+			return statement, nil
+		}
+		s.consume()
+		body, err := s.ParseBlock()
+		if err != nil {
+			return nil, err
+		}
+		statement.Substacks = append(statement.Substacks, RawSubstack{
+			Name: *next,
+			Body: body,
+			Span: next.Span.Merge(body.Span),
+		})
+		statement.Span = token.Span.Merge(body.Span)
+	}
+}
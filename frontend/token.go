@@ -1,6 +1,9 @@
 package frontend
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/fatih/color"
 	"yummy-go.com/m/v2/span"
 )
@@ -16,6 +19,7 @@ const (
 	TokenLiteralFalse  TokenType = "false"
 	TokenLiteralString TokenType = "string literal"
 	TokenIdentifier    TokenType = "idenifier"
+	TokenComment       TokenType = "comment"
 	TokenRawIdentifier TokenType = "raw idenifier"
 	// Parens
 	TokenOpenParen    TokenType = "("
@@ -41,8 +45,8 @@ const (
 	TokenOpGes    TokenType = "operator [>]"
 	TokenOpLte    TokenType = "operator [<=]"
 	TokenOpGte    TokenType = "operator [>=]"
-	TokenOpAnd    TokenType = "operator [||]"
-	TokenOpOr     TokenType = "operator [&&]"
+	TokenOpAnd    TokenType = "operator [&&]"
+	TokenOpOr     TokenType = "operator [||]"
 	TokenOpNot    TokenType = "operator [!]"
 	TokenOpMember TokenType = "operator [.]"
 	// Keywords
@@ -54,6 +58,8 @@ const (
 	TokenKeywordTarget TokenType = "keyword target"
 	TokenKeywordFunc   TokenType = "keyword func"
 	TokenKeywordStruct TokenType = "keyword struct"
+	TokenKeywordWhen   TokenType = "keyword when"
+	TokenKeywordRaw    TokenType = "keyword raw"
 	// Types
 	TokenTypeString TokenType = "type string"
 	TokenTypeNumber TokenType = "type number"
@@ -64,3 +70,32 @@ type Token struct {
 	Type TokenType
 	Span span.Span
 }
+
+// returns the text of a line comment without the leading `//`
+func (s *Token) CommentText() string {
+	text := strings.TrimPrefix(s.Span.String(), "//")
+	return strings.TrimSuffix(strings.TrimPrefix(text, " "), "\r")
+}
+
+// returns the value of a string literal
+func (s *Token) StringValue() string {
+	literal := s.Span.String()
+	unquoted, err := strconv.Unquote(literal)
+	if err != nil {
+		return literal[1 : len(literal)-1]
+	}
+	return unquoted
+}
+
+// returns the name of an identifier, unquoting raw identifiers
+func (s *Token) Identifier() string {
+	name := s.Span.String()
+	if s.Type != TokenRawIdentifier {
+		return name
+	}
+	unquoted, err := strconv.Unquote(name[1:])
+	if err != nil {
+		return name[2 : len(name)-1]
+	}
+	return unquoted
+}
@@ -0,0 +1,133 @@
+package frontend
+
+// Visit is called for each node in source order, the children of the node
+// are walked with the returned visitor, nil skips them
+type Visitor interface {
+	Visit(node Node) Visitor
+}
+
+// walks the node and its children, after the children Visit(nil) is called
+// on the visitor returned for the node
+func Walk(visitor Visitor, node Node) {
+	if visitor = visitor.Visit(node); visitor == nil {
+		return
+	}
+	switch node := node.(type) {
+	case *Program:
+		for _, declaration := range node.Declarations {
+			Walk(visitor, declaration)
+		}
+	case *GlobalVariableDeclaration:
+		walkOptional(visitor, node.TypeExpression)
+		walkOptional(visitor, node.Value)
+	case *FunctionDeclaration:
+		for idx := range node.Arguments {
+			Walk(visitor, &node.Arguments[idx])
+		}
+		walkOptional(visitor, node.ReturnType)
+		Walk(visitor, &node.Body)
+	case *Argument:
+		Walk(visitor, node.TypeExpression)
+	case *EventDeclaration:
+		if node.Raw != nil {
+			Walk(visitor, node.Raw)
+		}
+		for _, argument := range node.Arguments {
+			Walk(visitor, argument)
+		}
+		Walk(visitor, &node.Body)
+	case *CostumeDeclaration:
+		if node.Center != nil {
+			Walk(visitor, node.Center)
+		}
+	case *ArrayTypeExpression:
+		Walk(visitor, node.Element)
+	case *StructTypeExpression:
+		for idx := range node.Fields {
+			Walk(visitor, &node.Fields[idx])
+		}
+	case *StructField:
+		Walk(visitor, node.TypeExpression)
+	case *Block:
+		for _, statement := range node.Statements {
+			Walk(visitor, statement)
+		}
+	case *VarStatement:
+		walkOptional(visitor, node.TypeExpression)
+		walkOptional(visitor, node.Value)
+	case *DeclareAssignStatement:
+		Walk(visitor, node.Value)
+	case *AssignStatement:
+		Walk(visitor, node.Target)
+		Walk(visitor, node.Value)
+	case *ReturnStatement:
+		walkOptional(visitor, node.Value)
+	case *IfStatement:
+		Walk(visitor, node.Condition)
+		Walk(visitor, &node.Then)
+		if node.ElseIf != nil {
+			Walk(visitor, node.ElseIf)
+		}
+		if node.Else != nil {
+			Walk(visitor, node.Else)
+		}
+	case *ForStatement:
+		walkOptional(visitor, node.Condition)
+		Walk(visitor, &node.Body)
+	case *ExpressionStatement:
+		Walk(visitor, node.Expression)
+	case *RawStatement:
+		Walk(visitor, node.Block)
+		for idx := range node.Substacks {
+			Walk(visitor, &node.Substacks[idx])
+		}
+	case *RawSubstack:
+		Walk(visitor, &node.Body)
+	case *ParenExpression:
+		Walk(visitor, node.Value)
+	case *UnaryExpression:
+		Walk(visitor, node.Value)
+	case *BinaryExpression:
+		Walk(visitor, node.Lhs)
+		Walk(visitor, node.Rhs)
+	case *CallExpression:
+		Walk(visitor, node.Callee)
+		for _, argument := range node.Arguments {
+			Walk(visitor, argument)
+		}
+	case *IndexExpression:
+		Walk(visitor, node.Value)
+		Walk(visitor, node.Index)
+	case *MemberExpression:
+		Walk(visitor, node.Value)
+	case *RawExpression:
+		for idx := range node.Arguments {
+			Walk(visitor, &node.Arguments[idx])
+		}
+	case *RawArgument:
+		Walk(visitor, node.Value)
+	}
+	visitor.Visit(nil)
+}
+
+// optional children are nil interfaces when they are missing
+func walkOptional[T Node](visitor Visitor, node T) {
+	if Node(node) != nil {
+		Walk(visitor, node)
+	}
+}
+
+type inspector func(Node) bool
+
+func (s inspector) Visit(node Node) Visitor {
+	if s(node) {
+		return s
+	}
+	return nil
+}
+
+// calls f for each node in source order, the children are skipped when f
+// returns false, after the children f(nil) is called
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,104 @@
+package frontend_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"yummy-go.com/m/v2/frontend"
+)
+
+const walked = `target Stage
+
+var total number = 1
+
+func add(a number) number {
+	if a > 0 {
+		total = total + a
+	}
+	return add(-a)
+}
+`
+
+func parseProgram(t *testing.T, path, source string) *frontend.Program {
+	t.Helper()
+	parser := frontend.NewParser(frontend.NewLexer(path, source))
+	program, err := parser.ParseProgram()
+	if err != nil {
+		t.Fatalf("parse %s: %s", path, err)
+	}
+	return &program
+}
+
+func nodeName(node frontend.Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*frontend.")
+}
+
+// lists the nodes indented by their depth, the children are visited with
+// the visitor returned for their parent
+type depthVisitor struct {
+	depth int
+	lines *[]string
+}
+
+func (s depthVisitor) Visit(node frontend.Node) frontend.Visitor {
+	if node == nil {
+		return nil
+	}
+	*s.lines = append(*s.lines, strings.Repeat("  ", s.depth)+nodeName(node))
+	return depthVisitor{depth: s.depth + 1, lines: s.lines}
+}
+
+func TestWalkOrder(t *testing.T) {
+	lines := make([]string, 0)
+	frontend.Walk(depthVisitor{lines: &lines}, parseProgram(t, "walked.yum", walked))
+	expected := `Program
+  GlobalVariableDeclaration
+    NamedTypeExpression
+    LiteralExpression
+  FunctionDeclaration
+    Argument
+      NamedTypeExpression
+    NamedTypeExpression
+    Block
+      IfStatement
+        BinaryExpression
+          IdentifierExpression
+          LiteralExpression
+        Block
+          AssignStatement
+            IdentifierExpression
+            BinaryExpression
+              IdentifierExpression
+              IdentifierExpression
+      ReturnStatement
+        CallExpression
+          IdentifierExpression
+          UnaryExpression
+            IdentifierExpression`
+	if actual := strings.Join(lines, "\n"); actual != expected {
+		t.Errorf("visited:\n%s\nexpected:\n%s", actual, expected)
+	}
+}
+
+// the children of a node are skipped when false is returned for it, and
+// f(nil) follows the children of the others
+func TestInspectSkips(t *testing.T) {
+	visits := make([]string, 0)
+	frontend.Inspect(parseProgram(t, "walked.yum", walked), func(node frontend.Node) bool {
+		if node == nil {
+			visits = append(visits, ")")
+			return false
+		}
+		visits = append(visits, nodeName(node))
+		switch node.(type) {
+		case *frontend.GlobalVariableDeclaration, *frontend.Block:
+			return false
+		}
+		return true
+	})
+	expected := "Program GlobalVariableDeclaration FunctionDeclaration Argument NamedTypeExpression ) ) NamedTypeExpression ) Block ) )"
+	if actual := strings.Join(visits, " "); actual != expected {
+		t.Errorf("visited %s, expected %s", actual, expected)
+	}
+}
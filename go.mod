@@ -2,8 +2,6 @@ module yummy-go.com/m/v2
 
 go 1.24.5
 
-require github.com/google/uuid v1.6.0 // direct
-
 require (
 	github.com/fatih/color v1.18.0 // direct
 	github.com/mattn/go-colorable v0.1.13 // indirect
@@ -0,0 +1,321 @@
+package headless
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"yummy-go.com/m/v2/scir"
+)
+
+// evaluates an input of a block, an empty input is an empty string
+func (s *thread) evaluateInput(blockId string, block *scir.Block, name string) (Value, error) {
+	input, ok := block.Inputs[name]
+	if !ok {
+		return "", nil
+	}
+	theInput := input.ObscuredInput
+	if theInput == nil {
+		theInput = input.ShadowedInput
+	}
+	switch theInput := theInput.(type) {
+	case *scir.BlockInput:
+		inputId := string(*theInput)
+		inputBlock, err := s.block(inputId)
+		if err != nil {
+			return nil, err
+		}
+		return s.evaluate(inputId, inputBlock)
+	case *scir.NumberalInput:
+		return theInput.Value, nil
+	case *scir.StringInput:
+		return theInput.Value, nil
+	case *scir.BroadcastInput:
+		return theInput.Value, nil
+	case *scir.VariableOrListInput:
+		if theInput.Type == scir.InputList {
+			lists, ok := s.sprite.lookupList(theInput.Id)
+			if !ok {
+				return nil, s.error(blockId, "list `%s` does not exist", theInput.Value)
+			}
+			return listContents(lists[theInput.Id]), nil
+		}
+		variables, ok := s.sprite.lookupVariable(theInput.Id)
+		if !ok {
+			return nil, s.error(blockId, "variable `%s` does not exist", theInput.Value)
+		}
+		return variables[theInput.Id], nil
+	}
+	return "", nil
+}
+
+func (s *thread) evaluateNumbers(blockId string, block *scir.Block, lhs, rhs string) (float64, float64, error) {
+	lhsValue, err := s.evaluateInput(blockId, block, lhs)
+	if err != nil {
+		return 0, 0, err
+	}
+	rhsValue, err := s.evaluateInput(blockId, block, rhs)
+	if err != nil {
+		return 0, 0, err
+	}
+	return ToNumber(lhsValue), ToNumber(rhsValue), nil
+}
+
+func (s *thread) evaluatePair(blockId string, block *scir.Block, lhs, rhs string) (Value, Value, error) {
+	lhsValue, err := s.evaluateInput(blockId, block, lhs)
+	if err != nil {
+		return nil, nil, err
+	}
+	rhsValue, err := s.evaluateInput(blockId, block, rhs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lhsValue, rhsValue, nil
+}
+
+// evaluates a reporter block
+func (s *thread) evaluate(blockId string, block *scir.Block) (Value, error) {
+	if err := s.step(blockId); err != nil {
+		return nil, err
+	}
+	switch block.Opcode {
+	// shadows
+	case "math_number", "math_positive_number", "math_whole_number", "math_integer", "math_angle":
+		return block.Fields["NUM"].Value, nil
+	case "text":
+		return block.Fields["TEXT"].Value, nil
+	case "colour_picker":
+		return block.Fields["COLOUR"].Value, nil
+	case "event_broadcast_menu":
+		return block.Fields["BROADCAST_OPTION"].Value, nil
+	// operators
+	case "operator_add":
+		lhs, rhs, err := s.evaluateNumbers(blockId, block, "NUM1", "NUM2")
+		return lhs + rhs, err
+	case "operator_subtract":
+		lhs, rhs, err := s.evaluateNumbers(blockId, block, "NUM1", "NUM2")
+		return lhs - rhs, err
+	case "operator_multiply":
+		lhs, rhs, err := s.evaluateNumbers(blockId, block, "NUM1", "NUM2")
+		return lhs * rhs, err
+	case "operator_divide":
+		lhs, rhs, err := s.evaluateNumbers(blockId, block, "NUM1", "NUM2")
+		return lhs / rhs, err
+	case "operator_mod":
+		lhs, rhs, err := s.evaluateNumbers(blockId, block, "NUM1", "NUM2")
+		result := math.Mod(lhs, rhs)
+		// the result takes the sign of the divisor
+		if result/rhs < 0 {
+			result += rhs
+		}
+		return result, err
+	case "operator_random":
+		from, to, err := s.evaluatePair(blockId, block, "FROM", "TO")
+		if err != nil {
+			return nil, err
+		}
+		low, high := min(ToNumber(from), ToNumber(to)), max(ToNumber(from), ToNumber(to))
+		random := s.sprite.runtime.random
+		if low == high {
+			return low, nil
+		}
+		if isInt(from) && isInt(to) {
+			return low + math.Floor(random.Float64()*(high+1-low)), nil
+		}
+		return random.Float64()*(high-low) + low, nil
+	case "operator_lt":
+		lhs, rhs, err := s.evaluatePair(blockId, block, "OPERAND1", "OPERAND2")
+		return Compare(lhs, rhs) < 0, err
+	case "operator_gt":
+		lhs, rhs, err := s.evaluatePair(blockId, block, "OPERAND1", "OPERAND2")
+		return Compare(lhs, rhs) > 0, err
+	case "operator_equals":
+		lhs, rhs, err := s.evaluatePair(blockId, block, "OPERAND1", "OPERAND2")
+		return Compare(lhs, rhs) == 0, err
+	case "operator_and":
+		lhs, rhs, err := s.evaluatePair(blockId, block, "OPERAND1", "OPERAND2")
+		return ToBoolean(lhs) && ToBoolean(rhs), err
+	case "operator_or":
+		lhs, rhs, err := s.evaluatePair(blockId, block, "OPERAND1", "OPERAND2")
+		return ToBoolean(lhs) || ToBoolean(rhs), err
+	case "operator_not":
+		value, err := s.evaluateInput(blockId, block, "OPERAND")
+		return !ToBoolean(value), err
+	case "operator_join":
+		lhs, rhs, err := s.evaluatePair(blockId, block, "STRING1", "STRING2")
+		return ToString(lhs) + ToString(rhs), err
+	case "operator_letter_of":
+		letter, str, err := s.evaluatePair(blockId, block, "LETTER", "STRING")
+		if err != nil {
+			return nil, err
+		}
+		runes := []rune(ToString(str))
+		index := int(math.Floor(ToNumber(letter))) - 1
+		if index < 0 || index >= len(runes) {
+			return "", nil
+		}
+		return string(runes[index]), nil
+	case "operator_length":
+		str, err := s.evaluateInput(blockId, block, "STRING")
+		return float64(utf8.RuneCountInString(ToString(str))), err
+	case "operator_contains":
+		haystack, needle, err := s.evaluatePair(blockId, block, "STRING1", "STRING2")
+		return strings.Contains(strings.ToLower(ToString(haystack)), strings.ToLower(ToString(needle))), err
+	case "operator_round":
+		value, err := s.evaluateInput(blockId, block, "NUM")
+		return roundHalfUp(ToNumber(value)), err
+	case "operator_mathop":
+		value, err := s.evaluateInput(blockId, block, "NUM")
+		if err != nil {
+			return nil, err
+		}
+		return mathop(block.Fields["OPERATOR"].Value, ToNumber(value)), nil
+	// procedures
+	case "argument_reporter_string_number", "argument_reporter_boolean":
+		name := block.Fields["VALUE"].Value
+		if len(s.frames) > 0 {
+			if value, ok := s.frames[len(s.frames)-1][name]; ok {
+				return value, nil
+			}
+		}
+		// reporters outside the procedure they belong to
+		if block.Opcode == "argument_reporter_boolean" {
+			return false, nil
+		}
+		return float64(0), nil
+	// data
+	case "data_variable":
+		variables, id, err := s.variable(blockId, block)
+		if err != nil {
+			return nil, err
+		}
+		return variables[id], nil
+	case "data_listcontents":
+		lists, id, err := s.list(blockId, block)
+		if err != nil {
+			return nil, err
+		}
+		return listContents(lists[id]), nil
+	case "data_itemoflist":
+		lists, id, err := s.list(blockId, block)
+		if err != nil {
+			return nil, err
+		}
+		index, err := s.evaluateInput(blockId, block, "INDEX")
+		if err != nil {
+			return nil, err
+		}
+		position, ok := s.listIndex(index, len(lists[id]), false)
+		if !ok {
+			return "", nil
+		}
+		return lists[id][position], nil
+	case "data_itemnumoflist":
+		lists, id, err := s.list(blockId, block)
+		if err != nil {
+			return nil, err
+		}
+		item, err := s.evaluateInput(blockId, block, "ITEM")
+		if err != nil {
+			return nil, err
+		}
+		for idx, listItem := range lists[id] {
+			if Compare(listItem, item) == 0 {
+				return float64(idx + 1), nil
+			}
+		}
+		return float64(0), nil
+	case "data_lengthoflist":
+		lists, id, err := s.list(blockId, block)
+		if err != nil {
+			return nil, err
+		}
+		return float64(len(lists[id])), nil
+	case "data_listcontainsitem":
+		lists, id, err := s.list(blockId, block)
+		if err != nil {
+			return nil, err
+		}
+		item, err := s.evaluateInput(blockId, block, "ITEM")
+		if err != nil {
+			return nil, err
+		}
+		for _, listItem := range lists[id] {
+			if Compare(listItem, item) == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return nil, s.error(blockId, "unsupported reporter `%s`", block.Opcode)
+}
+
+// the contents of a list joined by spaces, or without any separator when
+// every item is a single letter
+func listContents(items []Value) string {
+	strs := make([]string, 0, len(items))
+	allLetters := true
+	for _, item := range items {
+		str := ToString(item)
+		if utf8.RuneCountInString(str) != 1 {
+			allLetters = false
+		}
+		strs = append(strs, str)
+	}
+	if allLetters {
+		return strings.Join(strs, "")
+	}
+	return strings.Join(strs, " ")
+}
+
+// `Math.round` of javascript
+func roundHalfUp(number float64) float64 {
+	return math.Floor(number + 0.5)
+}
+
+func mathop(operator string, number float64) float64 {
+	const radians = math.Pi / 180
+	switch operator {
+	case "abs":
+		return math.Abs(number)
+	case "floor":
+		return math.Floor(number)
+	case "ceiling":
+		return math.Ceil(number)
+	case "sqrt":
+		return math.Sqrt(number)
+	case "sin":
+		return math.Round(math.Sin(number*radians)*1e10) / 1e10
+	case "cos":
+		return math.Round(math.Cos(number*radians)*1e10) / 1e10
+	case "tan":
+		return tan(number)
+	case "asin":
+		return math.Asin(number) / radians
+	case "acos":
+		return math.Acos(number) / radians
+	case "atan":
+		return math.Atan(number) / radians
+	case "ln":
+		return math.Log(number)
+	case "log":
+		return math.Log10(number)
+	case "e ^":
+		return math.Exp(number)
+	case "10 ^":
+		return math.Pow(10, number)
+	}
+	return 0
+}
+
+// mirrors `Scratch3OperatorsBlocks._tan` with its infinities
+func tan(angle float64) float64 {
+	angle = math.Mod(angle, 360)
+	switch angle {
+	case -270, 90:
+		return math.Inf(1)
+	case -90, 270:
+		return math.Inf(-1)
+	}
+	return math.Round(math.Tan(angle*math.Pi/180)*1e10) / 1e10
+}
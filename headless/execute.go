@@ -0,0 +1,358 @@
+package headless
+
+import (
+	"fmt"
+	"math"
+
+	"yummy-go.com/m/v2/scir"
+)
+
+func (s *thread) error(blockId string, message string, args ...any) error {
+	return &RuntimeError{
+		Target:  s.sprite.Target.Name,
+		BlockId: blockId,
+		Message: fmt.Sprintf(message, args...),
+	}
+}
+
+func (s *thread) step(blockId string) error {
+	runtime := s.sprite.runtime
+	runtime.steps += 1
+	if runtime.steps > runtime.MaxSteps {
+		return s.error(blockId, "exceeds %d steps", runtime.MaxSteps)
+	}
+	return nil
+}
+
+func (s *thread) block(blockId string) (*scir.Block, error) {
+	block, ok := s.sprite.Target.Blocks[blockId]
+	if !ok {
+		return nil, s.error(blockId, "block does not exist")
+	}
+	return block, nil
+}
+
+// executes a stack of blocks linked by `next`
+func (s *thread) executeStack(blockId string) error {
+	current := &blockId
+	for current != nil {
+		block, err := s.block(*current)
+		if err != nil {
+			return err
+		}
+		if err := s.execute(*current, block); err != nil {
+			return err
+		}
+		current = block.Next
+	}
+	return nil
+}
+
+func (s *thread) executeSubstack(block *scir.Block, name string) error {
+	substackId := s.sprite.inputBlockId(block, name)
+	if substackId == nil {
+		return nil
+	}
+	return s.executeStack(*substackId)
+}
+
+func (s *thread) execute(blockId string, block *scir.Block) error {
+	if err := s.step(blockId); err != nil {
+		return err
+	}
+	switch block.Opcode {
+	// control
+	case "control_if":
+		condition, err := s.evaluateInput(blockId, block, "CONDITION")
+		if err != nil {
+			return err
+		}
+		if ToBoolean(condition) {
+			return s.executeSubstack(block, "SUBSTACK")
+		}
+		return nil
+	case "control_if_else":
+		condition, err := s.evaluateInput(blockId, block, "CONDITION")
+		if err != nil {
+			return err
+		}
+		if ToBoolean(condition) {
+			return s.executeSubstack(block, "SUBSTACK")
+		}
+		return s.executeSubstack(block, "SUBSTACK2")
+	case "control_repeat":
+		times, err := s.evaluateInput(blockId, block, "TIMES")
+		if err != nil {
+			return err
+		}
+		for range int(math.Round(ToNumber(times))) {
+			if err := s.step(blockId); err != nil {
+				return err
+			}
+			if err := s.executeSubstack(block, "SUBSTACK"); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "control_repeat_until", "control_while":
+		for {
+			if err := s.step(blockId); err != nil {
+				return err
+			}
+			condition, err := s.evaluateInput(blockId, block, "CONDITION")
+			if err != nil {
+				return err
+			}
+			if ToBoolean(condition) == (block.Opcode == "control_repeat_until") {
+				return nil
+			}
+			if err := s.executeSubstack(block, "SUBSTACK"); err != nil {
+				return err
+			}
+		}
+	case "control_forever":
+		for {
+			if err := s.step(blockId); err != nil {
+				return err
+			}
+			if err := s.executeSubstack(block, "SUBSTACK"); err != nil {
+				return err
+			}
+		}
+	case "control_wait":
+		// there is no time in a headless run
+		return nil
+	case "control_wait_until":
+		condition, err := s.evaluateInput(blockId, block, "CONDITION")
+		if err != nil {
+			return err
+		}
+		if !ToBoolean(condition) {
+			return s.error(blockId, "waits forever since scripts never run concurrently")
+		}
+		return nil
+	case "control_stop":
+		switch block.Fields["STOP_OPTION"].Value {
+		case "all":
+			return errStopAll
+		case "this script":
+			return errStopScript
+		}
+		// other scripts never run at the same time
+		return nil
+	// events
+	case "event_broadcast", "event_broadcastandwait":
+		message, err := s.evaluateInput(blockId, block, "BROADCAST_INPUT")
+		if err != nil {
+			return err
+		}
+		runtime := s.sprite.runtime
+		if block.Opcode == "event_broadcast" {
+			runtime.broadcasts = append(runtime.broadcasts, ToString(message))
+			return nil
+		}
+		for _, sprite := range runtime.Sprites {
+			if err := sprite.runHats(sprite.receives(ToString(message))); err != nil {
+				return err
+			}
+		}
+		return nil
+	// procedures
+	case "procedures_call":
+		return s.executeCall(blockId, block)
+	// data
+	case "data_setvariableto", "data_changevariableby":
+		variables, id, err := s.variable(blockId, block)
+		if err != nil {
+			return err
+		}
+		value, err := s.evaluateInput(blockId, block, "VALUE")
+		if err != nil {
+			return err
+		}
+		if block.Opcode == "data_changevariableby" {
+			value = ToNumber(variables[id]) + ToNumber(value)
+		}
+		variables[id] = value
+		return nil
+	case "data_showvariable", "data_hidevariable", "data_showlist", "data_hidelist":
+		return nil
+	case "data_addtolist":
+		lists, id, err := s.list(blockId, block)
+		if err != nil {
+			return err
+		}
+		item, err := s.evaluateInput(blockId, block, "ITEM")
+		if err != nil {
+			return err
+		}
+		lists[id] = append(lists[id], item)
+		return nil
+	case "data_deleteoflist":
+		lists, id, err := s.list(blockId, block)
+		if err != nil {
+			return err
+		}
+		index, err := s.evaluateInput(blockId, block, "INDEX")
+		if err != nil {
+			return err
+		}
+		if ToString(index) == "all" {
+			lists[id] = lists[id][:0]
+			return nil
+		}
+		position, ok := s.listIndex(index, len(lists[id]), false)
+		if ok {
+			lists[id] = append(lists[id][:position], lists[id][position+1:]...)
+		}
+		return nil
+	case "data_deletealloflist":
+		lists, id, err := s.list(blockId, block)
+		if err != nil {
+			return err
+		}
+		lists[id] = lists[id][:0]
+		return nil
+	case "data_insertatlist":
+		lists, id, err := s.list(blockId, block)
+		if err != nil {
+			return err
+		}
+		item, err := s.evaluateInput(blockId, block, "ITEM")
+		if err != nil {
+			return err
+		}
+		index, err := s.evaluateInput(blockId, block, "INDEX")
+		if err != nil {
+			return err
+		}
+		position, ok := s.listIndex(index, len(lists[id]), true)
+		if ok {
+			lists[id] = append(lists[id][:position], append([]Value{item}, lists[id][position:]...)...)
+		}
+		return nil
+	case "data_replaceitemoflist":
+		lists, id, err := s.list(blockId, block)
+		if err != nil {
+			return err
+		}
+		item, err := s.evaluateInput(blockId, block, "ITEM")
+		if err != nil {
+			return err
+		}
+		index, err := s.evaluateInput(blockId, block, "INDEX")
+		if err != nil {
+			return err
+		}
+		position, ok := s.listIndex(index, len(lists[id]), false)
+		if ok {
+			lists[id][position] = item
+		}
+		return nil
+	// looks
+	case "looks_say", "looks_sayforsecs", "looks_think", "looks_thinkforsecs":
+		message, err := s.evaluateInput(blockId, block, "MESSAGE")
+		if err != nil {
+			return err
+		}
+		runtime := s.sprite.runtime
+		runtime.Said = append(runtime.Said, ToString(message))
+		return nil
+	}
+	return s.error(blockId, "unsupported opcode `%s`", block.Opcode)
+}
+
+func (s *thread) executeCall(blockId string, block *scir.Block) error {
+	if block.Mutation == nil || block.Mutation.ProcCode == nil {
+		return s.error(blockId, "procedure call without `proccode`")
+	}
+	theProcedure, ok := s.sprite.procedures[*block.Mutation.ProcCode]
+	if !ok {
+		return s.error(blockId, "undefined procedure `%s`", *block.Mutation.ProcCode)
+	}
+	// arguments are bound by name, as Scratch does
+	frame := make(map[string]Value)
+	for idx, argumentId := range theProcedure.argumentIds {
+		if idx >= len(theProcedure.argumentNames) {
+			break
+		}
+		value, err := s.evaluateInput(blockId, block, argumentId)
+		if err != nil {
+			return err
+		}
+		frame[theProcedure.argumentNames[idx]] = value
+	}
+	return s.callProcedure(theProcedure, frame)
+}
+
+func (s *thread) callProcedure(theProcedure *procedure, frame map[string]Value) error {
+	if uint(len(s.frames)) >= s.sprite.runtime.MaxCallDepth {
+		return s.error(theProcedure.definitionId, "exceeds %d nested calls", s.sprite.runtime.MaxCallDepth)
+	}
+	definition, err := s.block(theProcedure.definitionId)
+	if err != nil {
+		return err
+	}
+	if definition.Next == nil {
+		return nil
+	}
+	s.frames = append(s.frames, frame)
+	err = s.executeStack(*definition.Next)
+	s.frames = s.frames[:len(s.frames)-1]
+	if err == errStopScript {
+		// `stop this script` returns from the procedure
+		return nil
+	}
+	return err
+}
+
+func (s *thread) variable(blockId string, block *scir.Block) (map[string]Value, string, error) {
+	field, ok := block.Fields["VARIABLE"]
+	if !ok || field.Id == nil {
+		return nil, "", s.error(blockId, "missing field `VARIABLE`")
+	}
+	variables, ok := s.sprite.lookupVariable(*field.Id)
+	if !ok {
+		return nil, "", s.error(blockId, "variable `%s` does not exist", field.Value)
+	}
+	return variables, *field.Id, nil
+}
+
+func (s *thread) list(blockId string, block *scir.Block) (map[string][]Value, string, error) {
+	field, ok := block.Fields["LIST"]
+	if !ok || field.Id == nil {
+		return nil, "", s.error(blockId, "missing field `LIST`")
+	}
+	lists, ok := s.sprite.lookupList(*field.Id)
+	if !ok {
+		return nil, "", s.error(blockId, "list `%s` does not exist", field.Value)
+	}
+	return lists, *field.Id, nil
+}
+
+// mirrors `Cast.toListIndex` of scratch-vm, returns a zero-based position.
+// "last", "random" and "any" are case-sensitive, inserting accepts the
+// position right after the last item
+func (s *thread) listIndex(index Value, length int, inserting bool) (int, bool) {
+	limit := length
+	if inserting {
+		limit = length + 1
+	}
+	switch ToString(index) {
+	case "last":
+		if limit == 0 {
+			return 0, false
+		}
+		return limit - 1, true
+	case "random", "any":
+		if limit == 0 {
+			return 0, false
+		}
+		return s.sprite.runtime.random.IntN(limit), true
+	}
+	position := int(math.Floor(ToNumber(index)))
+	if position < 1 || position > limit {
+		return 0, false
+	}
+	return position - 1, true
+}
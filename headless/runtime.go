@@ -0,0 +1,317 @@
+package headless
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"yummy-go.com/m/v2/scir"
+)
+
+const (
+	DefaultMaxSteps     uint = 10_000_000
+	DefaultMaxCallDepth uint = 10_000
+)
+
+type RuntimeError struct {
+	Target  string
+	BlockId string
+	Message string
+}
+
+func (s *RuntimeError) Error() string {
+	return fmt.Sprintf("target `%s`: block `%s`: %s", s.Target, s.BlockId, s.Message)
+}
+
+// unwinds the execution for `stop this script` and `stop all`
+var (
+	errStopScript = errors.New("stop this script")
+	errStopAll    = errors.New("stop all")
+)
+
+// executes the block graph of a project without Scratch, scripts triggered
+// by the same event run to completion one after another as if they all run
+// without screen refresh
+type Runtime struct {
+	Sprites      []*Sprite
+	Stage        *Sprite
+	MaxSteps     uint
+	MaxCallDepth uint
+	// everything said or thought by the sprites, in order
+	Said       []string
+	steps      uint
+	broadcasts []string
+	random     *rand.Rand
+}
+
+type Sprite struct {
+	Target    *scir.Target
+	Variables map[string]Value
+	Lists     map[string][]Value
+	// procedure definitions by proccode
+	procedures map[string]*procedure
+	runtime    *Runtime
+}
+
+type procedure struct {
+	definitionId  string
+	argumentNames []string
+	argumentIds   []string
+}
+
+// a running script with its procedure arguments
+type thread struct {
+	sprite *Sprite
+	frames []map[string]Value
+}
+
+func New(project *scir.Project) (*Runtime, error) {
+	runtime := Runtime{
+		Sprites:      make([]*Sprite, 0),
+		MaxSteps:     DefaultMaxSteps,
+		MaxCallDepth: DefaultMaxCallDepth,
+		Said:         make([]string, 0),
+		broadcasts:   make([]string, 0),
+		random:       rand.New(rand.NewPCG(0, 0)),
+	}
+	for idx := range project.Targets {
+		sprite, err := newSprite(&runtime, &project.Targets[idx])
+		if err != nil {
+			return nil, err
+		}
+		if sprite.Target.IsStage {
+			runtime.Stage = sprite
+		}
+		runtime.Sprites = append(runtime.Sprites, sprite)
+	}
+	if runtime.Stage == nil {
+		return nil, fmt.Errorf("missing target `stage`")
+	}
+	// the stage runs its scripts after the sprites
+	slices.SortStableFunc(runtime.Sprites, func(a, b *Sprite) int {
+		if a.Target.IsStage == b.Target.IsStage {
+			return 0
+		} else if a.Target.IsStage {
+			return 1
+		}
+		return -1
+	})
+	return &runtime, nil
+}
+
+func newSprite(runtime *Runtime, target *scir.Target) (*Sprite, error) {
+	sprite := Sprite{
+		Target:     target,
+		Variables:  make(map[string]Value),
+		Lists:      make(map[string][]Value),
+		procedures: make(map[string]*procedure),
+		runtime:    runtime,
+	}
+	for id, variable := range target.Variables {
+		sprite.Variables[id] = variable.Value
+	}
+	for id, list := range target.Lists {
+		items := make([]Value, 0, len(list.Value))
+		for _, item := range list.Value {
+			items = append(items, item)
+		}
+		sprite.Lists[id] = items
+	}
+	for blockId, block := range target.Blocks {
+		if block.Opcode != "procedures_definition" {
+			continue
+		}
+		prototype := sprite.inputBlock(block, "custom_block")
+		if prototype == nil || prototype.Mutation == nil || prototype.Mutation.ProcCode == nil {
+			return nil, &RuntimeError{
+				Target:  target.Name,
+				BlockId: blockId,
+				Message: "procedure definition without prototype",
+			}
+		}
+		theProcedure := procedure{
+			definitionId:  blockId,
+			argumentNames: make([]string, 0),
+			argumentIds:   make([]string, 0),
+		}
+		if prototype.Mutation.ArgumentNames != nil {
+			json.Unmarshal([]byte(*prototype.Mutation.ArgumentNames), &theProcedure.argumentNames)
+		}
+		if prototype.Mutation.ArgumentIds != nil {
+			json.Unmarshal([]byte(*prototype.Mutation.ArgumentIds), &theProcedure.argumentIds)
+		}
+		sprite.procedures[*prototype.Mutation.ProcCode] = &theProcedure
+	}
+	return &sprite, nil
+}
+
+func (s *Runtime) LookupSprite(name string) *Sprite {
+	for _, sprite := range s.Sprites {
+		if sprite.Target.Name == name {
+			return sprite
+		}
+	}
+	return nil
+}
+
+// runs every `when green flag clicked` script and the broadcasts they send
+func (s *Runtime) GreenFlag() error {
+	return s.runHats(func(block *scir.Block) bool {
+		return block.Opcode == "event_whenflagclicked"
+	})
+}
+
+// runs every `when I receive` script of the message and the broadcasts they send
+func (s *Runtime) Broadcast(message string) error {
+	s.broadcasts = append(s.broadcasts, message)
+	return s.runHats(func(*scir.Block) bool {
+		return false
+	})
+}
+
+func (s *Runtime) runHats(match func(block *scir.Block) bool) error {
+	for _, sprite := range s.Sprites {
+		if err := sprite.runHats(match); err != nil {
+			return s.stopped(err)
+		}
+	}
+	for len(s.broadcasts) > 0 {
+		message := s.broadcasts[0]
+		s.broadcasts = s.broadcasts[1:]
+		for _, sprite := range s.Sprites {
+			if err := sprite.runHats(sprite.receives(message)); err != nil {
+				return s.stopped(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Runtime) stopped(err error) error {
+	if err == errStopAll {
+		s.broadcasts = s.broadcasts[:0]
+		return nil
+	}
+	return err
+}
+
+// calls a custom block of the sprite with the values of its arguments
+func (s *Sprite) Call(procCode string, arguments ...Value) error {
+	theProcedure, ok := s.procedures[procCode]
+	if !ok {
+		return fmt.Errorf("target `%s`: undefined procedure `%s`", s.Target.Name, procCode)
+	}
+	if len(arguments) != len(theProcedure.argumentNames) {
+		return fmt.Errorf("target `%s`: `%s` takes %d arguments", s.Target.Name, procCode, len(theProcedure.argumentNames))
+	}
+	frame := make(map[string]Value)
+	for idx, name := range theProcedure.argumentNames {
+		frame[name] = arguments[idx]
+	}
+	thread := thread{sprite: s}
+	err := thread.callProcedure(theProcedure, frame)
+	return s.runtime.stopped(err)
+}
+
+// looks up a variable of the sprite, then of the stage, by name
+func (s *Sprite) Variable(name string) (Value, bool) {
+	for _, sprite := range []*Sprite{s, s.runtime.Stage} {
+		for id, variable := range sprite.Target.Variables {
+			if variable.Name == name {
+				return sprite.Variables[id], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// looks up a list of the sprite, then of the stage, by name
+func (s *Sprite) List(name string) ([]Value, bool) {
+	for _, sprite := range []*Sprite{s, s.runtime.Stage} {
+		for id, list := range sprite.Target.Lists {
+			if list.Name == name {
+				return sprite.Lists[id], true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (s *Sprite) receives(message string) func(*scir.Block) bool {
+	return func(block *scir.Block) bool {
+		if block.Opcode != "event_whenbroadcastreceived" {
+			return false
+		}
+		// Scratch matches messages ignoring their case
+		field, ok := block.Fields["BROADCAST_OPTION"]
+		return ok && strings.EqualFold(field.Value, message)
+	}
+}
+
+func (s *Sprite) runHats(match func(block *scir.Block) bool) error {
+	// like scratch-vm, scripts start in the order they were added to the
+	// target, their position in the editor does not matter
+	hatIds := make([]string, 0)
+	for _, blockId := range s.Target.BlockIds() {
+		if block := s.Target.Blocks[blockId]; block.TopLevel && match(block) {
+			hatIds = append(hatIds, blockId)
+		}
+	}
+	for _, hatId := range hatIds {
+		thread := thread{sprite: s}
+		hat := s.Target.Blocks[hatId]
+		if hat.Next == nil {
+			continue
+		}
+		if err := thread.executeStack(*hat.Next); err != nil && err != errStopScript {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Sprite) lookupVariable(id string) (map[string]Value, bool) {
+	if _, ok := s.Variables[id]; ok {
+		return s.Variables, true
+	}
+	if _, ok := s.runtime.Stage.Variables[id]; ok {
+		return s.runtime.Stage.Variables, true
+	}
+	return nil, false
+}
+
+func (s *Sprite) lookupList(id string) (map[string][]Value, bool) {
+	if _, ok := s.Lists[id]; ok {
+		return s.Lists, true
+	}
+	if _, ok := s.runtime.Stage.Lists[id]; ok {
+		return s.runtime.Stage.Lists, true
+	}
+	return nil, false
+}
+
+// returns the block an input refers to, preferring the obscuring block
+func (s *Sprite) inputBlock(block *scir.Block, name string) *scir.Block {
+	blockId := s.inputBlockId(block, name)
+	if blockId == nil {
+		return nil
+	}
+	return s.Target.Blocks[*blockId]
+}
+
+func (s *Sprite) inputBlockId(block *scir.Block, name string) *string {
+	input, ok := block.Inputs[name]
+	if !ok {
+		return nil
+	}
+	for _, input := range []scir.Input{input.ObscuredInput, input.ShadowedInput} {
+		if blockInput, ok := input.(*scir.BlockInput); ok && blockInput != nil {
+			blockId := string(*blockInput)
+			return &blockId
+		}
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+package headless_test
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"yummy-go.com/m/v2/headless"
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/omitter"
+	"yummy-go.com/m/v2/scir"
+)
+
+// omits a mir program into the stage of a new project
+func compile(t *testing.T, path string, source string) *headless.Runtime {
+	t.Helper()
+	program, err := mir.Parse(path, source)
+	if err != nil {
+		t.Fatalf("parse %s: %s", path, err)
+	}
+	project := scir.NewProject()
+	theOmitter := omitter.New(&project)
+	theOmitter.SetTarget("Stage")
+	if err := theOmitter.Omit(program); err != nil {
+		t.Fatalf("omit %s: %s", path, err)
+	}
+	if err := scir.Validate(&project); err != nil {
+		t.Fatalf("validate %s: %s", path, err)
+	}
+	runtime, err := headless.New(&project.Ir)
+	if err != nil {
+		t.Fatalf("load %s: %s", path, err)
+	}
+	return runtime
+}
+
+func expectVariable(t *testing.T, sprite *headless.Sprite, name string, expected string) {
+	t.Helper()
+	value, ok := sprite.Variable(name)
+	if !ok {
+		t.Fatalf("missing variable `%s`", name)
+	}
+	if actual := headless.ToString(value); actual != expected {
+		t.Errorf("variable `%s`: expected %s, found %s", name, expected, actual)
+	}
+}
+
+const identity = `func identity(x: number @0 slots(0 "identityArgument0000")) -> number @0 slots(1 "identityReturn000000") stack 0 proccode "identity %s" {
+    return x
+}
+
+func main() -> number @0 slots(2 "mainReturn0000000000") stack 0 proccode "main" {
+    var value: number @0
+    value = identity(7)
+    return value
+}
+`
+
+func TestRunIdentity(t *testing.T) {
+	runtime := compile(t, "identity.mir", identity)
+	if err := runtime.Stage.Call("identity %s", "-1.5"); err != nil {
+		t.Fatal(err)
+	}
+	expectVariable(t, runtime.Stage, "identity.ret0", "-1.5")
+	if err := runtime.Stage.Call("main"); err != nil {
+		t.Fatal(err)
+	}
+	// the return value of `identity` is read before `main` returns, so
+	// they share the register
+	expectVariable(t, runtime.Stage, "identity.ret0", "7")
+}
+
+func TestRunSquares(t *testing.T) {
+	source, err := os.ReadFile("../examples/squares.mir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime := compile(t, "squares.mir", string(source))
+	if err := runtime.Stage.Call("main"); err != nil {
+		t.Fatal(err)
+	}
+	// 3*3 + 4*4, then the two calls counted in the global. `main` returns
+	// in the register it shares with `square`
+	expectVariable(t, runtime.Stage, "counter", "2")
+	expectVariable(t, runtime.Stage, "square.ret0", "225")
+	if err := runtime.Stage.Call("main"); err != nil {
+		t.Fatal(err)
+	}
+	expectVariable(t, runtime.Stage, "counter", "4")
+	expectVariable(t, runtime.Stage, "square.ret0", "425")
+}
+
+const counting = `global counter: number @0 slots(0 "counterSlot000000000")
+
+func count() -> number @0 slots(1 "countReturn000000000") stack 0 proccode "count" {
+    counter = (counter + 1)
+    return (count() + 1)
+}
+`
+
+func TestRunGlobalInRecursiveFunction(t *testing.T) {
+	runtime := compile(t, "counting.mir", counting)
+	runtime.MaxCallDepth = 10
+	if err := runtime.Stage.Call("count"); err == nil {
+		t.Fatal("expected the call depth to be exceeded")
+	}
+	// the global is a variable, not a cell of the frames on `_Stack`
+	expectVariable(t, runtime.Stage, "counter", "10")
+}
+
+const early = `func early() -> number @0 slots(0 "earlyReturn000000000") stack 0 proccode "early" {
+    var value: number @0
+    value = 1
+    return value
+    value = 2
+    return value
+}
+`
+
+func TestRunEarlyReturn(t *testing.T) {
+	runtime := compile(t, "early.mir", early)
+	if err := runtime.Stage.Call("early"); err != nil {
+		t.Fatal(err)
+	}
+	expectVariable(t, runtime.Stage, "early.ret0", "1")
+}
+
+const sum = `func sum(n: number @0 slots(0 "sumArgument000000000"), acc: number @1 slots(1 "sumArgument100000000")) -> number @0 slots(2 "sumReturn00000000000") stack 0 proccode "sum %s %s" {
+    if (n < 1) {
+        return acc
+    }
+    return sum((n - 1), (acc + n))
+}
+
+func parity(n: number @0 slots(3 "parityArgument000000")) -> string @0 slots(4 "parityReturn00000000") stack 0 proccode "parity %s" {
+    if (n < 2) {
+        if (n == 0) {
+            return "even"
+        } else {
+            return "odd"
+        }
+    } else {
+        return parity((n - 2))
+    }
+}
+`
+
+func TestRunTailCalls(t *testing.T) {
+	runtime := compile(t, "sum.mir", sum)
+	// the tail calls became loops, which end by the returns in the branches
+	runtime.MaxCallDepth = 5
+	if err := runtime.Stage.Call("sum %s %s", "100", "0"); err != nil {
+		t.Fatal(err)
+	}
+	expectVariable(t, runtime.Stage, "sum.ret0", "5050")
+	if err := runtime.Stage.Call("parity %s", "7"); err != nil {
+		t.Fatal(err)
+	}
+	// the functions never run at the same time, so they share the register
+	expectVariable(t, runtime.Stage, "sum.ret0", "odd")
+}
+
+func TestRunHello(t *testing.T) {
+	source, err := os.ReadFile("../examples/hello.mir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime := compile(t, "hello.mir", string(source))
+	// `Hello` calls itself without end
+	runtime.MaxCallDepth = 100
+	if err := runtime.Stage.Call("Hello(world: %s %s )", "a", "b"); err == nil {
+		t.Fatal("expected the call depth to be exceeded")
+	}
+}
+
+// a project with only a stage, scripts are added by the tests
+type project struct {
+	scir.Scir
+}
+
+func newProject() *project {
+	theProject := project{scir.NewProject()}
+	theProject.SetEditingTarget("Stage")
+	return &theProject
+}
+
+func (s *project) hat(opcode string, x, y float64, fields map[string]scir.Field) string {
+	return s.InsertBlock(&scir.Block{
+		Opcode:   opcode,
+		Inputs:   make(map[string]scir.MaybeShadowedInput),
+		Fields:   fields,
+		TopLevel: true,
+		X:        &x,
+		Y:        &y,
+	})
+}
+
+func (s *project) say(message string) string {
+	return s.InsertBlock(&scir.Block{
+		Opcode: "looks_say",
+		Inputs: map[string]scir.MaybeShadowedInput{
+			"MESSAGE": {
+				Type:          scir.Shadow,
+				ShadowedInput: &scir.StringInput{Type: scir.InputString, Value: message},
+			},
+		},
+		Fields: make(map[string]scir.Field),
+	})
+}
+
+func (s *project) broadcast(message string) string {
+	return s.InsertBlock(&scir.Block{
+		Opcode: "event_broadcast",
+		Inputs: map[string]scir.MaybeShadowedInput{
+			"BROADCAST_INPUT": {
+				Type:          scir.Shadow,
+				ShadowedInput: &scir.BroadcastInput{Value: message, Id: message},
+			},
+		},
+		Fields: make(map[string]scir.Field),
+	})
+}
+
+func (s *project) run(t *testing.T) *headless.Runtime {
+	t.Helper()
+	runtime, err := headless.New(&s.Ir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.GreenFlag(); err != nil {
+		t.Fatal(err)
+	}
+	return runtime
+}
+
+func TestBroadcastIgnoresCase(t *testing.T) {
+	theProject := newProject()
+	flag := theProject.hat("event_whenflagclicked", 0, 0, map[string]scir.Field{})
+	theProject.ConnectBlocks(flag, theProject.broadcast("Start"))
+	received := theProject.hat("event_whenbroadcastreceived", 0, 100, map[string]scir.Field{
+		"BROADCAST_OPTION": {Value: "START"},
+	})
+	theProject.ConnectBlocks(received, theProject.say("received"))
+	runtime := theProject.run(t)
+	if !slices.Equal(runtime.Said, []string{"received"}) {
+		t.Errorf("expected the broadcast to be received, said %q", runtime.Said)
+	}
+}
+
+func TestHatOrder(t *testing.T) {
+	theProject := newProject()
+	// like scratch-vm, the scripts added first run first, wherever they are
+	for _, script := range []struct {
+		message string
+		x, y    float64
+	}{
+		{"first", 0, 200},
+		{"second", 300, 0},
+		{"third", 0, 0},
+		{"fourth", 100, 200},
+	} {
+		hat := theProject.hat("event_whenflagclicked", script.x, script.y, map[string]scir.Field{})
+		theProject.ConnectBlocks(hat, theProject.say(script.message))
+	}
+	expected := []string{"first", "second", "third", "fourth"}
+	for range 10 {
+		runtime := theProject.run(t)
+		if !slices.Equal(runtime.Said, expected) {
+			t.Fatalf("expected %q, said %q", expected, runtime.Said)
+		}
+	}
+	// the order is the one of the blocks in the project.json
+	content, err := json.Marshal(theProject.Ir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded scir.Project
+	if err := json.Unmarshal(content, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	runtime, err := headless.New(&loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.GreenFlag(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(runtime.Said, expected) {
+		t.Errorf("expected %q after loading, said %q", expected, runtime.Said)
+	}
+}
+
+func TestListIndexIsCaseSensitive(t *testing.T) {
+	theProject := newProject()
+	theProject.StageTarget.Lists["listId"] = scir.List{Name: "items", Value: []string{"a", "b"}}
+	flag := theProject.hat("event_whenflagclicked", 0, 0, map[string]scir.Field{})
+	previous := flag
+	for _, index := range []string{"last", "LAST", "Random"} {
+		item := theProject.InsertBlock(&scir.Block{
+			Opcode: "data_itemoflist",
+			Inputs: map[string]scir.MaybeShadowedInput{
+				"INDEX": {
+					Type:          scir.Shadow,
+					ShadowedInput: &scir.StringInput{Type: scir.InputString, Value: index},
+				},
+			},
+			Fields: map[string]scir.Field{"LIST": {Value: "items", Id: &[]string{"listId"}[0]}},
+		})
+		say := theProject.say("")
+		itemInput := scir.BlockInput(item)
+		theProject.EditingTarget.Blocks[say].Inputs["MESSAGE"] = scir.MaybeShadowedInput{
+			Type:          scir.Shadowed,
+			ObscuredInput: &itemInput,
+			ShadowedInput: &scir.StringInput{Type: scir.InputString, Value: ""},
+		}
+		theProject.EditingTarget.Blocks[item].Parent = &say
+		theProject.ConnectBlocks(previous, say)
+		previous = say
+	}
+	runtime := theProject.run(t)
+	if expected := []string{"b", "", ""}; !slices.Equal(runtime.Said, expected) {
+		t.Errorf("expected %q, said %q", expected, runtime.Said)
+	}
+}
+
+const negate = `func negate(x: number @0 slots(0 "negateArgument000000")) -> number @0 slots(1 "negateReturn00000000") stack 0 proccode "negate %s" {
+    return ((- x) + (x * 2))
+}
+
+func greet(name: string @0 slots(2 "greetArgument0000000")) -> string @0 slots(3 "greetReturn000000000") stack 0 proccode "greet %s" {
+    if (name == "") {
+        return "nobody"
+    }
+    return ("hi " + name: string)
+}
+`
+
+// operands obscure the shadows the editor shows once they are dragged out,
+// the missing operand of a negation is a 0 shadow
+func TestOperatorShadows(t *testing.T) {
+	runtime := compile(t, "negate.mir", negate)
+	target := runtime.Stage.Target
+	operators, negations := 0, 0
+	for blockId, block := range target.Blocks {
+		for name, input := range block.Inputs {
+			if !strings.HasPrefix(name, "NUM") && !strings.HasPrefix(name, "STRING") && block.Opcode != "operator_equals" {
+				continue
+			}
+			operators += 1
+			if input.ShadowedInput == nil {
+				t.Errorf("input `%s` of `%s` (%s) has no shadow", name, block.Opcode, blockId)
+			}
+		}
+		if block.Opcode == "operator_subtract" && block.Inputs["NUM2"].ObscuredInput != nil && block.Inputs["NUM1"].ObscuredInput == nil {
+			negations += 1
+			if number, ok := block.Inputs["NUM1"].ShadowedInput.(*scir.NumberalInput); !ok || number.Value != 0 || block.Inputs["NUM1"].Type != scir.Shadow {
+				t.Errorf("expected a 0 shadow in NUM1 of the negation, found %+v", block.Inputs["NUM1"])
+			}
+		}
+	}
+	if operators == 0 || negations != 1 {
+		t.Fatalf("expected operator inputs and a negation, found %d inputs and %d negations", operators, negations)
+	}
+	if err := runtime.Stage.Call("negate %s", "5"); err != nil {
+		t.Fatal(err)
+	}
+	expectVariable(t, runtime.Stage, "negate.ret0", "5")
+	if err := runtime.Stage.Call("greet %s", "ada"); err != nil {
+		t.Fatal(err)
+	}
+	expectVariable(t, runtime.Stage, "negate.ret0", "hi ada")
+}
@@ -0,0 +1,196 @@
+package headless
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// a Scratch value is either a float64, a string or a bool
+type Value any
+
+// mirrors `Cast.toNumber` of scratch-vm
+func ToNumber(value Value) float64 {
+	switch value := value.(type) {
+	case float64:
+		if math.IsNaN(value) {
+			return 0
+		}
+		return value
+	case bool:
+		if value {
+			return 1
+		}
+		return 0
+	case string:
+		number := jsNumber(value)
+		if math.IsNaN(number) {
+			return 0
+		}
+		return number
+	}
+	return 0
+}
+
+// mirrors `Cast.toBoolean` of scratch-vm
+func ToBoolean(value Value) bool {
+	switch value := value.(type) {
+	case bool:
+		return value
+	case float64:
+		return value != 0 && !math.IsNaN(value)
+	case string:
+		if value == "" || value == "0" || strings.ToLower(value) == "false" {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// mirrors `Cast.toString` of scratch-vm
+func ToString(value Value) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return formatNumber(value)
+	case bool:
+		if value {
+			return "true"
+		}
+		return "false"
+	}
+	return ""
+}
+
+func isWhiteSpace(value Value) bool {
+	str, ok := value.(string)
+	return ok && strings.TrimSpace(str) == ""
+}
+
+// mirrors `Cast.compare` of scratch-vm: numbers are compared numerically,
+// anything else case-insensitively as strings
+func Compare(lhs, rhs Value) float64 {
+	n1, n2 := jsNumberOf(lhs), jsNumberOf(rhs)
+	if n1 == 0 && isWhiteSpace(lhs) {
+		n1 = math.NaN()
+	} else if n2 == 0 && isWhiteSpace(rhs) {
+		n2 = math.NaN()
+	}
+	if math.IsNaN(n1) || math.IsNaN(n2) {
+		s1 := strings.ToLower(ToString(lhs))
+		s2 := strings.ToLower(ToString(rhs))
+		if s1 < s2 {
+			return -1
+		} else if s1 > s2 {
+			return 1
+		}
+		return 0
+	}
+	if (math.IsInf(n1, 1) && math.IsInf(n2, 1)) || (math.IsInf(n1, -1) && math.IsInf(n2, -1)) {
+		return 0
+	}
+	return n1 - n2
+}
+
+// mirrors `Cast.isInt` of scratch-vm
+func isInt(value Value) bool {
+	switch value := value.(type) {
+	case float64:
+		if math.IsNaN(value) {
+			return true
+		}
+		return value == math.Trunc(value)
+	case bool:
+		return true
+	case string:
+		return !strings.Contains(value, ".")
+	}
+	return false
+}
+
+// `Number(value)` of javascript
+func jsNumberOf(value Value) float64 {
+	switch value := value.(type) {
+	case float64:
+		return value
+	case bool:
+		if value {
+			return 1
+		}
+		return 0
+	case string:
+		return jsNumber(value)
+	}
+	return math.NaN()
+}
+
+// `Number(str)` of javascript, which differs from `strconv.ParseFloat` on
+// blank strings, `Infinity`, radix prefixes and underscores
+func jsNumber(str string) float64 {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return 0
+	}
+	switch str {
+	case "Infinity", "+Infinity":
+		return math.Inf(1)
+	case "-Infinity":
+		return math.Inf(-1)
+	}
+	if len(str) > 2 && str[0] == '0' {
+		base := 0
+		switch str[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 0 {
+			number, err := strconv.ParseUint(str[2:], base, 64)
+			if err != nil || strings.Contains(str, "_") {
+				return math.NaN()
+			}
+			return float64(number)
+		}
+	}
+	for _, char := range str {
+		if !strings.ContainsRune("0123456789+-.eE", char) {
+			return math.NaN()
+		}
+	}
+	number, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			return number
+		}
+		return math.NaN()
+	}
+	return number
+}
+
+// formats a number the way javascript converts it into a string
+func formatNumber(number float64) string {
+	switch {
+	case math.IsNaN(number):
+		return "NaN"
+	case math.IsInf(number, 1):
+		return "Infinity"
+	case math.IsInf(number, -1):
+		return "-Infinity"
+	case number == 0:
+		return "0"
+	}
+	abs := math.Abs(number)
+	if abs >= 1e21 || abs < 1e-6 {
+		formatted := strconv.FormatFloat(number, 'e', -1, 64)
+		mantissa, exponent, _ := strings.Cut(formatted, "e")
+		sign := exponent[0]
+		exponent = strings.TrimLeft(exponent[1:], "0")
+		return mantissa + "e" + string(sign) + exponent
+	}
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
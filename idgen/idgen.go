@@ -0,0 +1,47 @@
+package idgen
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"strings"
+)
+
+// Scratch ids are 20 characters long, we stick to alphanumerics
+const (
+	idLength = 20
+	alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// derives a stable id from the parts, the same parts always give the same id
+func Derive(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	id := make([]byte, idLength)
+	for idx := range id {
+		id[idx] = alphabet[int(sum[idx])%len(alphabet)]
+	}
+	return string(id)
+}
+
+// derives ids for consecutive items within scopes, the n-th id of a scope
+// does not depend on what happens in other scopes
+type Generator struct {
+	seed     string
+	counters map[string]uint
+}
+
+func New(seed string) Generator {
+	return Generator{
+		seed:     seed,
+		counters: make(map[string]uint),
+	}
+}
+
+func (s *Generator) Next(scope ...string) string {
+	if s.counters == nil {
+		s.counters = make(map[string]uint)
+	}
+	key := strings.Join(scope, "\x00")
+	n := s.counters[key]
+	s.counters[key] = n + 1
+	return Derive(append([]string{s.seed}, append(scope, strconv.FormatUint(uint64(n), 10))...)...)
+}
@@ -0,0 +1,51 @@
+package idgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDerive(t *testing.T) {
+	id := Derive("list", "_Stack")
+	if len(id) != idLength {
+		t.Errorf("expected %d characters, found %q", idLength, id)
+	}
+	for _, char := range id {
+		if !strings.ContainsRune(alphabet, char) {
+			t.Errorf("unexpected character %q in %q", char, id)
+		}
+	}
+	if again := Derive("list", "_Stack"); again != id {
+		t.Errorf("the same parts gave %q then %q", id, again)
+	}
+	// the parts are separated, so moving characters across them matters
+	if Derive("ab", "c") == Derive("a", "bc") {
+		t.Error("different parts gave the same id")
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	lhs, rhs := New("seed"), New("seed")
+	lhsIds := []string{lhs.Next("f"), lhs.Next("f"), lhs.Next("g")}
+	// another order of the scopes, the n-th id of each scope stays the same
+	rhsG := rhs.Next("g")
+	rhsIds := []string{rhs.Next("f"), rhs.Next("f"), rhsG}
+	for idx := range lhsIds {
+		if lhsIds[idx] != rhsIds[idx] {
+			t.Errorf("id %d: %q differs from %q", idx, lhsIds[idx], rhsIds[idx])
+		}
+	}
+	if lhsIds[0] == lhsIds[1] {
+		t.Errorf("consecutive ids of a scope are equal: %q", lhsIds[0])
+	}
+	other := New("other")
+	if other.Next("f") == lhsIds[0] {
+		t.Error("different seeds gave the same id")
+	}
+	// the zero value is usable
+	var zero Generator
+	empty := New("")
+	if zero.Next("f") != empty.Next("f") {
+		t.Error("the zero value differs from an empty seed")
+	}
+}
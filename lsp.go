@@ -0,0 +1,24 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"yummy-go.com/m/v2/lsp"
+)
+
+func runLsp(args []string) int {
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "usage: yummy lsp")
+		return 2
+	}
+	// stdout carries the protocol, problems of the server go to stderr
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fmt.Fprintf(os.Stderr, "yummy lsp: %s\n", err.Error())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package lsp
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"yummy-go.com/m/v2/checker"
+	"yummy-go.com/m/v2/frontend"
+	"yummy-go.com/m/v2/span"
+)
+
+// an open .yum file and what was found in its last text
+type document struct {
+	uri  string
+	path string
+	text string
+	// the source index of the start of each line
+	lineStarts  []uint
+	program     frontend.Program
+	info        *checker.Info
+	diagnostics []Diagnostic
+}
+
+func newDocument(uri string, text string) *document {
+	theDocument := &document{
+		uri:  uri,
+		path: uriPath(uri),
+	}
+	theDocument.update(text)
+	return theDocument
+}
+
+// file URIs become paths, other URIs are kept as they are
+func uriPath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+	return parsed.Path
+}
+
+func (s *document) update(text string) {
+	s.text = text
+	s.lineStarts = []uint{0}
+	for idx := 0; idx < len(text); idx += 1 {
+		if text[idx] == '\n' {
+			s.lineStarts = append(s.lineStarts, uint(idx+1))
+		}
+	}
+	s.analyze()
+}
+
+// parses and checks the text, the reports become diagnostics
+func (s *document) analyze() {
+	s.diagnostics = make([]Diagnostic, 0)
+	parsed := true
+	previous := span.SetReporter(func(level span.ReportLevel, theSpan *span.Span, message string) {
+		// problems found in a broken program mostly follow from the breakage
+		if !parsed {
+			return
+		}
+		s.addDiagnostic(level, theSpan, message)
+	})
+	defer span.SetReporter(previous)
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			s.addDiagnostic(span.Error, nil, fmt.Sprintf("internal error: %v", recovered))
+		}
+	}()
+	s.info = nil
+	lexer := frontend.NewLexer(s.path, s.text)
+	parser := frontend.NewParser(lexer)
+	program, err := parser.ParseProgram()
+	s.program = program
+	parsed = err == nil
+	s.info, _ = checker.Check(program)
+}
+
+func (s *document) addDiagnostic(level span.ReportLevel, theSpan *span.Span, message string) {
+	diagnostic := Diagnostic{
+		Severity: SeverityError,
+		Source:   "yummy",
+		Message:  strings.TrimPrefix(message, s.path+": "),
+	}
+	switch level {
+	case span.Warn:
+		diagnostic.Severity = SeverityWarning
+	case span.Info:
+		diagnostic.Severity = SeverityInformation
+	}
+	if theSpan != nil {
+		diagnostic.Range = s.spanRange(*theSpan)
+	} else {
+		// reports without a span are mostly about the end of the file
+		end := s.position(uint(len(s.text)))
+		diagnostic.Range = Range{Start: end, End: end}
+	}
+	for _, other := range s.diagnostics {
+		if other == diagnostic {
+			return
+		}
+	}
+	s.diagnostics = append(s.diagnostics, diagnostic)
+}
+
+func (s *document) spanRange(theSpan span.Span) Range {
+	return Range{
+		Start: s.position(theSpan.From.Index),
+		End:   s.position(theSpan.To.Index),
+	}
+}
+
+// positions count the UTF-16 units of the line, as editors do
+func (s *document) position(index uint) Position {
+	index = min(index, uint(len(s.text)))
+	line := uint(0)
+	for line+1 < uint(len(s.lineStarts)) && s.lineStarts[line+1] <= index {
+		line += 1
+	}
+	character := uint(0)
+	for _, char := range s.text[s.lineStarts[line]:index] {
+		character += uint(utf16.RuneLen(char))
+	}
+	return Position{Line: line, Character: character}
+}
+
+// the source index of a position, clamped to its line
+func (s *document) index(position Position) uint {
+	if position.Line >= uint(len(s.lineStarts)) {
+		return uint(len(s.text))
+	}
+	index := s.lineStarts[position.Line]
+	character := uint(0)
+	for index < uint(len(s.text)) && character < position.Character {
+		char, size := utf8.DecodeRuneInString(s.text[index:])
+		if char == '\n' {
+			break
+		}
+		character += uint(utf16.RuneLen(char))
+		index += uint(size)
+	}
+	return index
+}
@@ -0,0 +1,223 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"yummy-go.com/m/v2/checker"
+	"yummy-go.com/m/v2/frontend"
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/span"
+)
+
+var keywords = []string{
+	"target", "func", "var", "return", "if", "else", "for", "struct",
+	"costume", "sound", "from", "true", "false", "number", "string", "bool",
+	"when", "raw", "inline",
+}
+
+func (s *document) definition(position Position) *Location {
+	if s.info == nil {
+		return nil
+	}
+	reference := s.info.ReferenceAt(s.index(position))
+	if reference == nil || reference.Symbol.Definition == nil {
+		return nil
+	}
+	return &Location{
+		Uri:   s.uri,
+		Range: s.spanRange(*reference.Symbol.Definition),
+	}
+}
+
+func (s *document) hover(position Position) *Hover {
+	if s.info == nil {
+		return nil
+	}
+	reference := s.info.ReferenceAt(s.index(position))
+	if reference == nil {
+		return nil
+	}
+	symbol := reference.Symbol
+	value := "```yummy\n" + signature(symbol) + "\n```"
+	if symbol.Doc != "" {
+		value += "\n\n" + symbol.Doc
+	}
+	if layout := typeViewLayout(symbol); layout != "" {
+		value += "\n\n" + layout
+	}
+	theRange := s.spanRange(reference.Span)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: value},
+		Range:    &theRange,
+	}
+}
+
+// the symbol as it would be declared
+func signature(symbol *checker.Symbol) string {
+	switch symbol.Kind {
+	case checker.FunctionSymbol, checker.BuiltinSymbol:
+		arguments := make([]string, 0, len(symbol.Arguments))
+		for _, argument := range symbol.Arguments {
+			arguments = append(arguments, argument.Name+" "+checker.FormatType(argument.TypeView.Type))
+		}
+		result := fmt.Sprintf("func %s(%s)", symbol.Name, strings.Join(arguments, ", "))
+		if symbol.TypeView.Type != nil {
+			result += " " + checker.FormatType(symbol.TypeView.Type)
+		}
+		return result
+	case checker.FieldSymbol:
+		return "field " + symbol.Name + " " + checker.FormatType(symbol.TypeView.Type)
+	case checker.ArgumentSymbol:
+		return "argument " + symbol.Name + " " + checker.FormatType(symbol.TypeView.Type)
+	}
+	return "var " + symbol.Name + " " + checker.FormatType(symbol.TypeView.Type)
+}
+
+// how the value is laid out in mir, from its type view
+func typeViewLayout(symbol *checker.Symbol) string {
+	if symbol.Kind == checker.BuiltinSymbol {
+		return fmt.Sprintf("Scratch block `%s`", symbol.Builtin.Opcode)
+	}
+	theType := symbol.TypeView.Type
+	if theType == nil {
+		return ""
+	}
+	layout := "mir `" + mir.FormatType(theType) + "`"
+	if size := theType.GetSize(); size != nil {
+		layout += ", " + span.Pluralize(*size, "cell", "cells")
+	} else {
+		layout += ", no fixed size"
+	}
+	if symbol.Kind == checker.FieldSymbol {
+		layout += fmt.Sprintf(" at offset %d", symbol.TypeView.Offset)
+	}
+	return layout
+}
+
+// `a.b.` or `a.b.pa` right before the cursor
+var memberChain = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*)\.[A-Za-z0-9_]*$`)
+
+func (s *document) completion(position Position) []CompletionItem {
+	items := make([]CompletionItem, 0)
+	index := s.index(position)
+	linePrefix := s.text[s.lineStarts[min(position.Line, uint(len(s.lineStarts)-1))]:index]
+	if s.info == nil {
+		return items
+	}
+	if match := memberChain.FindStringSubmatch(linePrefix); match != nil {
+		return s.memberCompletion(index, strings.Split(match[1], "."))
+	}
+	for _, keyword := range keywords {
+		items = append(items, CompletionItem{Label: keyword, Kind: CompletionKeyword})
+	}
+	for _, symbol := range s.info.Visible(index) {
+		item := CompletionItem{
+			Label:         symbol.Name,
+			Kind:          CompletionVariable,
+			Detail:        signature(symbol),
+			Documentation: symbol.Doc,
+		}
+		if symbol.Kind == checker.FunctionSymbol || symbol.Kind == checker.BuiltinSymbol {
+			item.Kind = CompletionFunction
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// the fields of the value named by the chain
+func (s *document) memberCompletion(index uint, names []string) []CompletionItem {
+	items := make([]CompletionItem, 0)
+	var theType mir.Type
+	for _, symbol := range s.info.Visible(index) {
+		if symbol.Name == names[0] && symbol.Kind != checker.FunctionSymbol && symbol.Kind != checker.BuiltinSymbol {
+			theType = symbol.TypeView.Type
+			break
+		}
+	}
+	for _, name := range names[1:] {
+		structType, ok := theType.(*mir.StructType)
+		if !ok {
+			return items
+		}
+		theType = structType.Fields[name].Type
+	}
+	for _, field := range s.info.FieldsOf(theType) {
+		items = append(items, CompletionItem{
+			Label:  field.Name,
+			Kind:   CompletionField,
+			Detail: signature(field),
+		})
+	}
+	return items
+}
+
+func (s *document) symbols() []DocumentSymbol {
+	symbols := make([]DocumentSymbol, 0)
+	for _, declaration := range s.program.Declarations {
+		switch declaration := declaration.(type) {
+		case *frontend.FunctionDeclaration:
+			symbol := s.documentSymbol(declaration.Name, SymbolFunction, declaration.Span)
+			children := make([]DocumentSymbol, 0)
+			for _, argument := range declaration.Arguments {
+				children = append(children, s.documentSymbol(argument.Name, SymbolVariable, argument.Span))
+			}
+			symbol.Children = append(children, s.localSymbols(declaration.Body)...)
+			symbols = append(symbols, symbol)
+		case *frontend.EventDeclaration:
+			name := declaration.Event
+			if declaration.Raw != nil {
+				name = declaration.Raw.Opcode
+			}
+			symbol := s.documentSymbol(name, SymbolEvent, declaration.Span)
+			symbol.Detail = "when"
+			symbol.Children = s.localSymbols(declaration.Body)
+			symbols = append(symbols, symbol)
+		case *frontend.GlobalVariableDeclaration:
+			symbols = append(symbols, s.documentSymbol(declaration.Name, SymbolVariable, declaration.Span))
+		case *frontend.CostumeDeclaration:
+			symbol := s.documentSymbol(declaration.Name, SymbolFile, declaration.Span)
+			symbol.Name = declaration.Name.StringValue()
+			symbol.Detail = "costume"
+			symbols = append(symbols, symbol)
+		case *frontend.SoundDeclaration:
+			symbol := s.documentSymbol(declaration.Name, SymbolFile, declaration.Span)
+			symbol.Name = declaration.Name.StringValue()
+			symbol.Detail = "sound"
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// the detail is the signature the checker found for the name
+func (s *document) documentSymbol(name frontend.Token, kind SymbolKind, theSpan span.Span) DocumentSymbol {
+	symbol := DocumentSymbol{
+		Name:           name.Identifier(),
+		Kind:           kind,
+		Range:          s.spanRange(theSpan),
+		SelectionRange: s.spanRange(name.Span),
+	}
+	if s.info != nil {
+		if reference := s.info.ReferenceAt(name.Span.From.Index); reference != nil {
+			symbol.Detail = signature(reference.Symbol)
+		}
+	}
+	return symbol
+}
+
+func (s *document) localSymbols(block frontend.Block) []DocumentSymbol {
+	symbols := make([]DocumentSymbol, 0)
+	frontend.Inspect(&block, func(node frontend.Node) bool {
+		switch node := node.(type) {
+		case *frontend.VarStatement:
+			symbols = append(symbols, s.documentSymbol(node.Name, SymbolVariable, node.Span))
+		case *frontend.DeclareAssignStatement:
+			symbols = append(symbols, s.documentSymbol(node.Name, SymbolVariable, node.Span))
+		}
+		return true
+	})
+	return symbols
+}
@@ -0,0 +1,201 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// a language server for .yum sources, speaking JSON-RPC over a stream
+type Server struct {
+	reader    *bufio.Reader
+	writer    io.Writer
+	documents map[string]*document
+	shutdown  bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		reader:    bufio.NewReader(in),
+		writer:    out,
+		documents: make(map[string]*document),
+	}
+}
+
+// serves until the client exits, an exit without a shutdown is an error
+func (s *Server) Serve() error {
+	for {
+		content, err := readMessage(s.reader)
+		if err == io.EOF {
+			return fmt.Errorf("the client left without exit")
+		}
+		if err != nil {
+			return err
+		}
+		var theRequest request
+		if err := json.Unmarshal(content, &theRequest); err != nil {
+			if err := s.respondError(nil, codeParseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		if theRequest.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit before shutdown")
+			}
+			return nil
+		}
+		if err := s.handle(&theRequest); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(theRequest *request) error {
+	if theRequest.Id == nil {
+		return s.notified(theRequest)
+	}
+	if s.shutdown {
+		return s.respondError(theRequest.Id, codeInvalidRequest, "the server is shut down")
+	}
+	var result any
+	var err error
+	switch theRequest.Method {
+	case "initialize":
+		result = s.initialize()
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/definition":
+		result, err = s.withDocumentPosition(theRequest, func(theDocument *document, position Position) any {
+			if location := theDocument.definition(position); location != nil {
+				return location
+			}
+			return nil
+		})
+	case "textDocument/hover":
+		result, err = s.withDocumentPosition(theRequest, func(theDocument *document, position Position) any {
+			if hover := theDocument.hover(position); hover != nil {
+				return hover
+			}
+			return nil
+		})
+	case "textDocument/completion":
+		result, err = s.withDocumentPosition(theRequest, func(theDocument *document, position Position) any {
+			return theDocument.completion(position)
+		})
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err = json.Unmarshal(theRequest.Params, &params); err == nil {
+			if theDocument, ok := s.documents[params.TextDocument.Uri]; ok {
+				result = theDocument.symbols()
+			} else {
+				result = []DocumentSymbol{}
+			}
+		}
+	default:
+		return s.respondError(theRequest.Id, codeMethodNotFound, fmt.Sprintf("method `%s` not found", theRequest.Method))
+	}
+	if err != nil {
+		return s.respondError(theRequest.Id, codeInvalidParams, err.Error())
+	}
+	return s.respond(theRequest.Id, result)
+}
+
+func (s *Server) withDocumentPosition(theRequest *request, handler func(*document, Position) any) (any, error) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(theRequest.Params, &params); err != nil {
+		return nil, err
+	}
+	theDocument, ok := s.documents[params.TextDocument.Uri]
+	if !ok {
+		return nil, fmt.Errorf("document `%s` is not open", params.TextDocument.Uri)
+	}
+	return handler(theDocument, params.Position), nil
+}
+
+func (s *Server) initialize() any {
+	return map[string]any{
+		"capabilities": map[string]any{
+			// the client sends the full text on each change
+			"textDocumentSync":       1,
+			"definitionProvider":     true,
+			"hoverProvider":          true,
+			"documentSymbolProvider": true,
+			"completionProvider": map[string]any{
+				"triggerCharacters": []string{"."},
+			},
+		},
+		"serverInfo": map[string]any{
+			"name": "yummy",
+		},
+	}
+}
+
+// notifications get no response, unknown ones are ignored
+func (s *Server) notified(theRequest *request) error {
+	switch theRequest.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := json.Unmarshal(theRequest.Params, &params); err != nil {
+			return nil
+		}
+		theDocument := newDocument(params.TextDocument.Uri, params.TextDocument.Text)
+		s.documents[theDocument.uri] = theDocument
+		return s.publishDiagnostics(theDocument.uri, theDocument.diagnostics)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := json.Unmarshal(theRequest.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		theDocument, ok := s.documents[params.TextDocument.Uri]
+		if !ok {
+			theDocument = newDocument(params.TextDocument.Uri, text)
+			s.documents[theDocument.uri] = theDocument
+		} else {
+			theDocument.update(text)
+		}
+		return s.publishDiagnostics(theDocument.uri, theDocument.diagnostics)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := json.Unmarshal(theRequest.Params, &params); err != nil {
+			return nil
+		}
+		delete(s.documents, params.TextDocument.Uri)
+		return s.publishDiagnostics(params.TextDocument.Uri, []Diagnostic{})
+	}
+	return nil
+}
+
+func (s *Server) publishDiagnostics(uri string, diagnostics []Diagnostic) error {
+	return writeMessage(s.writer, &notification{
+		Jsonrpc: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params: PublishDiagnosticsParams{
+			Uri:         uri,
+			Diagnostics: diagnostics,
+		},
+	})
+}
+
+func (s *Server) respond(id *json.RawMessage, result any) error {
+	content, err := json.Marshal(result)
+	if err != nil {
+		return s.respondError(id, codeInternalError, err.Error())
+	}
+	raw := json.RawMessage(content)
+	return writeMessage(s.writer, &response{
+		Jsonrpc: "2.0",
+		Id:      id,
+		Result:  &raw,
+	})
+}
+
+func (s *Server) respondError(id *json.RawMessage, code int, message string) error {
+	return writeMessage(s.writer, &response{
+		Jsonrpc: "2.0",
+		Id:      id,
+		Error:   &responseError{Code: code, Message: message},
+	})
+}
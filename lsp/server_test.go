@@ -0,0 +1,229 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// a client talking to a server through pipes
+type client struct {
+	t      *testing.T
+	writer io.WriteCloser
+	reader *bufio.Reader
+	nextId int
+	// the result of Serve once it returns
+	done chan error
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	theClient := &client{
+		t:      t,
+		writer: clientOut,
+		reader: bufio.NewReader(clientIn),
+		done:   make(chan error, 1),
+	}
+	go func() {
+		theClient.done <- NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+	}()
+	t.Cleanup(func() {
+		clientOut.Close()
+	})
+	return theClient
+}
+
+type received struct {
+	Id     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+func (s *client) receive() received {
+	s.t.Helper()
+	content, err := readMessage(s.reader)
+	if err != nil {
+		s.t.Fatalf("read: %s", err)
+	}
+	var message received
+	if err := json.Unmarshal(content, &message); err != nil {
+		s.t.Fatalf("decode %s: %s", content, err)
+	}
+	return message
+}
+
+func (s *client) notify(method string, params any) {
+	s.t.Helper()
+	if err := writeMessage(s.writer, &notification{Jsonrpc: "2.0", Method: method, Params: params}); err != nil {
+		s.t.Fatalf("write: %s", err)
+	}
+}
+
+// sends a request and decodes the result of its response into `result`
+func (s *client) call(method string, params any, result any) *responseError {
+	s.t.Helper()
+	s.nextId += 1
+	content, err := json.Marshal(params)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	id := json.RawMessage(strconv.Itoa(s.nextId))
+	if err := writeMessage(s.writer, &request{Jsonrpc: "2.0", Id: &id, Method: method, Params: content}); err != nil {
+		s.t.Fatalf("write: %s", err)
+	}
+	response := s.receive()
+	if response.Id == nil || *response.Id != s.nextId {
+		s.t.Fatalf("%s: expected the response %d, received %+v", method, s.nextId, response)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if result != nil {
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			s.t.Fatalf("%s: decode %s: %s", method, response.Result, err)
+		}
+	}
+	return nil
+}
+
+// the diagnostics the server publishes after a change of the document
+func (s *client) diagnostics(uri string) []Diagnostic {
+	s.t.Helper()
+	message := s.receive()
+	if message.Method != "textDocument/publishDiagnostics" {
+		s.t.Fatalf("expected diagnostics, received %+v", message)
+	}
+	var params PublishDiagnosticsParams
+	if err := json.Unmarshal(message.Params, &params); err != nil {
+		s.t.Fatal(err)
+	}
+	if params.Uri != uri {
+		s.t.Fatalf("diagnostics of `%s` instead of `%s`", params.Uri, uri)
+	}
+	return params.Diagnostics
+}
+
+func (s *client) shutdown() {
+	s.t.Helper()
+	if err := s.call("shutdown", nil, nil); err != nil {
+		s.t.Fatalf("shutdown: %s", err.Message)
+	}
+	s.notify("exit", nil)
+	if err := <-s.done; err != nil {
+		s.t.Fatalf("serve: %s", err)
+	}
+}
+
+const uri = "file:///project/main.yum"
+
+const source = `target Stage
+
+var score number = 0
+
+// adds points to the score
+func add(points number) {
+	score = score + points
+}
+`
+
+func position(line, character uint) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{Uri: uri},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+func TestSession(t *testing.T) {
+	theClient := newClient(t)
+	var initialized struct {
+		Capabilities map[string]any `json:"capabilities"`
+	}
+	if err := theClient.call("initialize", map[string]any{"capabilities": map[string]any{}}, &initialized); err != nil {
+		t.Fatal(err.Message)
+	}
+	if initialized.Capabilities["hoverProvider"] != true {
+		t.Errorf("expected hovers, found %v", initialized.Capabilities)
+	}
+	theClient.notify("initialized", map[string]any{})
+
+	theClient.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{Uri: uri, LanguageId: "yummy", Version: 1, Text: source},
+	})
+	if diagnostics := theClient.diagnostics(uri); len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, found %+v", diagnostics)
+	}
+
+	// `score` in `score + points` is the global
+	var location *Location
+	if err := theClient.call("textDocument/definition", position(6, 10), &location); err != nil {
+		t.Fatal(err.Message)
+	}
+	if location == nil || location.Uri != uri || location.Range.Start.Line != 2 {
+		t.Errorf("expected the definition on line 2, found %+v", location)
+	}
+	var hover *Hover
+	if err := theClient.call("textDocument/hover", position(6, 18), &hover); err != nil {
+		t.Fatal(err.Message)
+	}
+	if hover == nil || !strings.Contains(hover.Contents.Value, "argument points number") {
+		t.Errorf("expected the argument in the hover, found %+v", hover)
+	}
+	var items []CompletionItem
+	if err := theClient.call("textDocument/completion", position(6, 1), &items); err != nil {
+		t.Fatal(err.Message)
+	}
+	labels := make([]string, 0, len(items))
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	for _, label := range []string{"score", "add", "points", "inline"} {
+		if !slices.Contains(labels, label) {
+			t.Errorf("expected `%s` in the completions, found %q", label, labels)
+		}
+	}
+	var symbols []DocumentSymbol
+	if err := theClient.call("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{Uri: uri}}, &symbols); err != nil {
+		t.Fatal(err.Message)
+	}
+	if len(symbols) != 2 || symbols[0].Name != "score" || symbols[1].Name != "add" || len(symbols[1].Children) == 0 || symbols[1].Children[0].Name != "points" {
+		t.Errorf("expected `score` and `add(points)`, found %+v", symbols)
+	}
+
+	theClient.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{Uri: uri},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: strings.Replace(source, "score + points", "score + missing", 1)}},
+	})
+	diagnostics := theClient.diagnostics(uri)
+	if len(diagnostics) != 1 || !strings.Contains(diagnostics[0].Message, "missing") || diagnostics[0].Range.Start.Line != 6 {
+		t.Errorf("expected `missing` to be reported on line 6, found %+v", diagnostics)
+	}
+
+	theClient.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{Uri: uri}})
+	if diagnostics := theClient.diagnostics(uri); len(diagnostics) != 0 {
+		t.Errorf("expected the diagnostics to be cleared, found %+v", diagnostics)
+	}
+	if err := theClient.call("textDocument/hover", position(6, 18), nil); err == nil || err.Code != codeInvalidParams {
+		t.Errorf("expected a hover of a closed document to fail, found %+v", err)
+	}
+	if err := theClient.call("bogus/method", map[string]any{}, nil); err == nil || err.Code != codeMethodNotFound {
+		t.Errorf("expected an unknown method to fail, found %+v", err)
+	}
+	theClient.shutdown()
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	theClient := newClient(t)
+	theClient.notify("exit", nil)
+	if err := <-theClient.done; err == nil {
+		t.Error("expected an exit without shutdown to fail")
+	}
+}
@@ -1,24 +1,51 @@
 package main
 
 import (
-	"yummy-go.com/m/v2/frontend"
-	"yummy-go.com/m/v2/span"
+	"fmt"
+	"os"
 )
 
-func main() {
-	sourcePath := "main.yum"
-	sourceCode := `target Stage
-
-func main() {}`
-	lexer := frontend.NewLexer(sourcePath, sourceCode)
-	parser := frontend.NewParser(lexer)
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: yummy <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  build     compile a .yum or .mir source into a .sb3 project")
+	fmt.Fprintln(os.Stderr, "  run       run a .yum or .mir source with the mir interpreter")
+	fmt.Fprintln(os.Stderr, "  dump-ast  print the syntax tree of a .yum source, as text or JSON")
+	fmt.Fprintln(os.Stderr, "  dump-mir  print the mir of a .yum or .mir source as text")
+	fmt.Fprintln(os.Stderr, "  fmt       format .yum sources")
+	fmt.Fprintln(os.Stderr, "  decompile lift the scripts of a .sb3 project into .yum sources")
+	fmt.Fprintln(os.Stderr, "  lsp       serve the language server protocol over stdio")
+	fmt.Fprintln(os.Stderr, "  where     show the source of a generated block")
+}
 
-	span.ResetStats()
-	ast, err := parser.ParseProgram()
-	if err != nil {
-		errorCount := span.GetStats(span.Error)
-		span.ReportNoSpan(span.Error, "%s: %s generated", sourcePath, span.Pluralize(errorCount, "error", "errors"))
-		return
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "build":
+		os.Exit(runBuild(os.Args[2:]))
+	case "run":
+		os.Exit(runRun(os.Args[2:]))
+	case "dump-ast":
+		os.Exit(runDumpAst(os.Args[2:]))
+	case "dump-mir":
+		os.Exit(runDumpMir(os.Args[2:]))
+	case "fmt":
+		os.Exit(runFmt(os.Args[2:]))
+	case "decompile":
+		os.Exit(runDecompile(os.Args[2:]))
+	case "lsp":
+		os.Exit(runLsp(os.Args[2:]))
+	case "where":
+		os.Exit(runWhere(os.Args[2:]))
+	case "help", "-h", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "yummy: unknown command `%s`\n", os.Args[1])
+		usage()
+		os.Exit(2)
 	}
-	ast.Display(0)
 }
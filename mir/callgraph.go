@@ -0,0 +1,109 @@
+package mir
+
+// the functions each function calls directly, in the order of the calls
+func CallGraph(program *Program) map[*FunctionDeclaration][]*FunctionDeclaration {
+	graph := make(map[*FunctionDeclaration][]*FunctionDeclaration)
+	for _, declaration := range program.Declarations {
+		function, ok := declaration.(*FunctionDeclaration)
+		if !ok {
+			continue
+		}
+		callees := make([]*FunctionDeclaration, 0)
+		walkBlockCalls(function.Body, func(call *CallExpression) {
+			callees = append(callees, call.Function)
+		})
+		graph[function] = callees
+	}
+	return graph
+}
+
+// the functions that may call themselves, directly or through others.
+// only these need a frame on `_Stack`
+func RecursiveFunctions(program *Program) map[*FunctionDeclaration]struct{} {
+	graph := CallGraph(program)
+	recursive := make(map[*FunctionDeclaration]struct{})
+	for function := range graph {
+		if reaches(graph, function, function) {
+			recursive[function] = struct{}{}
+		}
+	}
+	return recursive
+}
+
+// the functions that may run during a call of `function`, itself included
+func Callees(graph map[*FunctionDeclaration][]*FunctionDeclaration, function *FunctionDeclaration) map[*FunctionDeclaration]struct{} {
+	callees := map[*FunctionDeclaration]struct{}{
+		function: {},
+	}
+	pending := append([]*FunctionDeclaration{}, graph[function]...)
+	for len(pending) > 0 {
+		callee := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if _, ok := callees[callee]; ok {
+			continue
+		}
+		callees[callee] = struct{}{}
+		pending = append(pending, graph[callee]...)
+	}
+	return callees
+}
+
+// whether `to` is called when `from` runs
+func reaches(graph map[*FunctionDeclaration][]*FunctionDeclaration, from, to *FunctionDeclaration) bool {
+	visited := make(map[*FunctionDeclaration]struct{})
+	pending := append([]*FunctionDeclaration{}, graph[from]...)
+	for len(pending) > 0 {
+		function := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if function == to {
+			return true
+		}
+		if _, ok := visited[function]; ok {
+			continue
+		}
+		visited[function] = struct{}{}
+		pending = append(pending, graph[function]...)
+	}
+	return false
+}
+
+func walkBlockCalls(block Block, visit func(*CallExpression)) {
+	WalkStatements(block, func(statement Statement) {
+		for _, call := range StatementCalls(statement) {
+			visit(call)
+		}
+	})
+}
+
+func walkExpressionCalls(expression Expression, visit func(*CallExpression)) {
+	switch expression := expression.(type) {
+	case *BinaryExpression:
+		walkExpressionCalls(expression.Lhs, visit)
+		walkExpressionCalls(expression.Rhs, visit)
+	case *UnaryExpression:
+		walkExpressionCalls(expression.Value, visit)
+	case *CallExpression:
+		for _, argument := range expression.Arguments {
+			walkExpressionCalls(argument, visit)
+		}
+		visit(expression)
+	}
+}
+
+// the calls of a statement in the order they run, the arguments of a call
+// run before it. the statements nested in the statement are not included
+func StatementCalls(statement Statement) []*CallExpression {
+	calls := make([]*CallExpression, 0)
+	visit := func(call *CallExpression) {
+		calls = append(calls, call)
+	}
+	switch statement := statement.(type) {
+	case *AssignStatement:
+		walkExpressionCalls(statement.Value, visit)
+	case *ReturnStatement:
+		walkExpressionCalls(statement.Value, visit)
+	case *IfStatement:
+		walkExpressionCalls(statement.Condition, visit)
+	}
+	return calls
+}
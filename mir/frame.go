@@ -0,0 +1,142 @@
+package mir
+
+// the statements a local is live across, numbered in the order they run
+type liveRange struct {
+	declaration *DeclareStatement
+	from, to    uint
+}
+
+// gives each local of the function a frame offset. arguments come first,
+// locals whose live ranges do not overlap share their offsets. StackSize
+// is updated to the size of the resulting frame. values without a size get
+// no offset, Verify reports them
+func AllocateFrame(function *FunctionDeclaration) error {
+	var frame indexAllocator
+	for idx := range function.Arguments {
+		argument := &function.Arguments[idx]
+		size := sizeOf(argument.TypeView.Type)
+		if size == nil {
+			continue
+		}
+		argument.TypeView.Offset = frame.alloc(*size)
+	}
+	ranges := liveRanges(function.Body)
+	live := make([]liveRange, 0)
+	for _, theRange := range ranges {
+		// the locals dead before this one is declared give their cells back
+		stillLive := make([]liveRange, 0, len(live))
+		for _, liveOne := range live {
+			if liveOne.to < theRange.from {
+				frame.free(liveOne.declaration.TypeView.Offset, *liveOne.declaration.TypeView.Type.GetSize())
+			} else {
+				stillLive = append(stillLive, liveOne)
+			}
+		}
+		live = stillLive
+		size := sizeOf(theRange.declaration.TypeView.Type)
+		if size == nil {
+			continue
+		}
+		theRange.declaration.TypeView.Offset = frame.alloc(*size)
+		live = append(live, theRange)
+	}
+	function.StackSize = frame.next
+	return nil
+}
+
+// AllocateFrame for each function of the program
+func AllocateFrames(program *Program) error {
+	for _, declaration := range program.Declarations {
+		if function, ok := declaration.(*FunctionDeclaration); ok {
+			if err := AllocateFrame(function); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// the live ranges of the locals declared in a block, in the order of their
+// declarations. a local is live from its declaration to its last use, and
+// across the whole of the loops it is used in but declared out of
+func liveRanges(block Block) []liveRange {
+	ranges := make([]liveRange, 0)
+	indices := make(map[*DeclareStatement]int)
+	// the statements of each loop, from its first to its last
+	loops := make([][2]uint, 0)
+	var position uint = 0
+	use := func(acessor Acessor) {
+		variableAcessor, ok := acessor.(*VariableAcessor)
+		if !ok {
+			return
+		}
+		declaration, ok := variableAcessor.Declaration.(*DeclareStatement)
+		if !ok {
+			return
+		}
+		if idx, ok := indices[declaration]; ok {
+			ranges[idx].to = max(ranges[idx].to, position)
+		}
+	}
+	var walk func(block Block)
+	walk = func(block Block) {
+		for _, statement := range block.Statements {
+			switch statement := statement.(type) {
+			case *DeclareStatement:
+				indices[statement] = len(ranges)
+				ranges = append(ranges, liveRange{
+					declaration: statement,
+					from:        position,
+					to:          position,
+				})
+			case *AssignStatement:
+				walkExpressionAcessors(statement.Value, use)
+				use(statement.Acessor)
+			case *ReturnStatement:
+				walkExpressionAcessors(statement.Value, use)
+			case *LoopStatement:
+				from := position
+				position += 1
+				walk(statement.Body)
+				loops = append(loops, [2]uint{from, position})
+				continue
+			case *IfStatement:
+				walkExpressionAcessors(statement.Condition, use)
+				position += 1
+				walk(statement.Then)
+				walk(statement.Else)
+				continue
+			}
+			position += 1
+		}
+	}
+	walk(block)
+	for changed := true; changed; {
+		changed = false
+		for idx := range ranges {
+			for _, loop := range loops {
+				if ranges[idx].from < loop[0] && ranges[idx].to > loop[0] && ranges[idx].to < loop[1] {
+					ranges[idx].to = loop[1]
+					changed = true
+				}
+			}
+		}
+	}
+	return ranges
+}
+
+func walkExpressionAcessors(expression Expression, visit func(Acessor)) {
+	switch expression := expression.(type) {
+	case *AcessorExpression:
+		visit(expression.Acessor)
+	case *BinaryExpression:
+		walkExpressionAcessors(expression.Lhs, visit)
+		walkExpressionAcessors(expression.Rhs, visit)
+	case *UnaryExpression:
+		walkExpressionAcessors(expression.Value, visit)
+	case *CallExpression:
+		for _, argument := range expression.Arguments {
+			walkExpressionAcessors(argument, visit)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package mir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func parseFunction(t *testing.T, source string) *FunctionDeclaration {
+	t.Helper()
+	program, err := Parse("frame.mir", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AllocateFrames(&program); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(&program); err != nil {
+		t.Fatal(err)
+	}
+	return program.Declarations[0].(*FunctionDeclaration)
+}
+
+func offsets(function *FunctionDeclaration) map[string]uint {
+	result := make(map[string]uint)
+	WalkStatements(function.Body, func(statement Statement) {
+		if declaration, ok := statement.(*DeclareStatement); ok {
+			result[declaration.Name] = declaration.TypeView.Offset
+		}
+	})
+	return result
+}
+
+const branches = `func branches(p: number @0 slots(0 "branchesArgument0000")) -> number @0 slots(1 "branchesReturn000000") stack 0 proccode "branches %s" {
+    if (p > 0) {
+        var a: number @0
+        a = p
+        p = a
+    } else {
+        var b: number @0
+        b = p
+        p = b
+    }
+    var c: number @0
+    c = p
+    loop {
+        var d: number @0
+        d = c
+        c = (d + 1)
+        if (c > 10) {
+            return c
+        }
+    }
+}
+`
+
+// the locals of both branches are dead once the `if` is done, the ones
+// declared after it take their cells
+func TestFrameSharesOffsets(t *testing.T) {
+	function := parseFunction(t, branches)
+	expected := map[string]uint{"a": 1, "b": 1, "c": 1, "d": 2}
+	actual := offsets(function)
+	for name, offset := range expected {
+		if actual[name] != offset {
+			t.Errorf("local `%s`: expected @%d, found @%d", name, offset, actual[name])
+		}
+	}
+	if function.Arguments[0].TypeView.Offset != 0 {
+		t.Errorf("expected the argument at @0, found @%d", function.Arguments[0].TypeView.Offset)
+	}
+	// the argument, the cell of `a`, `b` and `c`, then `d`
+	if function.StackSize != 3 {
+		t.Errorf("expected a stack of 3, found %d", function.StackSize)
+	}
+}
+
+const looping = `func looping(p: number @0 slots(0 "loopingArgument00000")) -> number @0 slots(1 "loopingReturn0000000") stack 0 proccode "looping %s" {
+    var x: number @0
+    x = 1
+    loop {
+        p = x
+        var y: number @0
+        y = p
+        p = (y + 1)
+        if (p > 5) {
+            return p
+        }
+    }
+}
+`
+
+// `x` is last written before `y` is declared, but the loop reads it again
+func TestFrameKeepsLoopLocals(t *testing.T) {
+	actual := offsets(parseFunction(t, looping))
+	if actual["x"] == actual["y"] {
+		t.Errorf("`x` and `y` share @%d while both are live", actual["x"])
+	}
+}
+
+// two locals live at the same time never share a cell, in every source
+func TestFrameLiveLocalsNeverShare(t *testing.T) {
+	paths := make([]string, 0)
+	for _, pattern := range []string{"opt/testdata/*.mir", "../examples/*.mir"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, matches...)
+	}
+	sources := map[string]string{"branches.mir": branches, "looping.mir": looping}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources[path] = string(content)
+	}
+	for path, source := range sources {
+		program, err := Parse(path, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := AllocateFrames(&program); err != nil {
+			t.Fatal(err)
+		}
+		for _, declaration := range program.Declarations {
+			function, ok := declaration.(*FunctionDeclaration)
+			if !ok {
+				continue
+			}
+			ranges := liveRanges(function.Body)
+			cells := func(declaration *DeclareStatement) (uint, uint) {
+				return declaration.TypeView.Offset, declaration.TypeView.Offset + *declaration.TypeView.Type.GetSize()
+			}
+			for idx, lhs := range ranges {
+				lhsFrom, lhsTo := cells(lhs.declaration)
+				for _, argument := range function.Arguments {
+					if lhsFrom < argument.TypeView.Offset+*argument.TypeView.Type.GetSize() && argument.TypeView.Offset < lhsTo {
+						t.Errorf("%s: `%s` shares a cell with argument `%s`", path, lhs.declaration.Name, argument.Name)
+					}
+				}
+				for _, rhs := range ranges[idx+1:] {
+					if lhs.to < rhs.from || rhs.to < lhs.from {
+						continue
+					}
+					rhsFrom, rhsTo := cells(rhs.declaration)
+					if lhsFrom < rhsTo && rhsFrom < lhsTo {
+						t.Errorf("%s: `%s` and `%s` share a cell while both are live", path, lhs.declaration.Name, rhs.declaration.Name)
+					}
+				}
+				if lhsTo > function.StackSize {
+					t.Errorf("%s: `%s` does not fit in a stack of %d", path, lhs.declaration.Name, function.StackSize)
+				}
+			}
+		}
+	}
+}
@@ -1,11 +1,32 @@
 package mir
 
-import "yummy-go.com/m/v2/frontend"
+import (
+	"fmt"
 
-func GenerateMir(ast frontend.Program) Program {
+	"yummy-go.com/m/v2/frontend"
+)
+
+func GenerateMir(ast frontend.Program) (Program, error) {
 	return generateMirFromAstProgram(ast)
 }
 
-func generateMirFromAstProgram(_ast frontend.Program) Program {
-	return Program{}
+var unloweredDeclarations = map[frontend.DeclarationType]string{
+	frontend.GlobalVariableDeclarationType: "variables",
+	frontend.FunctionDeclarationType:       "functions",
+	frontend.EventDeclarationType:          "events",
+}
+
+// costumes and sounds are loaded by the build, they lower to nothing. the
+// lowering of the other declarations is not written yet, an empty program
+// would run and build into nothing without a word. when it is, a function
+// marked `inline` in the source becomes a FunctionDeclaration with Inline
+// set, like `inline` after the header in the .mir text
+func generateMirFromAstProgram(ast frontend.Program) (Program, error) {
+	for _, declaration := range ast.Declarations {
+		if kind, ok := unloweredDeclarations[declaration.Type()]; ok {
+			line := declaration.GetSpan().From.Lineno + 1
+			return Program{}, fmt.Errorf("line %d: lowering %s to mir is not implemented yet, pass a .mir source", line, kind)
+		}
+	}
+	return Program{Declarations: make([]Declaration, 0)}, nil
 }
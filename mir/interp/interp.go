@@ -0,0 +1,373 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+
+	"yummy-go.com/m/v2/headless"
+	"yummy-go.com/m/v2/mir"
+)
+
+const (
+	DefaultMaxSteps     uint = 10_000_000
+	DefaultMaxCallDepth uint = 10_000
+)
+
+// executes a mir.Program directly, as the reference of what the omitted
+// blocks should compute. values follow the Scratch semantics of headless
+type Interpreter struct {
+	MaxSteps     uint
+	MaxCallDepth uint
+	program      *mir.Program
+	// arguments and return values are passed through slots, as the
+	// omitter does with procedure arguments and variables
+	registers map[string]headless.Value
+	globals   map[*mir.GlobalDeclaration][]headless.Value
+	steps     uint
+	depth     uint
+}
+
+// the locals of a running function, addressed by their TypeView offset
+type frame struct {
+	function *mir.FunctionDeclaration
+	values   []headless.Value
+	returned bool
+}
+
+func New(program *mir.Program) *Interpreter {
+	interpreter := Interpreter{
+		MaxSteps:     DefaultMaxSteps,
+		MaxCallDepth: DefaultMaxCallDepth,
+		program:      program,
+		registers:    make(map[string]headless.Value),
+		globals:      make(map[*mir.GlobalDeclaration][]headless.Value),
+	}
+	for _, declaration := range program.Declarations {
+		if global, ok := declaration.(*mir.GlobalDeclaration); ok {
+			size := global.TypeView.Type.GetSize()
+			if size != nil {
+				interpreter.globals[global] = emptyValues(*size)
+			}
+		}
+	}
+	return &interpreter
+}
+
+func emptyValues(n uint) []headless.Value {
+	values := make([]headless.Value, n)
+	for idx := range values {
+		values[idx] = ""
+	}
+	return values
+}
+
+func (s *Interpreter) LookupFunction(name string) *mir.FunctionDeclaration {
+	for _, declaration := range s.program.Declarations {
+		if function, ok := declaration.(*mir.FunctionDeclaration); ok && function.Name == name {
+			return function
+		}
+	}
+	return nil
+}
+
+// the current values of a global variable
+func (s *Interpreter) Global(name string) ([]headless.Value, bool) {
+	for global, values := range s.globals {
+		if global.Name == name {
+			return values, true
+		}
+	}
+	return nil, false
+}
+
+// calls a function with the values of each of its arguments, returns the
+// values of its return slots
+func (s *Interpreter) Call(function *mir.FunctionDeclaration, arguments ...[]headless.Value) ([]headless.Value, error) {
+	if len(arguments) != len(function.Arguments) {
+		return nil, fmt.Errorf("function `%s`: arguments mismatched", function.Name)
+	}
+	for idx, argument := range function.Arguments {
+		if len(argument.TypeView.Slots) != len(arguments[idx]) {
+			return nil, fmt.Errorf("function `%s`: argument `%s`: type not fit", function.Name, argument.Name)
+		}
+		for slotIdx, slot := range argument.TypeView.Slots {
+			s.registers[slot.Uuid] = arguments[idx][slotIdx]
+		}
+	}
+	if err := s.call(function); err != nil {
+		return nil, err
+	}
+	return s.readSlots(function.ReturnTypeView.Slots), nil
+}
+
+func (s *Interpreter) readSlots(slots []mir.Slot) []headless.Value {
+	values := make([]headless.Value, 0, len(slots))
+	for _, slot := range slots {
+		value, ok := s.registers[slot.Uuid]
+		if !ok {
+			value = ""
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func (s *Interpreter) step(function *mir.FunctionDeclaration) error {
+	s.steps += 1
+	if s.steps > s.MaxSteps {
+		return fmt.Errorf("function `%s`: exceeds %d steps", function.Name, s.MaxSteps)
+	}
+	return nil
+}
+
+// runs a function whose arguments are already in their slots
+func (s *Interpreter) call(function *mir.FunctionDeclaration) error {
+	if s.depth >= s.MaxCallDepth {
+		return fmt.Errorf("function `%s`: exceeds %d nested calls", function.Name, s.MaxCallDepth)
+	}
+	s.depth += 1
+	defer func() {
+		s.depth -= 1
+	}()
+	theFrame := frame{
+		function: function,
+		values:   emptyValues(function.StackSize),
+	}
+	// the prologue moves the arguments into the frame
+	for _, argument := range function.Arguments {
+		values := s.readSlots(argument.TypeView.Slots)
+		if err := theFrame.store(argument.TypeView, values); err != nil {
+			return err
+		}
+	}
+	return s.executeBlock(&theFrame, function.Body)
+}
+
+func (s *frame) store(typeView mir.TypeView, values []headless.Value) error {
+	if typeView.Offset+uint(len(values)) > uint(len(s.values)) {
+		return fmt.Errorf("function `%s`: offset %d exceeds the stack size %d", s.function.Name, typeView.Offset, len(s.values))
+	}
+	copy(s.values[typeView.Offset:], values)
+	return nil
+}
+
+func (s *Interpreter) executeBlock(theFrame *frame, block mir.Block) error {
+	for _, statement := range block.Statements {
+		if err := s.executeStatement(theFrame, statement); err != nil {
+			return err
+		}
+		if theFrame.returned {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *Interpreter) executeStatement(theFrame *frame, statement mir.Statement) error {
+	if err := s.step(theFrame.function); err != nil {
+		return err
+	}
+	switch statement := statement.(type) {
+	case *mir.DeclareStatement:
+		return nil
+	case *mir.AssignStatement:
+		values, err := s.evaluate(theFrame, statement.Value)
+		if err != nil {
+			return err
+		}
+		return s.assign(theFrame, statement.Acessor, values)
+	case *mir.ReturnStatement:
+		if statement.Value != nil {
+			values, err := s.evaluate(theFrame, statement.Value)
+			if err != nil {
+				return err
+			}
+			slots := theFrame.function.ReturnTypeView.Slots
+			if len(slots) != len(values) {
+				return fmt.Errorf("function `%s`: type not fit", theFrame.function.Name)
+			}
+			for idx, slot := range slots {
+				s.registers[slot.Uuid] = values[idx]
+			}
+		}
+		theFrame.returned = true
+		return nil
+	case *mir.LoopStatement:
+		for !theFrame.returned {
+			if err := s.executeBlock(theFrame, statement.Body); err != nil {
+				return err
+			}
+			if err := s.step(theFrame.function); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *mir.IfStatement:
+		values, err := s.evaluate(theFrame, statement.Condition)
+		if err != nil {
+			return err
+		}
+		if len(values) == 1 && headless.ToBoolean(values[0]) {
+			return s.executeBlock(theFrame, statement.Then)
+		}
+		return s.executeBlock(theFrame, statement.Else)
+	}
+	return fmt.Errorf("function `%s`: not implemented yet", theFrame.function.Name)
+}
+
+// returns the storage of the value an acessor refers to
+func (s *Interpreter) locate(theFrame *frame, acessor mir.Acessor) ([]headless.Value, error) {
+	switch acessor := acessor.(type) {
+	case *mir.VariableAcessor:
+		typeView := acessor.Declaration.GetTypeView()
+		size := typeView.Type.GetSize()
+		if size == nil {
+			return nil, fmt.Errorf("function `%s`: cannot deref a dyn-sized value", theFrame.function.Name)
+		}
+		if global, ok := acessor.Declaration.(*mir.GlobalDeclaration); ok {
+			return s.globals[global], nil
+		}
+		if typeView.Offset+*size > uint(len(theFrame.values)) {
+			return nil, fmt.Errorf("function `%s`: offset %d exceeds the stack size %d", theFrame.function.Name, typeView.Offset, len(theFrame.values))
+		}
+		return theFrame.values[typeView.Offset : typeView.Offset+*size], nil
+	}
+	return nil, fmt.Errorf("function `%s`: not implemented yet", theFrame.function.Name)
+}
+
+func (s *Interpreter) assign(theFrame *frame, acessor mir.Acessor, values []headless.Value) error {
+	storage, err := s.locate(theFrame, acessor)
+	if err != nil {
+		return err
+	}
+	if len(storage) != len(values) {
+		return fmt.Errorf("function `%s`: type not fit", theFrame.function.Name)
+	}
+	copy(storage, values)
+	return nil
+}
+
+func (s *Interpreter) evaluate(theFrame *frame, expression mir.Expression) ([]headless.Value, error) {
+	switch expression := expression.(type) {
+	case *mir.LiteralExpression:
+		switch literal := expression.Literal.(type) {
+		case float64, string, bool:
+			return []headless.Value{literal}, nil
+		}
+		return nil, fmt.Errorf("function `%s`: unknown value type", theFrame.function.Name)
+	case *mir.AcessorExpression:
+		storage, err := s.locate(theFrame, expression.Acessor)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]headless.Value, len(storage))
+		copy(values, storage)
+		return values, nil
+	case *mir.BinaryExpression:
+		lhs, err := s.evaluate(theFrame, expression.Lhs)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := s.evaluate(theFrame, expression.Rhs)
+		if err != nil {
+			return nil, err
+		}
+		value, err := binary(expression, lhs, rhs)
+		if err != nil {
+			return nil, fmt.Errorf("function `%s`: %s", theFrame.function.Name, err.Error())
+		}
+		return []headless.Value{value}, nil
+	case *mir.UnaryExpression:
+		values, err := s.evaluate(theFrame, expression.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 1 {
+			return nil, fmt.Errorf("function `%s`: type not fit", theFrame.function.Name)
+		}
+		switch expression.Operator {
+		case mir.OperatorSub:
+			return []headless.Value{-headless.ToNumber(values[0])}, nil
+		case mir.OperatorAdd:
+			return []headless.Value{headless.ToNumber(values[0])}, nil
+		case mir.OperatorNot:
+			return []headless.Value{!headless.ToBoolean(values[0])}, nil
+		}
+		return nil, fmt.Errorf("function `%s`: unknown unary operator", theFrame.function.Name)
+	case *mir.CallExpression:
+		function := expression.Function
+		if len(function.Arguments) != len(expression.Arguments) {
+			return nil, fmt.Errorf("function `%s`: arguments mismatched", theFrame.function.Name)
+		}
+		// evaluate every argument before any of them is put into its slots
+		arguments := make([][]headless.Value, 0, len(expression.Arguments))
+		for idx, argument := range expression.Arguments {
+			values, err := s.evaluate(theFrame, argument)
+			if err != nil {
+				return nil, err
+			}
+			if len(values) != len(function.Arguments[idx].TypeView.Slots) {
+				return nil, fmt.Errorf("function `%s`: type not fit", theFrame.function.Name)
+			}
+			arguments = append(arguments, values)
+		}
+		for idx, argument := range function.Arguments {
+			for slotIdx, slot := range argument.TypeView.Slots {
+				s.registers[slot.Uuid] = arguments[idx][slotIdx]
+			}
+		}
+		if err := s.call(function); err != nil {
+			return nil, err
+		}
+		return s.readSlots(function.ReturnTypeView.Slots), nil
+	}
+	return nil, fmt.Errorf("function `%s`: not implemented yet", theFrame.function.Name)
+}
+
+func binary(expression *mir.BinaryExpression, lhs, rhs []headless.Value) (headless.Value, error) {
+	switch expression.Operator {
+	case mir.OperatorEq, mir.OperatorNe:
+		if len(lhs) != len(rhs) {
+			return nil, fmt.Errorf("type not fit")
+		}
+		equal := true
+		for idx := range lhs {
+			if headless.Compare(lhs[idx], rhs[idx]) != 0 {
+				equal = false
+			}
+		}
+		return equal == (expression.Operator == mir.OperatorEq), nil
+	}
+	if len(lhs) != 1 || len(rhs) != 1 {
+		return nil, fmt.Errorf("type not fit")
+	}
+	l, r := lhs[0], rhs[0]
+	switch expression.Operator {
+	case mir.OperatorAdd:
+		if expression.OutputType != nil && expression.OutputType.Type() == mir.TypeString {
+			return headless.ToString(l) + headless.ToString(r), nil
+		}
+		return headless.ToNumber(l) + headless.ToNumber(r), nil
+	case mir.OperatorSub:
+		return headless.ToNumber(l) - headless.ToNumber(r), nil
+	case mir.OperatorMul:
+		return headless.ToNumber(l) * headless.ToNumber(r), nil
+	case mir.OperatorDiv:
+		return headless.ToNumber(l) / headless.ToNumber(r), nil
+	case mir.OperatorPow:
+		return math.Pow(headless.ToNumber(l), headless.ToNumber(r)), nil
+	case mir.OperatorLt:
+		return headless.Compare(l, r) < 0, nil
+	case mir.OperatorGt:
+		return headless.Compare(l, r) > 0, nil
+	case mir.OperatorLe:
+		return headless.Compare(l, r) <= 0, nil
+	case mir.OperatorGe:
+		return headless.Compare(l, r) >= 0, nil
+	case mir.OperatorAnd:
+		return headless.ToBoolean(l) && headless.ToBoolean(r), nil
+	case mir.OperatorOr:
+		return headless.ToBoolean(l) || headless.ToBoolean(r), nil
+	}
+	return nil, fmt.Errorf("unknown binary operator")
+}
@@ -0,0 +1,223 @@
+package interp_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"yummy-go.com/m/v2/headless"
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/mir/interp"
+	"yummy-go.com/m/v2/mir/opt"
+	"yummy-go.com/m/v2/omitter"
+	"yummy-go.com/m/v2/scir"
+)
+
+// the arguments every function is called with, all of its slots get the
+// same one
+var samples = []string{"-2", "0", "3", "7"}
+
+const maxCallDepth = 50
+
+func sources(t *testing.T) map[string]string {
+	t.Helper()
+	result := make(map[string]string)
+	for _, pattern := range []string{"../../examples/*.mir", "../opt/testdata/*.mir"} {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result[path] = string(content)
+		}
+	}
+	if len(result) == 0 {
+		t.Fatal("no sources")
+	}
+	return result
+}
+
+func parse(t *testing.T, path, source string, level opt.Level) mir.Program {
+	t.Helper()
+	program, err := mir.Parse(path, source)
+	if err != nil {
+		t.Fatalf("parse %s: %s", path, err)
+	}
+	pipeline := opt.New(level)
+	pipeline.Run(&program)
+	return program
+}
+
+func functions(program *mir.Program) []*mir.FunctionDeclaration {
+	result := make([]*mir.FunctionDeclaration, 0)
+	for _, declaration := range program.Declarations {
+		if function, ok := declaration.(*mir.FunctionDeclaration); ok {
+			result = append(result, function)
+		}
+	}
+	return result
+}
+
+// what a backend made of the calls to every function of a program, in
+// order: the returned values and the globals after each call, or the
+// failure of the call
+type trace []string
+
+func step(function *mir.FunctionDeclaration, sample string, returned []string, err error, globals []string) string {
+	if err != nil {
+		return fmt.Sprintf("%s(%s) fails", function.Name, sample)
+	}
+	return fmt.Sprintf("%s(%s) = [%s] globals [%s]", function.Name, sample, strings.Join(returned, " "), strings.Join(globals, " "))
+}
+
+func globals(program *mir.Program) []*mir.GlobalDeclaration {
+	result := make([]*mir.GlobalDeclaration, 0)
+	for _, declaration := range program.Declarations {
+		if global, ok := declaration.(*mir.GlobalDeclaration); ok {
+			result = append(result, global)
+		}
+	}
+	return result
+}
+
+func interpret(t *testing.T, path, source string, level opt.Level) trace {
+	t.Helper()
+	program := parse(t, path, source, level)
+	if err := mir.AllocateFrames(&program); err != nil {
+		t.Fatalf("%s: %s", path, err)
+	}
+	if err := mir.Verify(&program); err != nil {
+		t.Fatalf("%s: %s", path, err)
+	}
+	interpreter := interp.New(&program)
+	interpreter.MaxCallDepth = maxCallDepth
+	result := make(trace, 0)
+	for _, function := range functions(&program) {
+		for _, sample := range samples {
+			arguments := make([][]headless.Value, 0, len(function.Arguments))
+			for _, argument := range function.Arguments {
+				values := make([]headless.Value, 0, len(argument.TypeView.Slots))
+				for range argument.TypeView.Slots {
+					values = append(values, sample)
+				}
+				arguments = append(arguments, values)
+			}
+			values, err := interpreter.Call(function, arguments...)
+			returned := make([]string, 0, len(values))
+			for _, value := range values {
+				returned = append(returned, headless.ToString(value))
+			}
+			globalValues := make([]string, 0)
+			for _, global := range globals(&program) {
+				values, _ := interpreter.Global(global.Name)
+				for _, value := range values {
+					globalValues = append(globalValues, headless.ToString(value))
+				}
+			}
+			result = append(result, step(function, sample, returned, err, globalValues))
+		}
+	}
+	return result
+}
+
+func omit(t *testing.T, path, source string, level opt.Level) trace {
+	t.Helper()
+	program := parse(t, path, source, level)
+	project := scir.NewProject()
+	theOmitter := omitter.New(&project)
+	theOmitter.SetTarget("Stage")
+	if err := theOmitter.Omit(program); err != nil {
+		t.Fatalf("omit %s: %s", path, err)
+	}
+	if err := scir.Validate(&project); err != nil {
+		t.Fatalf("validate %s: %s", path, err)
+	}
+	runtime, err := headless.New(&project.Ir)
+	if err != nil {
+		t.Fatalf("load %s: %s", path, err)
+	}
+	runtime.MaxCallDepth = maxCallDepth
+	variable := func(name string) string {
+		value, ok := runtime.Stage.Variable(name)
+		if !ok {
+			t.Fatalf("%s: missing variable `%s`", path, name)
+		}
+		return headless.ToString(value)
+	}
+	result := make(trace, 0)
+	for _, function := range functions(&program) {
+		for _, sample := range samples {
+			arguments := make([]headless.Value, 0)
+			for _, argument := range function.Arguments {
+				for range argument.TypeView.Slots {
+					arguments = append(arguments, sample)
+				}
+			}
+			err := runtime.Stage.Call(function.ProcCode, arguments...)
+			returned := make([]string, 0)
+			for _, name := range theOmitter.ReturnVariables(function) {
+				returned = append(returned, variable(name))
+			}
+			globalValues := make([]string, 0)
+			for _, global := range globals(&program) {
+				size := *global.TypeView.Type.GetSize()
+				if size == 1 {
+					globalValues = append(globalValues, variable(global.Name))
+					continue
+				}
+				for idx := range size {
+					globalValues = append(globalValues, variable(fmt.Sprintf("%s.%d", global.Name, idx)))
+				}
+			}
+			result = append(result, step(function, sample, returned, err, globalValues))
+		}
+	}
+	return result
+}
+
+// the interpreter is the reference of what the blocks compute: every
+// function of the examples and of the optimizer testdata returns the same
+// values and leaves the same globals when run by either backend, at every
+// level
+func TestInterpreterMatchesHeadless(t *testing.T) {
+	for path, source := range sources(t) {
+		reference := interpret(t, path, source, opt.O0)
+		for _, level := range []opt.Level{opt.O0, opt.O1, opt.O2} {
+			if actual := interpret(t, path, source, level); !slices.Equal(actual, reference) {
+				t.Errorf("%s: interpreted at O%d:\n%s\nexpected:\n%s", path, level, strings.Join(actual, "\n"), strings.Join(reference, "\n"))
+			}
+			if actual := omit(t, path, source, level); !slices.Equal(actual, reference) {
+				t.Errorf("%s: run by headless at O%d:\n%s\nexpected:\n%s", path, level, strings.Join(actual, "\n"), strings.Join(reference, "\n"))
+			}
+		}
+	}
+}
+
+const power = `func power() -> number @0 slots(0 "powerReturn000000000") stack 0 proccode "power" {
+    var base: number @0
+    base = 2
+    return (base ^ 10)
+}
+`
+
+// the omitter has no block for `^`, the verifier stops it before either
+// backend runs it
+func TestPowerIsRejected(t *testing.T) {
+	program, err := mir.Parse("power.mir", power)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mir.AllocateFrames(&program); err != nil {
+		t.Fatal(err)
+	}
+	if err := mir.Verify(&program); err == nil {
+		t.Error("expected `^` to be rejected")
+	}
+}
@@ -43,8 +43,11 @@ type FunctionDeclaration struct {
 	ProcCode       string
 	ArgumentIds    string
 	Warp           bool
-	StackSize      uint
-	Span           span.Span
+	// asks the optimizer to inline the calls to the function
+	Inline    bool
+	StackSize uint
+	Doc       string
+	Span      span.Span
 }
 
 type Argument struct {
@@ -72,6 +75,8 @@ const (
 	DeclareStatementType StatementType = iota
 	AssignStatementType
 	ReturnStatementType
+	LoopStatementType
+	IfStatementType
 )
 
 type Statement interface {
@@ -95,6 +100,7 @@ func (s *DeclareStatement) Type() StatementType {
 type AssignStatement struct {
 	Acessor Acessor
 	Value   Expression
+	Doc     string
 	Span    span.Span
 }
 
@@ -123,6 +129,7 @@ func (s *VariableAcessor) Type() AcessorType {
 
 type ReturnStatement struct {
 	Value Expression
+	Doc   string
 	Span  span.Span
 }
 
@@ -130,6 +137,43 @@ func (s *ReturnStatement) Type() StatementType {
 	return ReturnStatementType
 }
 
+// runs its body again and again, it is only left by a return
+type LoopStatement struct {
+	Body Block
+	Span span.Span
+}
+
+func (s *LoopStatement) Type() StatementType {
+	return LoopStatementType
+}
+
+// runs `Then` when its condition holds and `Else` otherwise, `Else` may be
+// empty
+type IfStatement struct {
+	Condition Expression
+	Then      Block
+	Else      Block
+	Span      span.Span
+}
+
+func (s *IfStatement) Type() StatementType {
+	return IfStatementType
+}
+
+// visits the statements of a block and of the blocks nested in them
+func WalkStatements(block Block, visit func(Statement)) {
+	for _, statement := range block.Statements {
+		visit(statement)
+		switch statement := statement.(type) {
+		case *LoopStatement:
+			WalkStatements(statement.Body, visit)
+		case *IfStatement:
+			WalkStatements(statement.Then, visit)
+			WalkStatements(statement.Else, visit)
+		}
+	}
+}
+
 type ExpressionType uint
 
 const (
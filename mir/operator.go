@@ -16,4 +16,5 @@ const (
 	OperatorAnd
 	OperatorOr
 	OperatorPow
+	OperatorNot
 )
@@ -0,0 +1,141 @@
+package opt
+
+import "yummy-go.com/m/v2/mir"
+
+// replaces the reads of a local assigned a literal or another local by the
+// value itself, as long as neither is assigned again. the facts do not
+// cross loops nor conditionals
+func PropagateCopies(program *mir.Program) bool {
+	changed := false
+	for _, function := range functions(program) {
+		if propagateCopies(function.Body) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func propagateCopies(block mir.Block) bool {
+	changed := false
+	// the value each local is known to hold
+	facts := make(map[mir.VariableDeclaration]mir.Expression)
+	substitute := func(expression mir.Expression) mir.Expression {
+		acessorExpression, ok := expression.(*mir.AcessorExpression)
+		if !ok {
+			return expression
+		}
+		declaration, ok := localOf(acessorExpression.Acessor)
+		if !ok {
+			return expression
+		}
+		fact, ok := facts[declaration]
+		if !ok {
+			return expression
+		}
+		// a fact standing for the same value leaves the tree as it was
+		if sameValue(fact, expression) {
+			return expression
+		}
+		changed = true
+		return cloneValue(fact)
+	}
+	kill := func(declaration mir.VariableDeclaration) {
+		delete(facts, declaration)
+		for other, fact := range facts {
+			if readsLocal(fact, declaration) {
+				delete(facts, other)
+			}
+		}
+	}
+	for _, statement := range block.Statements {
+		switch statement := statement.(type) {
+		case *mir.DeclareStatement:
+			kill(statement)
+		case *mir.AssignStatement:
+			statement.Value = rewriteExpression(statement.Value, substitute)
+			declaration, ok := localOf(statement.Acessor)
+			if !ok {
+				continue
+			}
+			kill(declaration)
+			if isCopyOf(statement.Value, declaration) {
+				facts[declaration] = statement.Value
+			}
+		case *mir.ReturnStatement:
+			statement.Value = rewriteExpression(statement.Value, substitute)
+		case *mir.LoopStatement:
+			facts = make(map[mir.VariableDeclaration]mir.Expression)
+			if propagateCopies(statement.Body) {
+				changed = true
+			}
+		case *mir.IfStatement:
+			statement.Condition = rewriteExpression(statement.Condition, substitute)
+			facts = make(map[mir.VariableDeclaration]mir.Expression)
+			for _, branch := range []mir.Block{statement.Then, statement.Else} {
+				if propagateCopies(branch) {
+					changed = true
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// whether the value can stand for the local: a literal of a single cell
+// local, or a whole other local of the same size
+func isCopyOf(value mir.Expression, declaration mir.VariableDeclaration) bool {
+	size := declaration.GetTypeView().Type.GetSize()
+	if size == nil {
+		return false
+	}
+	switch value := value.(type) {
+	case *mir.LiteralExpression:
+		return *size == 1
+	case *mir.AcessorExpression:
+		source, ok := localOf(value.Acessor)
+		if !ok || source == declaration {
+			return false
+		}
+		sourceSize := source.GetTypeView().Type.GetSize()
+		return sourceSize != nil && *sourceSize == *size
+	}
+	return false
+}
+
+// whether two values of facts are the same literal or read the same local
+func sameValue(lhs, rhs mir.Expression) bool {
+	switch lhs := lhs.(type) {
+	case *mir.LiteralExpression:
+		rhs, ok := rhs.(*mir.LiteralExpression)
+		return ok && lhs.Literal == rhs.Literal
+	case *mir.AcessorExpression:
+		rhs, ok := rhs.(*mir.AcessorExpression)
+		if !ok {
+			return false
+		}
+		lhsLocal, lhsOk := localOf(lhs.Acessor)
+		rhsLocal, rhsOk := localOf(rhs.Acessor)
+		return lhsOk && rhsOk && lhsLocal == rhsLocal
+	}
+	return false
+}
+
+// a fresh copy of a value of a fact, so no node is shared between
+// statements
+func cloneValue(value mir.Expression) mir.Expression {
+	switch value := value.(type) {
+	case *mir.LiteralExpression:
+		clone := *value
+		return &clone
+	case *mir.AcessorExpression:
+		if acessor, ok := value.Acessor.(*mir.VariableAcessor); ok {
+			return &mir.AcessorExpression{
+				Acessor: &mir.VariableAcessor{
+					Declaration: acessor.Declaration,
+					Span:        acessor.Span,
+				},
+			}
+		}
+	}
+	return value
+}
@@ -0,0 +1,171 @@
+package opt
+
+import "yummy-go.com/m/v2/mir"
+
+// drops the statements following a return, or a loop since loops are only
+// left by returns
+func RemoveUnreachable(program *mir.Program) bool {
+	changed := false
+	for _, function := range functions(program) {
+		if removeUnreachable(&function.Body) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func removeUnreachable(block *mir.Block) bool {
+	changed := false
+	for idx, statement := range block.Statements {
+		for _, nested := range nestedBlocks(statement) {
+			if removeUnreachable(nested) {
+				changed = true
+			}
+		}
+		switch statement.(type) {
+		case *mir.ReturnStatement, *mir.LoopStatement:
+			if idx+1 < len(block.Statements) {
+				block.Statements = block.Statements[:idx+1]
+				return true
+			}
+		}
+	}
+	return changed
+}
+
+// drops the locals that are never read, with the assignments to them that
+// have no side effects
+func EliminateDeadCode(program *mir.Program) bool {
+	changed := false
+	for _, function := range functions(program) {
+		for eliminateDeadCode(function) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func eliminateDeadCode(function *mir.FunctionDeclaration) bool {
+	reads := countReads(function.Body)
+	// locals still assigned after the pass, they keep their declaration
+	assigned := make(map[mir.VariableDeclaration]struct{})
+	changed := filterBlock(&function.Body, func(statement mir.Statement) bool {
+		assign, ok := statement.(*mir.AssignStatement)
+		if !ok {
+			return false
+		}
+		declaration, ok := localOf(assign.Acessor)
+		if !ok {
+			return false
+		}
+		if reads[declaration] == 0 && isPure(assign.Value) {
+			return true
+		}
+		assigned[declaration] = struct{}{}
+		return false
+	})
+	if filterBlock(&function.Body, func(statement mir.Statement) bool {
+		declare, ok := statement.(*mir.DeclareStatement)
+		if !ok {
+			return false
+		}
+		_, isAssigned := assigned[declare]
+		return reads[declare] == 0 && !isAssigned
+	}) {
+		changed = true
+	}
+	return changed
+}
+
+// drops the assignments whose value is overwritten before being read
+func EliminateDeadStores(program *mir.Program) bool {
+	changed := false
+	for _, function := range functions(program) {
+		if eliminateDeadStores(&function.Body, true) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// the block ending the function may also drop the stores never read again,
+// a loop body may not since the next iteration may read them
+func eliminateDeadStores(block *mir.Block, endsFunction bool) bool {
+	changed := false
+	dead := make(map[mir.Statement]struct{})
+	for idx, statement := range block.Statements {
+		// a loop body runs again and a branch is followed by the rest of
+		// the block, neither ends the function
+		for _, nested := range nestedBlocks(statement) {
+			if eliminateDeadStores(nested, false) {
+				changed = true
+			}
+		}
+		assign, ok := statement.(*mir.AssignStatement)
+		if !ok || !isPure(assign.Value) {
+			continue
+		}
+		declaration, ok := localOf(assign.Acessor)
+		if !ok {
+			continue
+		}
+		if overwrittenBeforeRead(block.Statements[idx+1:], declaration, endsFunction) {
+			dead[statement] = struct{}{}
+		}
+	}
+	if len(dead) == 0 {
+		return changed
+	}
+	statements := make([]mir.Statement, 0, len(block.Statements))
+	for _, statement := range block.Statements {
+		if _, ok := dead[statement]; !ok {
+			statements = append(statements, statement)
+		}
+	}
+	block.Statements = statements
+	return true
+}
+
+func overwrittenBeforeRead(statements []mir.Statement, declaration mir.VariableDeclaration, endsFunction bool) bool {
+	for _, statement := range statements {
+		switch statement := statement.(type) {
+		case *mir.AssignStatement:
+			if readsLocal(statement.Value, declaration) {
+				return false
+			}
+			if target, ok := localOf(statement.Acessor); ok && target == declaration {
+				return true
+			}
+		case *mir.ReturnStatement:
+			return !readsLocal(statement.Value, declaration)
+		case *mir.LoopStatement:
+			reads := countReads(statement.Body)
+			if reads[declaration] > 0 {
+				return false
+			}
+		case *mir.IfStatement:
+			// an assignment in a branch may not run, so only the reads count
+			if readsLocal(statement.Condition, declaration) {
+				return false
+			}
+			for _, branch := range []mir.Block{statement.Then, statement.Else} {
+				if reads := countReads(branch); reads[declaration] > 0 {
+					return false
+				}
+			}
+		}
+	}
+	return endsFunction
+}
+
+func readsLocal(expression mir.Expression, declaration mir.VariableDeclaration) bool {
+	reads := false
+	visitExpression(expression, func(expression mir.Expression) {
+		if acessorExpression, ok := expression.(*mir.AcessorExpression); ok {
+			if read, ok := localOf(acessorExpression.Acessor); ok && read == declaration {
+				reads = true
+			}
+		}
+	})
+	return reads
+}
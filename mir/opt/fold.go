@@ -0,0 +1,117 @@
+package opt
+
+import (
+	"math"
+
+	"yummy-go.com/m/v2/headless"
+	"yummy-go.com/m/v2/mir"
+)
+
+// evaluates the operators whose operands are literals, with the casts of
+// Scratch so the result is what the blocks would compute
+func FoldConstants(program *mir.Program) bool {
+	changed := false
+	for _, function := range functions(program) {
+		if rewriteBlock(function.Body, foldExpression) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func foldExpression(expression mir.Expression) mir.Expression {
+	switch expression := expression.(type) {
+	case *mir.BinaryExpression:
+		lhs, ok := expression.Lhs.(*mir.LiteralExpression)
+		if !ok {
+			return expression
+		}
+		rhs, ok := expression.Rhs.(*mir.LiteralExpression)
+		if !ok {
+			return expression
+		}
+		value, ok := foldBinary(expression, lhs.Literal, rhs.Literal)
+		if !ok {
+			return expression
+		}
+		return literal(value)
+	case *mir.UnaryExpression:
+		operand, ok := expression.Value.(*mir.LiteralExpression)
+		if !ok {
+			return expression
+		}
+		var value headless.Value
+		switch expression.Operator {
+		case mir.OperatorSub:
+			value = -headless.ToNumber(operand.Literal)
+		case mir.OperatorAdd:
+			value = headless.ToNumber(operand.Literal)
+		case mir.OperatorNot:
+			value = !headless.ToBoolean(operand.Literal)
+		default:
+			return expression
+		}
+		if !representable(value) {
+			return expression
+		}
+		return literal(value)
+	}
+	return expression
+}
+
+func foldBinary(expression *mir.BinaryExpression, l, r headless.Value) (headless.Value, bool) {
+	var value headless.Value
+	switch expression.Operator {
+	case mir.OperatorAdd:
+		if expression.OutputType != nil && expression.OutputType.Type() == mir.TypeString {
+			value = headless.ToString(l) + headless.ToString(r)
+		} else {
+			value = headless.ToNumber(l) + headless.ToNumber(r)
+		}
+	case mir.OperatorSub:
+		value = headless.ToNumber(l) - headless.ToNumber(r)
+	case mir.OperatorMul:
+		value = headless.ToNumber(l) * headless.ToNumber(r)
+	case mir.OperatorDiv:
+		value = headless.ToNumber(l) / headless.ToNumber(r)
+	case mir.OperatorPow:
+		value = math.Pow(headless.ToNumber(l), headless.ToNumber(r))
+	case mir.OperatorEq:
+		value = headless.Compare(l, r) == 0
+	case mir.OperatorNe:
+		value = headless.Compare(l, r) != 0
+	case mir.OperatorLt:
+		value = headless.Compare(l, r) < 0
+	case mir.OperatorGt:
+		value = headless.Compare(l, r) > 0
+	case mir.OperatorLe:
+		value = headless.Compare(l, r) <= 0
+	case mir.OperatorGe:
+		value = headless.Compare(l, r) >= 0
+	case mir.OperatorAnd:
+		value = headless.ToBoolean(l) && headless.ToBoolean(r)
+	case mir.OperatorOr:
+		value = headless.ToBoolean(l) || headless.ToBoolean(r)
+	default:
+		return nil, false
+	}
+	return value, representable(value)
+}
+
+// NaN and the infinities have no literal in a project
+func representable(value headless.Value) bool {
+	number, ok := value.(float64)
+	return !ok || !(math.IsNaN(number) || math.IsInf(number, 0))
+}
+
+func literal(value headless.Value) *mir.LiteralExpression {
+	switch value := value.(type) {
+	case float64:
+		return &mir.LiteralExpression{Literal: value, LiteralType: &mir.NumberType{}}
+	case string:
+		return &mir.LiteralExpression{Literal: value, LiteralType: &mir.StringType{}}
+	case bool:
+		return &mir.LiteralExpression{Literal: value, LiteralType: &mir.BooleanType{}}
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package opt
+
+import (
+	"fmt"
+
+	"yummy-go.com/m/v2/mir"
+)
+
+// the size under which O2 inlines a function without the `inline`
+// attribute, counted in statements and expression nodes
+const DefaultInlineThreshold = 12
+
+// substitutes the bodies of small or `inline` functions into their callers.
+// a call is inlined only when it is the first thing of its statement with
+// side effects, so hoisting the body above the statement keeps the order
+type Inliner struct {
+	// functions no larger than this are inlined, 0 only inlines the ones
+	// marked `inline`
+	Threshold uint
+	// the slots of each caller, the re-slotted locals take new ones
+	allocators map[*mir.FunctionDeclaration]*mir.SlotAllocator
+	// the number of calls inlined in each caller, to name the temporaries
+	counts map[*mir.FunctionDeclaration]uint
+}
+
+func NewInliner(threshold uint) *Inliner {
+	return &Inliner{
+		Threshold:  threshold,
+		allocators: make(map[*mir.FunctionDeclaration]*mir.SlotAllocator),
+		counts:     make(map[*mir.FunctionDeclaration]uint),
+	}
+}
+
+func (s *Inliner) Pass() Pass {
+	return Pass{
+		Name: "inlining",
+		Run:  s.Run,
+	}
+}
+
+func (s *Inliner) Run(program *mir.Program) bool {
+	recursive := mir.RecursiveFunctions(program)
+	changed := false
+	for _, caller := range functions(program) {
+		if s.inlineBlock(caller, &caller.Body, recursive) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (s *Inliner) inlineBlock(caller *mir.FunctionDeclaration, block *mir.Block, recursive map[*mir.FunctionDeclaration]struct{}) bool {
+	changed := false
+	statements := make([]mir.Statement, 0, len(block.Statements))
+	for _, statement := range block.Statements {
+		for _, nested := range nestedBlocks(statement) {
+			if s.inlineBlock(caller, nested, recursive) {
+				changed = true
+			}
+		}
+		call := s.candidate(caller, statement, recursive)
+		if call == nil {
+			statements = append(statements, statement)
+			continue
+		}
+		hoisted, result, slots := s.expand(caller, call)
+		replaceCall(statement, call, result)
+		statements = append(statements, hoisted...)
+		statements = append(statements, statement)
+		// the locals of the inlined body are dead after the statement, the
+		// next inlined call reuses their slots
+		s.allocators[caller].Free(slots)
+		changed = true
+	}
+	block.Statements = statements
+	return changed
+}
+
+// the call of a statement to inline, if any
+func (s *Inliner) candidate(caller *mir.FunctionDeclaration, statement mir.Statement, recursive map[*mir.FunctionDeclaration]struct{}) *mir.CallExpression {
+	calls := mir.StatementCalls(statement)
+	if len(calls) == 0 {
+		return nil
+	}
+	call := calls[0]
+	callee := call.Function
+	if callee == caller || !s.inlinable(callee) {
+		return nil
+	}
+	if _, ok := recursive[callee]; ok {
+		return nil
+	}
+	// the callee may change globals, which the statement must not read
+	// before the call
+	if readsGlobalsOutside(statement, call) {
+		return nil
+	}
+	return call
+}
+
+// a body of straight statements ending with a return of a value
+func (s *Inliner) inlinable(callee *mir.FunctionDeclaration) bool {
+	if callee.ReturnTypeView.Type == nil || callee.ReturnTypeView.Type.GetSize() == nil {
+		return false
+	}
+	statements := callee.Body.Statements
+	if len(statements) == 0 {
+		return false
+	}
+	for idx, statement := range statements {
+		switch statement := statement.(type) {
+		case *mir.LoopStatement, *mir.IfStatement:
+			return false
+		case *mir.ReturnStatement:
+			if idx != len(statements)-1 || statement.Value == nil {
+				return false
+			}
+		}
+	}
+	if _, ok := statements[len(statements)-1].(*mir.ReturnStatement); !ok {
+		return false
+	}
+	return callee.Inline || size(callee.Body) <= s.Threshold
+}
+
+func size(block mir.Block) uint {
+	var result uint = 0
+	count := func(expression mir.Expression) {
+		visitExpression(expression, func(mir.Expression) {
+			result += 1
+		})
+	}
+	mir.WalkStatements(block, func(statement mir.Statement) {
+		result += 1
+		switch statement := statement.(type) {
+		case *mir.AssignStatement:
+			count(statement.Value)
+		case *mir.ReturnStatement:
+			count(statement.Value)
+		case *mir.IfStatement:
+			count(statement.Condition)
+		}
+	})
+	return result
+}
+
+func readsGlobalsOutside(statement mir.Statement, call *mir.CallExpression) bool {
+	var value mir.Expression
+	switch statement := statement.(type) {
+	case *mir.AssignStatement:
+		// the store happens after the call anyway
+		value = statement.Value
+	case *mir.ReturnStatement:
+		value = statement.Value
+	case *mir.IfStatement:
+		value = statement.Condition
+	}
+	reads := false
+	var visit func(expression mir.Expression)
+	visit = func(expression mir.Expression) {
+		switch expression := expression.(type) {
+		case *mir.AcessorExpression:
+			if variableAcessor, ok := expression.Acessor.(*mir.VariableAcessor); ok {
+				if _, ok := variableAcessor.Declaration.(*mir.GlobalDeclaration); ok {
+					reads = true
+				}
+			}
+		case *mir.BinaryExpression:
+			visit(expression.Lhs)
+			visit(expression.Rhs)
+		case *mir.UnaryExpression:
+			visit(expression.Value)
+		case *mir.CallExpression:
+			// the arguments of the inlined call are evaluated before its body
+			if expression == call {
+				return
+			}
+			for _, argument := range expression.Arguments {
+				visit(argument)
+			}
+		}
+	}
+	visit(value)
+	return reads
+}
+
+// the statements running the body of the callee, the expression reading
+// its result and the slots its locals took
+func (s *Inliner) expand(caller *mir.FunctionDeclaration, call *mir.CallExpression) ([]mir.Statement, mir.Expression, []mir.Slot) {
+	callee := call.Function
+	s.counts[caller] += 1
+	prefix := fmt.Sprintf("(%s#%d)", callee.Name, s.counts[caller])
+	allocator, ok := s.allocators[caller]
+	if !ok {
+		theAllocator := mir.NewSlotAllocator("inline." + caller.Name)
+		allocator = &theAllocator
+		s.allocators[caller] = allocator
+	}
+	clone := cloner{
+		declarations: make(map[mir.VariableDeclaration]mir.VariableDeclaration),
+	}
+	slots := make([]mir.Slot, 0)
+	// the arguments and the result become plain locals, they need no slots
+	// whatever their size as locals live in the cells of the frame. only the
+	// locals of the callee keep their slots, re-slotted so no two live ones
+	// share one
+	declare := func(name string, typeView mir.TypeView, reslot bool) *mir.DeclareStatement {
+		declaration := &mir.DeclareStatement{
+			Name: prefix + name,
+			TypeView: mir.TypeView{
+				Type: typeView.Type,
+			},
+			Span: call.Function.Span,
+		}
+		if reslot && len(typeView.Slots) > 0 {
+			declaration.TypeView.Slots = allocator.AllocN(uint(len(typeView.Slots)))
+			slots = append(slots, declaration.TypeView.Slots...)
+		}
+		return declaration
+	}
+	hoisted := make([]mir.Statement, 0)
+	for idx := range callee.Arguments {
+		argument := &callee.Arguments[idx]
+		temporary := declare(argument.Name, argument.TypeView, false)
+		clone.declarations[argument] = temporary
+		hoisted = append(hoisted, temporary, &mir.AssignStatement{
+			Acessor: &mir.VariableAcessor{
+				Declaration: temporary,
+				Span:        argument.Span,
+			},
+			Value: call.Arguments[idx],
+			Span:  argument.Span,
+		})
+	}
+	result := declare("ret", callee.ReturnTypeView, false)
+	hoisted = append(hoisted, result)
+	for _, statement := range callee.Body.Statements {
+		switch statement := statement.(type) {
+		case *mir.DeclareStatement:
+			local := declare(statement.Name, statement.TypeView, true)
+			clone.declarations[statement] = local
+			hoisted = append(hoisted, local)
+		case *mir.AssignStatement:
+			hoisted = append(hoisted, &mir.AssignStatement{
+				Acessor: clone.acessor(statement.Acessor),
+				Value:   clone.expression(statement.Value),
+				Doc:     statement.Doc,
+				Span:    statement.Span,
+			})
+		case *mir.ReturnStatement:
+			hoisted = append(hoisted, &mir.AssignStatement{
+				Acessor: &mir.VariableAcessor{
+					Declaration: result,
+					Span:        statement.Span,
+				},
+				Value: clone.expression(statement.Value),
+				Doc:   statement.Doc,
+				Span:  statement.Span,
+			})
+		}
+	}
+	return hoisted, &mir.AcessorExpression{
+		Acessor: &mir.VariableAcessor{
+			Declaration: result,
+			Span:        callee.Span,
+		},
+	}, slots
+}
+
+// copies the statements of a callee, with its locals replaced
+type cloner struct {
+	declarations map[mir.VariableDeclaration]mir.VariableDeclaration
+}
+
+func (s *cloner) acessor(acessor mir.Acessor) mir.Acessor {
+	switch acessor := acessor.(type) {
+	case *mir.VariableAcessor:
+		declaration := acessor.Declaration
+		if replaced, ok := s.declarations[declaration]; ok {
+			declaration = replaced
+		}
+		return &mir.VariableAcessor{
+			Declaration: declaration,
+			Span:        acessor.Span,
+		}
+	}
+	return acessor
+}
+
+func (s *cloner) expression(expression mir.Expression) mir.Expression {
+	switch expression := expression.(type) {
+	case *mir.LiteralExpression:
+		clone := *expression
+		return &clone
+	case *mir.AcessorExpression:
+		return &mir.AcessorExpression{
+			Acessor: s.acessor(expression.Acessor),
+		}
+	case *mir.BinaryExpression:
+		return &mir.BinaryExpression{
+			Lhs:        s.expression(expression.Lhs),
+			Rhs:        s.expression(expression.Rhs),
+			Operator:   expression.Operator,
+			OutputType: expression.OutputType,
+		}
+	case *mir.UnaryExpression:
+		return &mir.UnaryExpression{
+			Value:      s.expression(expression.Value),
+			Operator:   expression.Operator,
+			OutputType: expression.OutputType,
+		}
+	case *mir.CallExpression:
+		arguments := make([]mir.Expression, 0, len(expression.Arguments))
+		for _, argument := range expression.Arguments {
+			arguments = append(arguments, s.expression(argument))
+		}
+		return &mir.CallExpression{
+			Function:  expression.Function,
+			Arguments: arguments,
+		}
+	}
+	return expression
+}
+
+// puts the result of an inlined call where the call was
+func replaceCall(statement mir.Statement, call *mir.CallExpression, result mir.Expression) {
+	replace := func(expression mir.Expression) mir.Expression {
+		if expression == mir.Expression(call) {
+			return result
+		}
+		return expression
+	}
+	switch statement := statement.(type) {
+	case *mir.AssignStatement:
+		statement.Value = rewriteExpression(statement.Value, replace)
+	case *mir.ReturnStatement:
+		statement.Value = rewriteExpression(statement.Value, replace)
+	case *mir.IfStatement:
+		statement.Condition = rewriteExpression(statement.Condition, replace)
+	}
+}
@@ -0,0 +1,123 @@
+package opt
+
+import (
+	"fmt"
+	"io"
+
+	"yummy-go.com/m/v2/mir"
+)
+
+// a pass rewrites a program in place and tells whether it changed anything
+type Pass struct {
+	Name string
+	Run  func(program *mir.Program) bool
+}
+
+type Level uint
+
+const (
+	O0 Level = iota
+	O1
+	O2
+)
+
+const DefaultLevel = O1
+
+// the passes run by Pipeline.Run, in order
+var (
+	ConstantFolding = Pass{
+		Name: "constant-folding",
+		Run:  FoldConstants,
+	}
+	UnreachableRemoval = Pass{
+		Name: "unreachable-removal",
+		Run:  RemoveUnreachable,
+	}
+	DeadCodeElimination = Pass{
+		Name: "dead-code-elimination",
+		Run:  EliminateDeadCode,
+	}
+	CopyPropagation = Pass{
+		Name: "copy-propagation",
+		Run:  PropagateCopies,
+	}
+	DeadStoreElimination = Pass{
+		Name: "dead-store-elimination",
+		Run:  EliminateDeadStores,
+	}
+)
+
+// the passes of each level
+func Passes(level Level) []Pass {
+	switch level {
+	case O0:
+		return []Pass{}
+	case O1:
+		// only the functions marked `inline`
+		inlining := NewInliner(0).Pass()
+		return []Pass{inlining, ConstantFolding, UnreachableRemoval, DeadCodeElimination}
+	}
+	inlining := NewInliner(DefaultInlineThreshold).Pass()
+	return []Pass{inlining, ConstantFolding, UnreachableRemoval, CopyPropagation, ConstantFolding, DeadStoreElimination, DeadCodeElimination}
+}
+
+// levels above O1 run their passes again until nothing changes
+const maxIterations = 8
+
+type Pipeline struct {
+	Level Level
+	// prints the program after each pass when set
+	Dump io.Writer
+}
+
+func New(level Level) Pipeline {
+	return Pipeline{
+		Level: level,
+	}
+}
+
+func (s *Pipeline) Run(program *mir.Program) {
+	iterations := 1
+	if s.Level >= O2 {
+		iterations = maxIterations
+	}
+	// the passes keep their state between iterations
+	passes := Passes(s.Level)
+	for iteration := range iterations {
+		changed := false
+		for _, pass := range passes {
+			passChanged := pass.Run(program)
+			changed = changed || passChanged
+			if s.Dump != nil {
+				fmt.Fprintf(s.Dump, "// after %s (iteration %d, changed: %t)\n", pass.Name, iteration+1, passChanged)
+				mir.Fprint(s.Dump, program)
+				fmt.Fprintln(s.Dump)
+			}
+		}
+		if !changed {
+			return
+		}
+	}
+}
+
+func ParseLevel(level string) (Level, error) {
+	switch level {
+	case "0":
+		return O0, nil
+	case "1":
+		return O1, nil
+	case "2":
+		return O2, nil
+	}
+	return O0, fmt.Errorf("unknown optimization level `%s`", level)
+}
+
+func functions(program *mir.Program) []*mir.FunctionDeclaration {
+	result := make([]*mir.FunctionDeclaration, 0)
+	for _, declaration := range program.Declarations {
+		if function, ok := declaration.(*mir.FunctionDeclaration); ok {
+			result = append(result, function)
+		}
+	}
+	return result
+}
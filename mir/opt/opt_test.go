@@ -0,0 +1,163 @@
+package opt_test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/mir/opt"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// the sources the pipeline is run on, the ones of testdata and the examples
+func sources(t *testing.T) map[string]string {
+	t.Helper()
+	result := make(map[string]string)
+	for _, pattern := range []string{"testdata/*.mir", "../../examples/*.mir"} {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result[path] = string(content)
+		}
+	}
+	return result
+}
+
+func parse(t *testing.T, path, source string) mir.Program {
+	t.Helper()
+	program, err := mir.Parse(path, source)
+	if err != nil {
+		t.Fatalf("parse %s: %s", path, err)
+	}
+	return program
+}
+
+// the program each level makes of a source of testdata is kept next to it,
+// `go test -update` rewrites them
+func TestGoldens(t *testing.T) {
+	paths, err := filepath.Glob("testdata/*.mir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, level := range []opt.Level{opt.O1, opt.O2} {
+			program := parse(t, path, string(source))
+			pipeline := opt.New(level)
+			pipeline.Run(&program)
+			actual := mir.Sprint(&program)
+			goldenPath := fmt.Sprintf("%s.O%d.golden", strings.TrimSuffix(path, ".mir"), level)
+			if *update {
+				if err := os.WriteFile(goldenPath, []byte(actual), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			expected, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != string(expected) {
+				t.Errorf("%s differs, found:\n%s", goldenPath, actual)
+			}
+		}
+	}
+}
+
+// a pass reports a change exactly when the printed program differs, so the
+// fixpoint loop of O2 stops as soon as nothing changes
+func TestChangedOnlyWhenDifferent(t *testing.T) {
+	for path, source := range sources(t) {
+		program := parse(t, path, source)
+		passes := opt.Passes(opt.O2)
+		for iteration := 1; ; iteration += 1 {
+			changed := false
+			for _, pass := range passes {
+				before := mir.Sprint(&program)
+				passChanged := pass.Run(&program)
+				if differs := mir.Sprint(&program) != before; passChanged != differs {
+					t.Errorf("%s: %s (iteration %d) reports changed: %t while the program differs: %t", path, pass.Name, iteration, passChanged, differs)
+				}
+				changed = changed || passChanged
+			}
+			if !changed {
+				break
+			}
+			if iteration == 8 {
+				t.Errorf("%s: still changing after %d iterations", path, iteration)
+				break
+			}
+		}
+	}
+}
+
+func TestPropagateCopiesReachesFixpoint(t *testing.T) {
+	source, err := os.ReadFile("testdata/copies.mir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	program := parse(t, "testdata/copies.mir", string(source))
+	if !opt.PropagateCopies(&program) {
+		t.Fatal("expected the copies to be propagated")
+	}
+	if opt.PropagateCopies(&program) {
+		t.Errorf("propagating again reports a change:\n%s", mir.Sprint(&program))
+	}
+}
+
+const reslotting = `func pair(x: number @0 slots(0 "pairArgument00000000")) -> number @0 slots(1 "pairReturn0000000000") stack 0 proccode "pair %s" inline {
+    var both: [number; 2] @0 slots(2 "pairLocal00000000000", 3 "pairLocal10000000000")
+    both = x
+    return x
+}
+
+func main() -> number @0 slots(4 "mainReturn0000000000") stack 0 proccode "main" {
+    var a: number @0
+    a = pair(1)
+    a = (a + pair(2))
+    return a
+}
+`
+
+// the locals of an inlined body are dead after its statement, the next
+// inlined call takes their slots again
+func TestInlinedLocalsReuseSlots(t *testing.T) {
+	program := parse(t, "reslotting.mir", reslotting)
+	opt.NewInliner(0).Run(&program)
+	slots := make([][]mir.Slot, 0)
+	for _, declaration := range program.Declarations {
+		function, ok := declaration.(*mir.FunctionDeclaration)
+		if !ok || function.Name != "main" {
+			continue
+		}
+		mir.WalkStatements(function.Body, func(statement mir.Statement) {
+			if local, ok := statement.(*mir.DeclareStatement); ok && strings.HasSuffix(local.Name, "both") {
+				slots = append(slots, local.TypeView.Slots)
+			}
+		})
+	}
+	if len(slots) != 2 {
+		t.Fatalf("expected two inlined copies of `both`, found %d", len(slots))
+	}
+	if len(slots[0]) != 2 || slots[0][1].Index != slots[0][0].Index+1 {
+		t.Errorf("expected a run of two slots, found %v", slots[0])
+	}
+	if !slices.Equal(slots[0], slots[1]) {
+		t.Errorf("expected the second call to reuse %v, found %v", slots[0], slots[1])
+	}
+}
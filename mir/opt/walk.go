@@ -0,0 +1,134 @@
+package opt
+
+import "yummy-go.com/m/v2/mir"
+
+// rebuilds an expression bottom-up, `rewrite` sees each node after its
+// operands were rewritten
+func rewriteExpression(expression mir.Expression, rewrite func(mir.Expression) mir.Expression) mir.Expression {
+	switch theExpression := expression.(type) {
+	case nil:
+		return nil
+	case *mir.BinaryExpression:
+		theExpression.Lhs = rewriteExpression(theExpression.Lhs, rewrite)
+		theExpression.Rhs = rewriteExpression(theExpression.Rhs, rewrite)
+	case *mir.UnaryExpression:
+		theExpression.Value = rewriteExpression(theExpression.Value, rewrite)
+	case *mir.CallExpression:
+		for idx, argument := range theExpression.Arguments {
+			theExpression.Arguments[idx] = rewriteExpression(argument, rewrite)
+		}
+	}
+	return rewrite(expression)
+}
+
+// rewrites every expression of a block and of the blocks nested in it,
+// returns whether any expression was replaced
+func rewriteBlock(block mir.Block, rewrite func(mir.Expression) mir.Expression) bool {
+	changed := false
+	tracked := func(expression mir.Expression) mir.Expression {
+		result := rewrite(expression)
+		if result != expression {
+			changed = true
+		}
+		return result
+	}
+	mir.WalkStatements(block, func(statement mir.Statement) {
+		switch statement := statement.(type) {
+		case *mir.AssignStatement:
+			statement.Value = rewriteExpression(statement.Value, tracked)
+		case *mir.ReturnStatement:
+			statement.Value = rewriteExpression(statement.Value, tracked)
+		case *mir.IfStatement:
+			statement.Condition = rewriteExpression(statement.Condition, tracked)
+		}
+	})
+	return changed
+}
+
+func visitExpression(expression mir.Expression, visit func(mir.Expression)) {
+	rewriteExpression(expression, func(expression mir.Expression) mir.Expression {
+		visit(expression)
+		return expression
+	})
+}
+
+// expressions without calls can be dropped or duplicated freely
+func isPure(expression mir.Expression) bool {
+	pure := true
+	visitExpression(expression, func(expression mir.Expression) {
+		if _, ok := expression.(*mir.CallExpression); ok {
+			pure = false
+		}
+	})
+	return pure
+}
+
+// the local or argument an acessor refers to, globals may be changed by
+// any call so they are never tracked
+func localOf(acessor mir.Acessor) (mir.VariableDeclaration, bool) {
+	variableAcessor, ok := acessor.(*mir.VariableAcessor)
+	if !ok {
+		return nil, false
+	}
+	switch declaration := variableAcessor.Declaration.(type) {
+	case *mir.DeclareStatement, *mir.Argument:
+		return declaration, true
+	}
+	return nil, false
+}
+
+// the number of times each local is read in a block
+func countReads(block mir.Block) map[mir.VariableDeclaration]uint {
+	reads := make(map[mir.VariableDeclaration]uint)
+	count := func(expression mir.Expression) {
+		visitExpression(expression, func(expression mir.Expression) {
+			if acessorExpression, ok := expression.(*mir.AcessorExpression); ok {
+				if declaration, ok := localOf(acessorExpression.Acessor); ok {
+					reads[declaration] += 1
+				}
+			}
+		})
+	}
+	mir.WalkStatements(block, func(statement mir.Statement) {
+		switch statement := statement.(type) {
+		case *mir.AssignStatement:
+			count(statement.Value)
+		case *mir.ReturnStatement:
+			count(statement.Value)
+		case *mir.IfStatement:
+			count(statement.Condition)
+		}
+	})
+	return reads
+}
+
+// removes the statements for which `drop` holds, in nested blocks too
+func filterBlock(block *mir.Block, drop func(mir.Statement) bool) bool {
+	changed := false
+	statements := make([]mir.Statement, 0, len(block.Statements))
+	for _, statement := range block.Statements {
+		if drop(statement) {
+			changed = true
+			continue
+		}
+		for _, nested := range nestedBlocks(statement) {
+			if filterBlock(nested, drop) {
+				changed = true
+			}
+		}
+		statements = append(statements, statement)
+	}
+	block.Statements = statements
+	return changed
+}
+
+// the blocks nested in a statement
+func nestedBlocks(statement mir.Statement) []*mir.Block {
+	switch statement := statement.(type) {
+	case *mir.LoopStatement:
+		return []*mir.Block{&statement.Body}
+	case *mir.IfStatement:
+		return []*mir.Block{&statement.Then, &statement.Else}
+	}
+	return nil
+}
@@ -0,0 +1,966 @@
+package mir
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"yummy-go.com/m/v2/span"
+)
+
+// reads the text form of mir written by Fprint
+func Parse(path, source string) (Program, error) {
+	lexer := newTextLexer(path, source)
+	tokens, err := lexer.tokenize()
+	if err != nil {
+		return Program{}, err
+	}
+	parser := textParser{
+		tokens:    tokens,
+		comments:  lexer.comments,
+		globals:   make(map[string]*GlobalDeclaration),
+		functions: make(map[string]*FunctionDeclaration),
+	}
+	return parser.program()
+}
+
+func ParseFile(path string) (Program, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return Program{}, span.ReportNoSpan(span.Error, "%s", err.Error())
+	}
+	return Parse(path, string(source))
+}
+
+type textTokenType uint
+
+const (
+	textEOF textTokenType = iota
+	textName
+	textNumber
+	textString
+	textPunct
+)
+
+type textToken struct {
+	Type textTokenType
+	// names are unquoted, other tokens keep their source
+	Text string
+	Span span.Span
+}
+
+func (s *textToken) is(punct string) bool {
+	return s.Type == textPunct && s.Text == punct
+}
+
+func (s *textToken) isKeyword(keyword string) bool {
+	return s.Type == textName && !s.raw() && s.Text == keyword
+}
+
+func (s *textToken) raw() bool {
+	return strings.HasPrefix(s.Span.String(), "#")
+}
+
+func (s *textToken) describe() string {
+	if s.Type == textEOF {
+		return "EOF"
+	}
+	return "`" + s.Span.String() + "`"
+}
+
+// the operators and punctuation, longest first
+var textPuncts = []string{
+	"->", "==", "!=", "<=", ">=", "&&", "||",
+	"(", ")", "{", "}", "[", "]", ",", ":", ";", "=", "@",
+	"+", "-", "*", "/", "<", ">", "^", "!",
+}
+
+type textLexer struct {
+	path        string
+	source      string
+	sourceLines []string
+	index       int
+	lineno      uint
+	lineIndex   uint
+	// the text of the line comments alone on their line, by line
+	comments map[uint]string
+}
+
+func newTextLexer(path, source string) *textLexer {
+	return &textLexer{
+		path:        path,
+		source:      source,
+		sourceLines: strings.Split(source, "\n"),
+		comments:    make(map[uint]string),
+	}
+}
+
+func (s *textLexer) position() span.Position {
+	return span.Position{
+		Index:     uint(s.index),
+		LineIndex: s.lineIndex,
+		Lineno:    s.lineno,
+	}
+}
+
+func (s *textLexer) spanFrom(from span.Position) span.Span {
+	return span.Span{
+		From:        from,
+		To:          s.position(),
+		Source:      &s.source,
+		SourceLines: &s.sourceLines,
+		Path:        &s.path,
+	}
+}
+
+func (s *textLexer) peek(offset int) byte {
+	if s.index+offset >= len(s.source) {
+		return 0
+	}
+	return s.source[s.index+offset]
+}
+
+func (s *textLexer) advance(n int) {
+	for range n {
+		if s.source[s.index] == '\n' {
+			s.lineno += 1
+			s.lineIndex = 0
+		} else {
+			s.lineIndex += 1
+		}
+		s.index += 1
+	}
+}
+
+func isTextDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
+
+func isTextNameChar(char byte) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || char == '_' || isTextDigit(char)
+}
+
+func (s *textLexer) tokenize() ([]textToken, error) {
+	tokens := make([]textToken, 0)
+	// whether a token was already found on the current line
+	lineStarted := false
+	for {
+		char := s.peek(0)
+		if char == 0 {
+			break
+		}
+		if char == '\n' {
+			lineStarted = false
+			s.advance(1)
+			continue
+		}
+		if char == ' ' || char == '\t' || char == '\r' {
+			s.advance(1)
+			continue
+		}
+		from := s.position()
+		if char == '/' && s.peek(1) == '/' {
+			start := s.index
+			for s.peek(0) != '\n' && s.peek(0) != 0 {
+				s.advance(1)
+			}
+			if !lineStarted {
+				text := strings.TrimPrefix(s.source[start:s.index], "//")
+				s.comments[from.Lineno] = strings.TrimPrefix(strings.TrimRight(text, "\r"), " ")
+			}
+			continue
+		}
+		lineStarted = true
+		token, err := s.token(char, tokens)
+		if err != nil {
+			return nil, err
+		}
+		token.Span = s.spanFrom(from)
+		tokens = append(tokens, token)
+	}
+	from := s.position()
+	tokens = append(tokens, textToken{
+		Type: textEOF,
+		Span: s.spanFrom(from),
+	})
+	return tokens, nil
+}
+
+func (s *textLexer) token(char byte, previous []textToken) (textToken, error) {
+	from := s.position()
+	switch {
+	case char == '"':
+		text, err := s.quoted()
+		if err != nil {
+			return textToken{}, err
+		}
+		return textToken{Type: textString, Text: text}, nil
+	case char == '#' && s.peek(1) == '"':
+		s.advance(1)
+		start := s.index
+		if _, err := s.quoted(); err != nil {
+			return textToken{}, err
+		}
+		name, err := strconv.Unquote(s.source[start:s.index])
+		if err != nil {
+			return textToken{}, span.Report(s.spanFrom(from), span.Error, "invalid raw name")
+		}
+		return textToken{Type: textName, Text: name}, nil
+	case isTextDigit(char) || (char == '-' && isTextDigit(s.peek(1)) && !endsValue(previous)):
+		start := s.index
+		s.advance(1)
+		for isTextDigit(s.peek(0)) || s.peek(0) == '.' {
+			s.advance(1)
+		}
+		if s.peek(0) == 'e' || s.peek(0) == 'E' {
+			s.advance(1)
+			if s.peek(0) == '+' || s.peek(0) == '-' {
+				s.advance(1)
+			}
+			for isTextDigit(s.peek(0)) {
+				s.advance(1)
+			}
+		}
+		return textToken{Type: textNumber, Text: s.source[start:s.index]}, nil
+	case isTextNameChar(char):
+		start := s.index
+		for isTextNameChar(s.peek(0)) {
+			s.advance(1)
+		}
+		return textToken{Type: textName, Text: s.source[start:s.index]}, nil
+	}
+	for _, punct := range textPuncts {
+		if strings.HasPrefix(s.source[s.index:], punct) {
+			s.advance(len(punct))
+			return textToken{Type: textPunct, Text: punct}, nil
+		}
+	}
+	s.advance(1)
+	return textToken{}, span.Report(s.spanFrom(from), span.Error, "unexpected char %c", char)
+}
+
+// a minus after a value is a binary operator, `a - 1` and `(a -1)` are
+// the same expression
+func endsValue(tokens []textToken) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	return last.Type == textName || last.Type == textNumber || last.Type == textString || last.is(")") || last.is("]")
+}
+
+// consumes a string literal, escapes included
+func (s *textLexer) quoted() (string, error) {
+	from := s.position()
+	start := s.index
+	s.advance(1)
+	for {
+		switch s.peek(0) {
+		case 0, '\n':
+			return "", span.Report(s.spanFrom(from), span.Error, "unterminated string")
+		case '\\':
+			s.advance(2)
+		case '"':
+			s.advance(1)
+			text, err := strconv.Unquote(s.source[start:s.index])
+			if err != nil {
+				return "", span.Report(s.spanFrom(from), span.Error, "invalid string")
+			}
+			return text, nil
+		default:
+			s.advance(1)
+		}
+	}
+}
+
+type textParser struct {
+	tokens   []textToken
+	comments map[uint]string
+	current  int
+	// the variables visible in the function being parsed, innermost last
+	scopes    []map[string]VariableDeclaration
+	globals   map[string]*GlobalDeclaration
+	functions map[string]*FunctionDeclaration
+	// globals and functions may be used before their declaration, so the
+	// uses are resolved at the end
+	pendingGlobals []pendingGlobal
+	pendingCalls   []pendingCall
+}
+
+type pendingGlobal struct {
+	acessor *VariableAcessor
+	token   textToken
+}
+
+type pendingCall struct {
+	call  *CallExpression
+	token textToken
+}
+
+func (s *textParser) peek() *textToken {
+	return &s.tokens[s.current]
+}
+
+func (s *textParser) consume() textToken {
+	token := s.tokens[s.current]
+	if token.Type != textEOF {
+		s.current += 1
+	}
+	return token
+}
+
+// the span from a token to the last consumed one
+func (s *textParser) spanFrom(first textToken) span.Span {
+	if s.current == 0 {
+		return first.Span
+	}
+	return span.Merge(first.Span, s.tokens[s.current-1].Span)
+}
+
+func (s *textParser) report(token *textToken, message string, args ...any) error {
+	return span.Report(token.Span, span.Error, message, args...)
+}
+
+func (s *textParser) expect(punct string) (textToken, error) {
+	token := s.peek()
+	if !token.is(punct) {
+		return *token, s.report(token, "expected `%s`, found %s", punct, token.describe())
+	}
+	return s.consume(), nil
+}
+
+func (s *textParser) expectKeyword(keyword string) error {
+	token := s.peek()
+	if !token.isKeyword(keyword) {
+		return s.report(token, "expected `%s`, found %s", keyword, token.describe())
+	}
+	s.consume()
+	return nil
+}
+
+func (s *textParser) name() (textToken, error) {
+	token := s.peek()
+	if token.Type != textName {
+		return *token, s.report(token, "expected a name, found %s", token.describe())
+	}
+	return s.consume(), nil
+}
+
+func (s *textParser) number() (uint, error) {
+	token := s.peek()
+	value, err := strconv.ParseUint(token.Text, 10, 0)
+	if token.Type != textNumber || err != nil {
+		return 0, s.report(token, "expected an unsigned integer, found %s", token.describe())
+	}
+	s.consume()
+	return uint(value), nil
+}
+
+func (s *textParser) string() (string, error) {
+	token := s.peek()
+	if token.Type != textString {
+		return "", s.report(token, "expected a string, found %s", token.describe())
+	}
+	s.consume()
+	return token.Text, nil
+}
+
+// the comments on the lines right above a line
+func (s *textParser) doc(lineno uint) string {
+	lines := make([]string, 0)
+	for line := int(lineno) - 1; line >= 0; line -= 1 {
+		text, ok := s.comments[uint(line)]
+		if !ok {
+			break
+		}
+		lines = append([]string{text}, lines...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *textParser) program() (Program, error) {
+	program := Program{
+		Declarations: make([]Declaration, 0),
+	}
+	for s.peek().Type != textEOF {
+		token := s.peek()
+		switch {
+		case token.isKeyword("global"):
+			global, err := s.global()
+			if err != nil {
+				return Program{}, err
+			}
+			program.Declarations = append(program.Declarations, global)
+		case token.isKeyword("func"):
+			function, err := s.function()
+			if err != nil {
+				return Program{}, err
+			}
+			program.Declarations = append(program.Declarations, function)
+		default:
+			return Program{}, s.report(token, "expected `global` or `func`, found %s", token.describe())
+		}
+	}
+	for _, pending := range s.pendingGlobals {
+		global, ok := s.globals[pending.token.Text]
+		if !ok {
+			return Program{}, s.report(&pending.token, "unknown variable `%s`", pending.token.Text)
+		}
+		pending.acessor.Declaration = global
+	}
+	for _, pending := range s.pendingCalls {
+		function, ok := s.functions[pending.token.Text]
+		if !ok {
+			return Program{}, s.report(&pending.token, "unknown function `%s`", pending.token.Text)
+		}
+		pending.call.Function = function
+	}
+	return program, nil
+}
+
+func (s *textParser) global() (*GlobalDeclaration, error) {
+	first := s.consume()
+	name, err := s.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.globals[name.Text]; ok {
+		return nil, s.report(&name, "global `%s` is declared twice", name.Text)
+	}
+	typeView, err := s.typedPlacement()
+	if err != nil {
+		return nil, err
+	}
+	global := &GlobalDeclaration{
+		Name:     name.Text,
+		TypeView: typeView,
+		Span:     s.spanFrom(first),
+	}
+	s.globals[name.Text] = global
+	return global, nil
+}
+
+// `: type @offset slots(...)`
+func (s *textParser) typedPlacement() (TypeView, error) {
+	if _, err := s.expect(":"); err != nil {
+		return TypeView{}, err
+	}
+	theType, err := s.parseType()
+	if err != nil {
+		return TypeView{}, err
+	}
+	typeView, err := s.placement()
+	typeView.Type = theType
+	return typeView, err
+}
+
+func (s *textParser) placement() (TypeView, error) {
+	if _, err := s.expect("@"); err != nil {
+		return TypeView{}, err
+	}
+	offset, err := s.number()
+	if err != nil {
+		return TypeView{}, err
+	}
+	typeView := TypeView{
+		Offset: offset,
+	}
+	if !s.peek().isKeyword("slots") {
+		return typeView, nil
+	}
+	s.consume()
+	if _, err := s.expect("("); err != nil {
+		return TypeView{}, err
+	}
+	typeView.Slots = make([]Slot, 0)
+	for !s.peek().is(")") {
+		if len(typeView.Slots) > 0 {
+			if _, err := s.expect(","); err != nil {
+				return TypeView{}, err
+			}
+		}
+		index, err := s.number()
+		if err != nil {
+			return TypeView{}, err
+		}
+		uuid, err := s.string()
+		if err != nil {
+			return TypeView{}, err
+		}
+		typeView.Slots = append(typeView.Slots, Slot{
+			Uuid:  uuid,
+			Index: index,
+		})
+	}
+	s.consume()
+	return typeView, nil
+}
+
+func (s *textParser) parseType() (Type, error) {
+	token := s.peek()
+	switch {
+	case token.isKeyword("number"):
+		s.consume()
+		return &NumberType{}, nil
+	case token.isKeyword("string"):
+		s.consume()
+		return &StringType{}, nil
+	case token.isKeyword("bool"):
+		s.consume()
+		return &BooleanType{}, nil
+	case token.isKeyword("untyped"):
+		s.consume()
+		return nil, nil
+	case token.is("["):
+		s.consume()
+		inner, err := s.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if s.peek().is("]") {
+			s.consume()
+			return &DynArrayType{Inner: inner}, nil
+		}
+		if _, err := s.expect(";"); err != nil {
+			return nil, err
+		}
+		n, err := s.number()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.expect("]"); err != nil {
+			return nil, err
+		}
+		return &ArrayType{Inner: inner, N: n}, nil
+	case token.isKeyword("struct"):
+		return s.structType()
+	}
+	return nil, s.report(token, "expected a type, found %s", token.describe())
+}
+
+// `struct(size) { name: type @offset, ... }`
+func (s *textParser) structType() (Type, error) {
+	s.consume()
+	if _, err := s.expect("("); err != nil {
+		return nil, err
+	}
+	size, err := s.number()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.expect(")"); err != nil {
+		return nil, err
+	}
+	if _, err := s.expect("{"); err != nil {
+		return nil, err
+	}
+	structType := &StructType{
+		Fields: make(map[string]StructField),
+		Size:   size,
+	}
+	for !s.peek().is("}") {
+		if len(structType.Fields) > 0 {
+			if _, err := s.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		name, err := s.name()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.expect(":"); err != nil {
+			return nil, err
+		}
+		fieldType, err := s.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.expect("@"); err != nil {
+			return nil, err
+		}
+		offset, err := s.number()
+		if err != nil {
+			return nil, err
+		}
+		structType.Fields[name.Text] = StructField{
+			Type:   fieldType,
+			Offset: offset,
+		}
+	}
+	s.consume()
+	return structType, nil
+}
+
+func (s *textParser) function() (*FunctionDeclaration, error) {
+	first := s.consume()
+	name, err := s.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.functions[name.Text]; ok {
+		return nil, s.report(&name, "function `%s` is declared twice", name.Text)
+	}
+	function := &FunctionDeclaration{
+		Name:      name.Text,
+		Arguments: make([]Argument, 0),
+		Doc:       s.doc(first.Span.From.Lineno),
+		Span:      s.spanFrom(first),
+	}
+	s.functions[name.Text] = function
+	if _, err := s.expect("("); err != nil {
+		return nil, err
+	}
+	for !s.peek().is(")") {
+		if len(function.Arguments) > 0 {
+			if _, err := s.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		argumentName, err := s.name()
+		if err != nil {
+			return nil, err
+		}
+		typeView, err := s.typedPlacement()
+		if err != nil {
+			return nil, err
+		}
+		function.Arguments = append(function.Arguments, Argument{
+			Name:     argumentName.Text,
+			TypeView: typeView,
+			Span:     s.spanFrom(argumentName),
+		})
+	}
+	s.consume()
+	if s.peek().is("->") {
+		s.consume()
+		returnType, err := s.parseType()
+		if err != nil {
+			return nil, err
+		}
+		function.ReturnTypeView, err = s.placement()
+		if err != nil {
+			return nil, err
+		}
+		function.ReturnTypeView.Type = returnType
+	}
+	if err := s.expectKeyword("stack"); err != nil {
+		return nil, err
+	}
+	if function.StackSize, err = s.number(); err != nil {
+		return nil, err
+	}
+	hasArgumentIds := false
+	for !s.peek().is("{") {
+		token := s.peek()
+		switch {
+		case token.isKeyword("proccode"):
+			s.consume()
+			if function.ProcCode, err = s.string(); err != nil {
+				return nil, err
+			}
+		case token.isKeyword("argumentids"):
+			s.consume()
+			if function.ArgumentIds, err = s.string(); err != nil {
+				return nil, err
+			}
+			hasArgumentIds = true
+		case token.isKeyword("warp"):
+			s.consume()
+			function.Warp = true
+		case token.isKeyword("inline"):
+			s.consume()
+			function.Inline = true
+		default:
+			return nil, s.report(token, "expected `{`, found %s", token.describe())
+		}
+	}
+	if !hasArgumentIds {
+		function.ArgumentIds = DefaultArgumentIds(function)
+	}
+	arguments := make(map[string]VariableDeclaration)
+	for idx := range function.Arguments {
+		arguments[function.Arguments[idx].Name] = &function.Arguments[idx]
+	}
+	s.scopes = []map[string]VariableDeclaration{arguments}
+	function.Body, err = s.block()
+	s.scopes = nil
+	return function, err
+}
+
+// `{ statements }`
+func (s *textParser) block() (Block, error) {
+	first, err := s.expect("{")
+	if err != nil {
+		return Block{}, err
+	}
+	s.scopes = append(s.scopes, make(map[string]VariableDeclaration))
+	defer func() {
+		s.scopes = s.scopes[:len(s.scopes)-1]
+	}()
+	block := Block{
+		Statements: make([]Statement, 0),
+	}
+	for !s.peek().is("}") {
+		if s.peek().Type == textEOF {
+			return Block{}, s.report(s.peek(), "expected `}`, found EOF")
+		}
+		statement, err := s.statement()
+		if err != nil {
+			return Block{}, err
+		}
+		block.Statements = append(block.Statements, statement)
+	}
+	s.consume()
+	block.Span = s.spanFrom(first)
+	return block, nil
+}
+
+func (s *textParser) statement() (Statement, error) {
+	first := *s.peek()
+	doc := s.doc(first.Span.From.Lineno)
+	switch {
+	case first.isKeyword("var"):
+		s.consume()
+		name, err := s.name()
+		if err != nil {
+			return nil, err
+		}
+		typeView, err := s.typedPlacement()
+		if err != nil {
+			return nil, err
+		}
+		declaration := &DeclareStatement{
+			Name:     name.Text,
+			TypeView: typeView,
+			Span:     s.spanFrom(first),
+		}
+		s.scopes[len(s.scopes)-1][name.Text] = declaration
+		return declaration, nil
+	case first.isKeyword("return"):
+		s.consume()
+		statement := &ReturnStatement{
+			Doc: doc,
+		}
+		// a value on the next line is another statement
+		if next := s.peek(); !next.is("}") && next.Span.From.Lineno == first.Span.From.Lineno {
+			value, err := s.expression()
+			if err != nil {
+				return nil, err
+			}
+			statement.Value = value
+		}
+		statement.Span = s.spanFrom(first)
+		return statement, nil
+	case first.isKeyword("loop"):
+		s.consume()
+		body, err := s.block()
+		if err != nil {
+			return nil, err
+		}
+		return &LoopStatement{
+			Body: body,
+			Span: s.spanFrom(first),
+		}, nil
+	case first.isKeyword("if"):
+		s.consume()
+		condition, err := s.expression()
+		if err != nil {
+			return nil, err
+		}
+		then, err := s.block()
+		if err != nil {
+			return nil, err
+		}
+		statement := &IfStatement{
+			Condition: condition,
+			Then:      then,
+			Else: Block{
+				Statements: make([]Statement, 0),
+			},
+		}
+		if s.peek().isKeyword("else") {
+			s.consume()
+			statement.Else, err = s.block()
+			if err != nil {
+				return nil, err
+			}
+		}
+		statement.Span = s.spanFrom(first)
+		return statement, nil
+	}
+	acessor, err := s.acessor()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.expect("="); err != nil {
+		return nil, err
+	}
+	value, err := s.expression()
+	if err != nil {
+		return nil, err
+	}
+	return &AssignStatement{
+		Acessor: acessor,
+		Value:   value,
+		Doc:     doc,
+		Span:    s.spanFrom(first),
+	}, nil
+}
+
+func (s *textParser) acessor() (*VariableAcessor, error) {
+	name, err := s.name()
+	if err != nil {
+		return nil, err
+	}
+	acessor := &VariableAcessor{
+		Span: name.Span,
+	}
+	for idx := len(s.scopes) - 1; idx >= 0; idx -= 1 {
+		if declaration, ok := s.scopes[idx][name.Text]; ok {
+			acessor.Declaration = declaration
+			return acessor, nil
+		}
+	}
+	s.pendingGlobals = append(s.pendingGlobals, pendingGlobal{
+		acessor: acessor,
+		token:   name,
+	})
+	return acessor, nil
+}
+
+var textOperators = func() map[string]OperatorType {
+	operators := make(map[string]OperatorType)
+	for operator, symbol := range operatorSymbols {
+		operators[symbol] = operator
+	}
+	return operators
+}()
+
+func (s *textParser) operator() (OperatorType, bool) {
+	token := s.peek()
+	if token.Type != textPunct {
+		return 0, false
+	}
+	operator, ok := textOperators[token.Text]
+	if ok {
+		s.consume()
+	}
+	return operator, ok
+}
+
+func (s *textParser) expression() (Expression, error) {
+	token := s.peek()
+	switch {
+	case token.Type == textNumber:
+		s.consume()
+		value, err := strconv.ParseFloat(token.Text, 64)
+		if err != nil {
+			return nil, s.report(token, "invalid number %s", token.describe())
+		}
+		return &LiteralExpression{Literal: value}, nil
+	case token.Type == textString:
+		s.consume()
+		return &LiteralExpression{Literal: token.Text}, nil
+	case token.isKeyword("true"), token.isKeyword("false"):
+		s.consume()
+		return &LiteralExpression{Literal: token.Text == "true"}, nil
+	case token.is("("):
+		return s.parenthesized()
+	case token.Type == textName:
+		if s.tokens[s.current+1].is("(") {
+			return s.call()
+		}
+		acessor, err := s.acessor()
+		if err != nil {
+			return nil, err
+		}
+		return &AcessorExpression{Acessor: acessor}, nil
+	}
+	return nil, s.report(token, "expected an expression, found %s", token.describe())
+}
+
+// `(number "NaN")`, `(op x: type)` or `(a op b: type)`
+func (s *textParser) parenthesized() (Expression, error) {
+	s.consume()
+	if token := s.peek(); token.isKeyword("number") {
+		s.consume()
+		text, err := s.string()
+		if err != nil {
+			return nil, err
+		}
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, s.report(token, "invalid number `%s`", text)
+		}
+		_, err = s.expect(")")
+		return &LiteralExpression{Literal: value}, err
+	}
+	var result Expression
+	var outputType *Type
+	if operator, ok := s.operator(); ok {
+		value, err := s.expression()
+		if err != nil {
+			return nil, err
+		}
+		unary := &UnaryExpression{
+			Value:    value,
+			Operator: operator,
+		}
+		result, outputType = unary, &unary.OutputType
+	} else {
+		lhs, err := s.expression()
+		if err != nil {
+			return nil, err
+		}
+		operator, ok := s.operator()
+		if !ok {
+			return nil, s.report(s.peek(), "expected an operator, found %s", s.peek().describe())
+		}
+		rhs, err := s.expression()
+		if err != nil {
+			return nil, err
+		}
+		binary := &BinaryExpression{
+			Lhs:      lhs,
+			Rhs:      rhs,
+			Operator: operator,
+		}
+		result, outputType = binary, &binary.OutputType
+	}
+	if s.peek().is(":") {
+		s.consume()
+		theType, err := s.parseType()
+		if err != nil {
+			return nil, err
+		}
+		*outputType = theType
+	}
+	_, err := s.expect(")")
+	return result, err
+}
+
+func (s *textParser) call() (Expression, error) {
+	name := s.consume()
+	s.consume()
+	call := &CallExpression{
+		Arguments: make([]Expression, 0),
+	}
+	for !s.peek().is(")") {
+		if len(call.Arguments) > 0 {
+			if _, err := s.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		argument, err := s.expression()
+		if err != nil {
+			return nil, err
+		}
+		call.Arguments = append(call.Arguments, argument)
+	}
+	s.consume()
+	s.pendingCalls = append(s.pendingCalls, pendingCall{
+		call:  call,
+		token: name,
+	})
+	return call, nil
+}
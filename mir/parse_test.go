@@ -0,0 +1,35 @@
+package mir_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"yummy-go.com/m/v2/mir"
+)
+
+// each source of testdata is printed back as it was written
+func TestRoundTrip(t *testing.T) {
+	paths, err := filepath.Glob("testdata/*.mir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no sources in testdata")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			program, err := mir.Parse(path, string(source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if printed := mir.Sprint(&program); printed != string(source) {
+				t.Errorf("printed differently:\n%s", printed)
+			}
+		})
+	}
+}
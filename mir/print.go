@@ -0,0 +1,304 @@
+package mir
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var operatorSymbols = map[OperatorType]string{
+	OperatorAdd: "+",
+	OperatorSub: "-",
+	OperatorMul: "*",
+	OperatorDiv: "/",
+	OperatorEq:  "==",
+	OperatorLt:  "<",
+	OperatorGt:  ">",
+	OperatorNe:  "!=",
+	OperatorLe:  "<=",
+	OperatorGe:  ">=",
+	OperatorAnd: "&&",
+	OperatorOr:  "||",
+	OperatorPow: "^",
+	OperatorNot: "!",
+}
+
+func (s OperatorType) String() string {
+	if symbol, ok := operatorSymbols[s]; ok {
+		return symbol
+	}
+	return fmt.Sprintf("(operator %d)", uint(s))
+}
+
+// prints a program in the text form of mir
+func Sprint(program *Program) string {
+	var builder strings.Builder
+	Fprint(&builder, program)
+	return builder.String()
+}
+
+func Fprint(w io.Writer, program *Program) {
+	printer := printer{w: w}
+	for idx, declaration := range program.Declarations {
+		if idx > 0 {
+			printer.line("")
+		}
+		switch declaration := declaration.(type) {
+		case *GlobalDeclaration:
+			printer.line("global %s: %s%s", FormatName(declaration.Name), FormatType(declaration.TypeView.Type), formatPlacement(declaration.TypeView))
+		case *FunctionDeclaration:
+			printer.function(declaration)
+		}
+	}
+}
+
+type printer struct {
+	w      io.Writer
+	indent int
+}
+
+func (s *printer) line(format string, args ...any) {
+	if format == "" {
+		fmt.Fprintln(s.w)
+		return
+	}
+	fmt.Fprint(s.w, strings.Repeat("    ", s.indent))
+	fmt.Fprintf(s.w, format, args...)
+	fmt.Fprintln(s.w)
+}
+
+func (s *printer) doc(doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		s.line("// %s", line)
+	}
+}
+
+func (s *printer) function(function *FunctionDeclaration) {
+	s.doc(function.Doc)
+	arguments := make([]string, 0, len(function.Arguments))
+	for _, argument := range function.Arguments {
+		arguments = append(arguments, fmt.Sprintf("%s: %s%s", FormatName(argument.Name), FormatType(argument.TypeView.Type), formatPlacement(argument.TypeView)))
+	}
+	header := fmt.Sprintf("func %s(%s)", FormatName(function.Name), strings.Join(arguments, ", "))
+	if function.ReturnTypeView.Type != nil {
+		header += fmt.Sprintf(" -> %s%s", FormatType(function.ReturnTypeView.Type), formatPlacement(function.ReturnTypeView))
+	}
+	header += fmt.Sprintf(" stack %d", function.StackSize)
+	if function.ProcCode != "" {
+		header += " proccode " + strconv.Quote(function.ProcCode)
+	}
+	if function.ArgumentIds != DefaultArgumentIds(function) {
+		header += " argumentids " + strconv.Quote(function.ArgumentIds)
+	}
+	if function.Warp {
+		header += " warp"
+	}
+	if function.Inline {
+		header += " inline"
+	}
+	s.line("%s {", header)
+	s.block(function.Body)
+	s.line("}")
+}
+
+func (s *printer) block(block Block) {
+	s.indent += 1
+	for _, statement := range block.Statements {
+		s.statement(statement)
+	}
+	s.indent -= 1
+}
+
+func (s *printer) statement(statement Statement) {
+	switch statement := statement.(type) {
+	case *DeclareStatement:
+		s.line("var %s: %s%s", FormatName(statement.Name), FormatType(statement.TypeView.Type), formatPlacement(statement.TypeView))
+	case *AssignStatement:
+		s.doc(statement.Doc)
+		s.line("%s = %s", FormatAcessor(statement.Acessor), FormatExpression(statement.Value))
+	case *ReturnStatement:
+		s.doc(statement.Doc)
+		if statement.Value == nil {
+			s.line("return")
+		} else {
+			s.line("return %s", FormatExpression(statement.Value))
+		}
+	case *LoopStatement:
+		s.line("loop {")
+		s.block(statement.Body)
+		s.line("}")
+	case *IfStatement:
+		s.line("if %s {", FormatExpression(statement.Condition))
+		s.block(statement.Then)
+		if len(statement.Else.Statements) == 0 {
+			s.line("}")
+			return
+		}
+		s.line("} else {")
+		s.block(statement.Else)
+		s.line("}")
+	default:
+		s.line("(unknown statement)")
+	}
+}
+
+// the arguments ids of a procedure are the uuids of the slots of its
+// arguments unless told otherwise
+func DefaultArgumentIds(function *FunctionDeclaration) string {
+	ids := make([]string, 0)
+	for _, argument := range function.Arguments {
+		for _, slot := range argument.TypeView.Slots {
+			ids = append(ids, slot.Uuid)
+		}
+	}
+	content, _ := json.Marshal(ids)
+	return string(content)
+}
+
+// ` @offset` and the slots of a type view, if any
+func formatPlacement(typeView TypeView) string {
+	result := fmt.Sprintf(" @%d", typeView.Offset)
+	if len(typeView.Slots) == 0 {
+		return result
+	}
+	slots := make([]string, 0, len(typeView.Slots))
+	for _, slot := range typeView.Slots {
+		slots = append(slots, fmt.Sprintf("%d %s", slot.Index, strconv.Quote(slot.Uuid)))
+	}
+	return result + " slots(" + strings.Join(slots, ", ") + ")"
+}
+
+var keywords = map[string]struct{}{
+	"func": {}, "global": {}, "var": {}, "return": {}, "loop": {}, "if": {}, "else": {},
+	"stack": {}, "proccode": {}, "argumentids": {}, "warp": {}, "inline": {}, "slots": {},
+	"number": {}, "string": {}, "bool": {}, "struct": {}, "untyped": {},
+	"true": {}, "false": {},
+}
+
+// names that are not identifiers are written as raw identifiers `#"..."`
+func FormatName(name string) string {
+	if _, ok := keywords[name]; ok || !isIdentifier(name) {
+		return "#" + strconv.Quote(name)
+	}
+	return name
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for idx, char := range name {
+		alpha := (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || char == '_'
+		if !alpha && (idx == 0 || char < '0' || char > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func FormatType(theType Type) string {
+	switch theType := theType.(type) {
+	case nil:
+		return "untyped"
+	case *NumberType:
+		return "number"
+	case *StringType:
+		return "string"
+	case *BooleanType:
+		return "bool"
+	case *ArrayType:
+		return fmt.Sprintf("[%s; %d]", FormatType(theType.Inner), theType.N)
+	case *DynArrayType:
+		return fmt.Sprintf("[%s]", FormatType(theType.Inner))
+	case *StructType:
+		names := make([]string, 0, len(theType.Fields))
+		for name := range theType.Fields {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			lhs, rhs := theType.Fields[names[i]], theType.Fields[names[j]]
+			if lhs.Offset != rhs.Offset {
+				return lhs.Offset < rhs.Offset
+			}
+			return names[i] < names[j]
+		})
+		fields := make([]string, 0, len(names))
+		for _, name := range names {
+			field := theType.Fields[name]
+			fields = append(fields, fmt.Sprintf("%s: %s @%d", FormatName(name), FormatType(field.Type), field.Offset))
+		}
+		return fmt.Sprintf("struct(%d) { %s }", theType.Size, strings.Join(fields, ", "))
+	}
+	return "untyped"
+}
+
+func FormatAcessor(acessor Acessor) string {
+	switch acessor := acessor.(type) {
+	case *VariableAcessor:
+		return FormatName(declarationName(acessor.Declaration))
+	}
+	return "(unknown acessor)"
+}
+
+func declarationName(declaration VariableDeclaration) string {
+	switch declaration := declaration.(type) {
+	case *GlobalDeclaration:
+		return declaration.Name
+	case *Argument:
+		return declaration.Name
+	case *DeclareStatement:
+		return declaration.Name
+	}
+	return "(unknown)"
+}
+
+func FormatLiteral(literal any) string {
+	switch literal := literal.(type) {
+	case float64:
+		if math.IsNaN(literal) || math.IsInf(literal, 0) {
+			return fmt.Sprintf("(number %s)", strconv.Quote(strconv.FormatFloat(literal, 'g', -1, 64)))
+		}
+		return strconv.FormatFloat(literal, 'g', -1, 64)
+	case string:
+		return strconv.Quote(literal)
+	case bool:
+		return strconv.FormatBool(literal)
+	}
+	return "(unknown literal)"
+}
+
+func FormatExpression(expression Expression) string {
+	switch expression := expression.(type) {
+	case *LiteralExpression:
+		return FormatLiteral(expression.Literal)
+	case *AcessorExpression:
+		return FormatAcessor(expression.Acessor)
+	case *BinaryExpression:
+		result := fmt.Sprintf("%s %s %s", FormatExpression(expression.Lhs), expression.Operator, FormatExpression(expression.Rhs))
+		if expression.OutputType != nil {
+			result += ": " + FormatType(expression.OutputType)
+		}
+		return "(" + result + ")"
+	case *UnaryExpression:
+		// `(- 1)` is not the literal `-1`
+		result := fmt.Sprintf("%s %s", expression.Operator, FormatExpression(expression.Value))
+		if expression.OutputType != nil {
+			result += ": " + FormatType(expression.OutputType)
+		}
+		return "(" + result + ")"
+	case *CallExpression:
+		arguments := make([]string, 0, len(expression.Arguments))
+		for _, argument := range expression.Arguments {
+			arguments = append(arguments, FormatExpression(argument))
+		}
+		return fmt.Sprintf("%s(%s)", FormatName(expression.Function.Name), strings.Join(arguments, ", "))
+	}
+	return "(unknown expression)"
+}
@@ -1,7 +1,10 @@
 package mir
 
 import (
-	"github.com/google/uuid"
+	"slices"
+	"strconv"
+
+	"yummy-go.com/m/v2/idgen"
 )
 
 type Slot struct {
@@ -9,39 +12,80 @@ type Slot struct {
 	Index uint
 }
 
-func NewSlot(index uint) Slot {
+// slots of the same seed and index always have the same uuid
+func NewSlot(seed string, index uint) Slot {
 	return Slot{
-		Uuid:  uuid.NewString(),
+		Uuid:  idgen.Derive("slot", seed, strconv.FormatUint(uint64(index), 10)),
 		Index: index,
 	}
 }
 
 type SlotAllocator struct {
-	freeSlots map[Slot]struct{}
-	transform uint
+	seed    string
+	indices indexAllocator
 }
 
-func NewSlotAllocator() SlotAllocator {
+// allocators sharing a program must have different seeds
+func NewSlotAllocator(seed string) SlotAllocator {
 	return SlotAllocator{
-		freeSlots: make(map[Slot]struct{}),
-		transform: 0,
+		seed: seed,
 	}
 }
 
+// allocates n slots of consecutive indices, reusing freed ones first
 func (s *SlotAllocator) AllocN(n uint) []Slot {
-	slots := make([]Slot, 0)
-	for slot := range s.freeSlots {
-		slots = append(slots, slot)
-		if uint(len(slots)) == n {
+	first := s.indices.alloc(n)
+	slots := make([]Slot, 0, n)
+	for idx := range n {
+		slots = append(slots, NewSlot(s.seed, first+idx))
+	}
+	return slots
+}
+
+// gives the slots back, they are reused by later allocations
+func (s *SlotAllocator) Free(slots []Slot) {
+	for _, slot := range slots {
+		s.indices.free(slot.Index, 1)
+	}
+}
+
+// hands out runs of consecutive indices, the lowest free run first so the
+// result only depends on the order of the calls
+type indexAllocator struct {
+	// sorted, without duplicates
+	freeIndices []uint
+	next        uint
+}
+
+func (s *indexAllocator) alloc(n uint) uint {
+	if n == 0 {
+		return s.next
+	}
+	for idx := range s.freeIndices {
+		if idx+int(n) > len(s.freeIndices) {
 			break
 		}
+		first := s.freeIndices[idx]
+		if s.freeIndices[idx+int(n)-1] == first+n-1 {
+			s.freeIndices = slices.Delete(s.freeIndices, idx, idx+int(n))
+			return first
+		}
 	}
-	for _, slot := range slots {
-		delete(s.freeSlots, slot)
+	// a free run at the end grows into new indices
+	first := s.next
+	for len(s.freeIndices) > 0 && s.freeIndices[len(s.freeIndices)-1] == first-1 {
+		first -= 1
+		s.freeIndices = s.freeIndices[:len(s.freeIndices)-1]
 	}
-	for uint(len(slots)) < n {
-		slots = append(slots, NewSlot(s.transform))
-		s.transform += 1
+	s.next = first + n
+	return first
+}
+
+func (s *indexAllocator) free(first, n uint) {
+	for index := first; index < first+n; index += 1 {
+		position, found := slices.BinarySearch(s.freeIndices, index)
+		if !found && index < s.next {
+			s.freeIndices = slices.Insert(s.freeIndices, position, index)
+		}
 	}
-	return slots
 }
@@ -0,0 +1,72 @@
+package mir
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIndexAllocator(t *testing.T) {
+	var allocator indexAllocator
+	for _, test := range []struct {
+		name  string
+		free  [2]uint
+		alloc uint
+		first uint
+	}{
+		{"fresh run", [2]uint{}, 3, 0},
+		{"after it", [2]uint{}, 2, 3},
+		// 0..2 and 3..4 are taken, 1 is given back
+		{"single hole", [2]uint{1, 1}, 1, 1},
+		// 1..2 are free, a run of 2 fits in them
+		{"run in a hole", [2]uint{1, 2}, 2, 1},
+		// 3..4 are free and end the allocated ones, the run grows from 3
+		{"growing run", [2]uint{3, 2}, 4, 3},
+		{"nothing", [2]uint{}, 0, 7},
+	} {
+		if test.free[1] > 0 {
+			allocator.free(test.free[0], test.free[1])
+		}
+		if first := allocator.alloc(test.alloc); first != test.first {
+			t.Errorf("%s: expected %d, found %d", test.name, test.first, first)
+		}
+	}
+	// indices never handed out are not freed
+	allocator.free(20, 1)
+	if slices.Contains(allocator.freeIndices, 20) {
+		t.Error("freed an index never allocated")
+	}
+	// freeing twice keeps one copy
+	allocator.free(2, 1)
+	allocator.free(2, 1)
+	if !slices.Equal(allocator.freeIndices, []uint{2}) {
+		t.Errorf("expected 2 free once, found %v", allocator.freeIndices)
+	}
+}
+
+func TestSlotAllocatorReusesFreedSlots(t *testing.T) {
+	allocator := NewSlotAllocator("test")
+	first := allocator.AllocN(2)
+	second := allocator.AllocN(1)
+	allocator.Free(first)
+	// a run of 3 does not fit in the hole of 2, the hole stays
+	third := allocator.AllocN(3)
+	if third[0].Index != 3 {
+		t.Errorf("expected the run after the taken ones, found %d", third[0].Index)
+	}
+	reused := allocator.AllocN(2)
+	if !slices.Equal(reused, first) {
+		t.Errorf("expected the freed slots %v, found %v", first, reused)
+	}
+	if second[0].Index != 2 || second[0] != NewSlot("test", 2) {
+		t.Errorf("unexpected slot %v", second[0])
+	}
+	// the same calls give the same slots
+	again := NewSlotAllocator("test")
+	again.AllocN(2)
+	again.AllocN(1)
+	again.Free(first)
+	again.AllocN(3)
+	if slots := again.AllocN(2); !slices.Equal(slots, reused) {
+		t.Errorf("expected %v, found %v", reused, slots)
+	}
+}
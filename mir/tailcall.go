@@ -0,0 +1,124 @@
+package mir
+
+import "fmt"
+
+// rewrites the self tail calls of each function into loops
+func EliminateTailCalls(program *Program) {
+	for _, declaration := range program.Declarations {
+		if function, ok := declaration.(*FunctionDeclaration); ok {
+			EliminateTailCall(function)
+		}
+	}
+}
+
+// a function ending with `return f(...)` where f is the function itself,
+// maybe in a branch of a final `if`, reuses its frame: the body runs in a
+// loop, and the tail call becomes the assignment of the new arguments. the
+// arguments are evaluated into temporaries first since they may read the
+// current ones. the loop is only left by a return, so every other way out
+// of the body must be one, or the function would run again instead of
+// ending. returns whether the function was rewritten
+func EliminateTailCall(function *FunctionDeclaration) bool {
+	tails, ok := tailCalls(function, function.Body)
+	if !ok || len(tails) == 0 {
+		return false
+	}
+	rewriteTailCalls(function, &function.Body)
+	function.Body = Block{
+		Statements: []Statement{
+			&LoopStatement{
+				Body: function.Body,
+				Span: function.Body.Span,
+			},
+		},
+		Span: function.Body.Span,
+	}
+	return true
+}
+
+// the self tail calls ending a block, and whether each way out of the block
+// is either one of them or another return
+func tailCalls(function *FunctionDeclaration, block Block) ([]*ReturnStatement, bool) {
+	statements := block.Statements
+	if len(statements) == 0 {
+		return nil, false
+	}
+	switch last := statements[len(statements)-1].(type) {
+	case *ReturnStatement:
+		if isSelfCall(function, last.Value) {
+			return []*ReturnStatement{last}, true
+		}
+		return nil, true
+	case *IfStatement:
+		thenTails, ok := tailCalls(function, last.Then)
+		if !ok {
+			return nil, false
+		}
+		elseTails, ok := tailCalls(function, last.Else)
+		if !ok {
+			return nil, false
+		}
+		return append(thenTails, elseTails...), true
+	}
+	return nil, false
+}
+
+func isSelfCall(function *FunctionDeclaration, value Expression) bool {
+	call, ok := value.(*CallExpression)
+	return ok && call.Function == function && len(call.Arguments) == len(function.Arguments)
+}
+
+// replaces the self tail calls of the block, found by tailCalls, by the
+// assignments of the new arguments
+func rewriteTailCalls(function *FunctionDeclaration, block *Block) {
+	statements := block.Statements
+	switch last := statements[len(statements)-1].(type) {
+	case *ReturnStatement:
+		if isSelfCall(function, last.Value) {
+			block.Statements = append(statements[:len(statements)-1], nextArguments(function, last)...)
+		}
+	case *IfStatement:
+		rewriteTailCalls(function, &last.Then)
+		rewriteTailCalls(function, &last.Else)
+	}
+}
+
+func nextArguments(function *FunctionDeclaration, tail *ReturnStatement) []Statement {
+	call := tail.Value.(*CallExpression)
+	statements := make([]Statement, 0, 3*len(call.Arguments))
+	temporaries := make([]*DeclareStatement, 0, len(call.Arguments))
+	for idx, argument := range call.Arguments {
+		temporary := &DeclareStatement{
+			Name: fmt.Sprintf("(tail)%s", function.Arguments[idx].Name),
+			TypeView: TypeView{
+				Type: function.Arguments[idx].TypeView.Type,
+			},
+			Span: tail.Span,
+		}
+		temporaries = append(temporaries, temporary)
+		statements = append(statements, temporary, &AssignStatement{
+			Acessor: &VariableAcessor{
+				Declaration: temporary,
+				Span:        tail.Span,
+			},
+			Value: argument,
+			Span:  tail.Span,
+		})
+	}
+	for idx, temporary := range temporaries {
+		statements = append(statements, &AssignStatement{
+			Acessor: &VariableAcessor{
+				Declaration: &function.Arguments[idx],
+				Span:        tail.Span,
+			},
+			Value: &AcessorExpression{
+				Acessor: &VariableAcessor{
+					Declaration: temporary,
+					Span:        tail.Span,
+				},
+			},
+			Span: tail.Span,
+		})
+	}
+	return statements
+}
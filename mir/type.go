@@ -76,6 +76,10 @@ func (s *ArrayType) Type() TypeType {
 }
 
 func (s *ArrayType) GetSize() *uint {
+	// an array of dyn-sized values has no size either
+	if s.Inner == nil || s.Inner.GetSize() == nil {
+		return nil
+	}
 	var len uint = *s.Inner.GetSize() * s.N
 	return &len
 }
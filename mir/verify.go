@@ -0,0 +1,246 @@
+package mir
+
+import (
+	"fmt"
+
+	"yummy-go.com/m/v2/span"
+)
+
+// checks a frame-allocated program before it is omitted. every problem is
+// reported with the span of the node it was found in
+func Verify(program *Program) error {
+	verifier := verifier{}
+	for _, declaration := range program.Declarations {
+		switch declaration := declaration.(type) {
+		case *GlobalDeclaration:
+			verifier.typeView(declaration.TypeView, false, declaration.Span, "global `%s`", declaration.Name)
+		case *FunctionDeclaration:
+			verifier.function(declaration)
+		}
+	}
+	if verifier.problems == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s found by the mir verifier", span.Pluralize(verifier.problems, "problem", "problems"))
+}
+
+type verifier struct {
+	verifying *FunctionDeclaration
+	problems  uint
+}
+
+func (s *verifier) report(theSpan span.Span, message string, args ...any) {
+	s.problems += 1
+	message = fmt.Sprintf(message, args...)
+	if s.verifying != nil {
+		message = fmt.Sprintf("function `%s`: %s", s.verifying.Name, message)
+	}
+	if theSpan.Path == nil || theSpan.SourceLines == nil {
+		span.ReportNoSpan(span.Error, "%s", message)
+		return
+	}
+	span.Report(theSpan, span.Error, "%s", message)
+}
+
+func sizeOf(theType Type) *uint {
+	if theType == nil {
+		return nil
+	}
+	return theType.GetSize()
+}
+
+// the value must have a size, and as many slots when it has some or when
+// they are required
+func (s *verifier) typeView(typeView TypeView, slotsRequired bool, theSpan span.Span, what string, args ...any) {
+	what = fmt.Sprintf(what, args...)
+	size := sizeOf(typeView.Type)
+	if size == nil {
+		s.report(theSpan, "%s: `%s` has no fixed size", what, FormatType(typeView.Type))
+		return
+	}
+	if (slotsRequired || len(typeView.Slots) > 0) && uint(len(typeView.Slots)) != *size {
+		s.report(theSpan, "%s: %s for `%s` of size %d", what, span.Pluralize(uint(len(typeView.Slots)), "slot", "slots"), FormatType(typeView.Type), *size)
+	}
+}
+
+// the local must fit in the frame
+func (s *verifier) frameCells(typeView TypeView, theSpan span.Span, what string, name string) {
+	size := sizeOf(typeView.Type)
+	if size == nil {
+		return
+	}
+	if typeView.Offset+*size > s.verifying.StackSize {
+		s.report(theSpan, "%s `%s` at @%d does not fit in a stack of %d", what, name, typeView.Offset, s.verifying.StackSize)
+	}
+}
+
+func (s *verifier) function(function *FunctionDeclaration) {
+	s.verifying = function
+	defer func() {
+		s.verifying = nil
+	}()
+	for _, argument := range function.Arguments {
+		s.typeView(argument.TypeView, true, argument.Span, "argument `%s`", argument.Name)
+		s.frameCells(argument.TypeView, argument.Span, "argument", argument.Name)
+	}
+	if function.ReturnTypeView.Type != nil {
+		s.typeView(function.ReturnTypeView, true, function.Span, "return value")
+	}
+	s.block(function.Body)
+}
+
+func (s *verifier) block(block Block) {
+	for _, statement := range block.Statements {
+		switch statement := statement.(type) {
+		case *DeclareStatement:
+			s.typeView(statement.TypeView, false, statement.Span, "local `%s`", statement.Name)
+			s.frameCells(statement.TypeView, statement.Span, "local", statement.Name)
+		case *AssignStatement:
+			acessorType := s.acessor(statement.Acessor, statement.Span)
+			valueType := s.expression(statement.Value, statement.Span)
+			if !SameType(acessorType, valueType) {
+				s.report(statement.Span, "cannot assign `%s` to `%s` of type `%s`", FormatType(valueType), FormatAcessor(statement.Acessor), FormatType(acessorType))
+			}
+		case *ReturnStatement:
+			returnType := s.verifying.ReturnTypeView.Type
+			if statement.Value == nil {
+				if returnType != nil {
+					s.report(statement.Span, "missing a return value of type `%s`", FormatType(returnType))
+				}
+				continue
+			}
+			valueType := s.expression(statement.Value, statement.Span)
+			if returnType == nil {
+				s.report(statement.Span, "returns a value but has no return type")
+			} else if !SameType(returnType, valueType) {
+				s.report(statement.Span, "cannot return `%s` as `%s`", FormatType(valueType), FormatType(returnType))
+			}
+		case *LoopStatement:
+			s.block(statement.Body)
+		case *IfStatement:
+			conditionType := s.expression(statement.Condition, statement.Span)
+			if !SameType(conditionType, &BooleanType{}) {
+				s.report(statement.Span, "condition of type `%s` is not a `bool`", FormatType(conditionType))
+			}
+			s.block(statement.Then)
+			s.block(statement.Else)
+		}
+	}
+}
+
+func (s *verifier) acessor(acessor Acessor, theSpan span.Span) Type {
+	switch acessor := acessor.(type) {
+	case *VariableAcessor:
+		if acessor.Declaration == nil {
+			s.report(theSpan, "`%s` refers to nothing", FormatAcessor(acessor))
+			return nil
+		}
+		return acessor.Declaration.GetTypeView().Type
+	}
+	return nil
+}
+
+// the type of the value of an expression, nil when unknown
+func (s *verifier) expression(expression Expression, theSpan span.Span) Type {
+	switch expression := expression.(type) {
+	case *LiteralExpression:
+		switch expression.Literal.(type) {
+		case float64:
+			return &NumberType{}
+		case string:
+			return &StringType{}
+		case bool:
+			return &BooleanType{}
+		}
+		s.report(theSpan, "unknown literal `%v`", expression.Literal)
+	case *AcessorExpression:
+		return s.acessor(expression.Acessor, theSpan)
+	case *BinaryExpression:
+		s.operand(expression.Lhs, expression.Operator, theSpan)
+		s.operand(expression.Rhs, expression.Operator, theSpan)
+		// Scratch has no block for it, only constants of it fold away
+		if expression.Operator == OperatorPow {
+			s.report(theSpan, "operator `%s` has no Scratch block", expression.Operator)
+		}
+		if expression.OutputType != nil {
+			return expression.OutputType
+		}
+		return operatorType(expression.Operator)
+	case *UnaryExpression:
+		s.operand(expression.Value, expression.Operator, theSpan)
+		if expression.OutputType != nil {
+			return expression.OutputType
+		}
+		return operatorType(expression.Operator)
+	case *CallExpression:
+		return s.call(expression, theSpan)
+	}
+	return nil
+}
+
+// operators work on single values
+func (s *verifier) operand(expression Expression, operator OperatorType, theSpan span.Span) {
+	size := sizeOf(s.expression(expression, theSpan))
+	if size != nil && *size != 1 {
+		s.report(theSpan, "operand `%s` of `%s` is not a single value", FormatExpression(expression), operator)
+	}
+}
+
+func operatorType(operator OperatorType) Type {
+	switch operator {
+	case OperatorEq, OperatorLt, OperatorGt, OperatorNe, OperatorLe, OperatorGe, OperatorAnd, OperatorOr, OperatorNot:
+		return &BooleanType{}
+	}
+	return &NumberType{}
+}
+
+func (s *verifier) call(call *CallExpression, theSpan span.Span) Type {
+	callee := call.Function
+	if callee == nil {
+		s.report(theSpan, "call to nothing")
+		return nil
+	}
+	if len(call.Arguments) != len(callee.Arguments) {
+		s.report(theSpan, "`%s` takes %s but is called with %d", callee.Name, span.Pluralize(uint(len(callee.Arguments)), "argument", "arguments"), len(call.Arguments))
+	}
+	for idx, argument := range call.Arguments {
+		argumentType := s.expression(argument, theSpan)
+		if idx >= len(callee.Arguments) {
+			continue
+		}
+		expected := callee.Arguments[idx]
+		if !SameType(expected.TypeView.Type, argumentType) {
+			s.report(theSpan, "argument `%s` of `%s` is `%s` but `%s` is given", expected.Name, callee.Name, FormatType(expected.TypeView.Type), FormatType(argumentType))
+		}
+	}
+	return callee.ReturnTypeView.Type
+}
+
+// unknown and untyped types match any type
+func SameType(lhs, rhs Type) bool {
+	if lhs == nil || rhs == nil || lhs.Type() == Untyped || rhs.Type() == Untyped {
+		return true
+	}
+	if lhs.Type() != rhs.Type() {
+		return false
+	}
+	switch lhs := lhs.(type) {
+	case *ArrayType:
+		rhs := rhs.(*ArrayType)
+		return lhs.N == rhs.N && SameType(lhs.Inner, rhs.Inner)
+	case *DynArrayType:
+		return SameType(lhs.Inner, rhs.(*DynArrayType).Inner)
+	case *StructType:
+		rhs := rhs.(*StructType)
+		if lhs.Size != rhs.Size || len(lhs.Fields) != len(rhs.Fields) {
+			return false
+		}
+		for name, field := range lhs.Fields {
+			other, ok := rhs.Fields[name]
+			if !ok || field.Offset != other.Offset || !SameType(field.Type, other.Type) {
+				return false
+			}
+		}
+	}
+	return true
+}
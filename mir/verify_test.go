@@ -0,0 +1,104 @@
+package mir_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/span"
+)
+
+// a problem as reported by the verifier, at the line and column it starts
+type problem struct {
+	line    uint
+	column  uint
+	message string
+}
+
+func (s problem) String() string {
+	return fmt.Sprintf("%d:%d %s", s.line, s.column, s.message)
+}
+
+// each source of testdata/verify breaks one rule of the verifier
+var verifyCases = map[string][]problem{
+	"slots.mir": {
+		{1, 12, "function `first`: argument `pair`: 1 slot for `[number; 2]` of size 2"},
+	},
+	"arity.mir": {
+		{7, 5, "function `main`: `double` takes 1 argument but is called with 2"},
+	},
+	"argument.mir": {
+		{7, 5, "function `main`: argument `value` of `double` is `number` but `string` is given"},
+	},
+	"return.mir": {
+		{4, 5, "function `name`: cannot return `string` as `number`"},
+	},
+	"stack.mir": {
+		{3, 5, "function `swap`: local `b` at @1 does not fit in a stack of 1"},
+	},
+	"dynarray.mir": {
+		{1, 1, "global `history`: `[number]` has no fixed size"},
+	},
+}
+
+func verify(t *testing.T, path string) ([]problem, error) {
+	t.Helper()
+	source, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := mir.Parse(path, string(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := make([]problem, 0)
+	previous := span.SetReporter(func(level span.ReportLevel, theSpan *span.Span, message string) {
+		if theSpan == nil {
+			t.Errorf("reported without a span: %s", message)
+			return
+		}
+		if theSpan.Path == nil || *theSpan.Path != path {
+			t.Errorf("reported in another source: %s", message)
+		}
+		problems = append(problems, problem{theSpan.From.Lineno + 1, theSpan.From.LineIndex + 1, message})
+	})
+	defer span.SetReporter(previous)
+	return problems, mir.Verify(&program)
+}
+
+func TestVerify(t *testing.T) {
+	paths, err := filepath.Glob("testdata/verify/*.mir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != len(verifyCases) {
+		t.Errorf("%d sources in testdata/verify for %d cases", len(paths), len(verifyCases))
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			expected, ok := verifyCases[filepath.Base(path)]
+			if !ok {
+				t.Fatal("no expected problems")
+			}
+			problems, err := verify(t, path)
+			if !slices.Equal(problems, expected) {
+				t.Errorf("reported %v, expected %v", problems, expected)
+			}
+			message := fmt.Sprintf("%s found by the mir verifier", span.Pluralize(uint(len(expected)), "problem", "problems"))
+			if err == nil || err.Error() != message {
+				t.Errorf("returned %v, expected %s", err, message)
+			}
+		})
+	}
+}
+
+// a source using every construct breaks none of them
+func TestVerifySyntax(t *testing.T) {
+	problems, err := verify(t, "testdata/syntax.mir")
+	if err != nil {
+		t.Errorf("%s: %v", err, problems)
+	}
+}
@@ -0,0 +1,48 @@
+package omitter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/span"
+)
+
+// annotates every generated statement with the source line it comes from
+func (s *Omitter) SetDebugComments(enabled bool) {
+	s.debugComments = enabled
+}
+
+// attaches the doc comment, and the source location in debug mode, to the
+// first block generated for a declaration or statement
+func (s *Omitter) OmitComment(blockUuid string, doc string, theSpan span.Span) {
+	lines := make([]string, 0)
+	if doc != "" {
+		lines = append(lines, doc)
+	}
+	if s.debugComments && theSpan.Path != nil {
+		lines = append(lines, fmt.Sprintf("%s:%d", filepath.Base(*theSpan.Path), theSpan.From.Lineno+1))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	s.scir.AttachComment(blockUuid, strings.Join(lines, "\n"))
+}
+
+// the doc comment and the span of a statement
+func statementSource(statement mir.Statement) (string, span.Span) {
+	switch statement := statement.(type) {
+	case *mir.AssignStatement:
+		return statement.Doc, statement.Span
+	case *mir.ReturnStatement:
+		return statement.Doc, statement.Span
+	case *mir.DeclareStatement:
+		return "", statement.Span
+	case *mir.LoopStatement:
+		return "", statement.Span
+	case *mir.IfStatement:
+		return "", statement.Span
+	}
+	return "", span.Span{}
+}
@@ -75,6 +75,12 @@ func (s *Omitter) OmitExpression(expression mir.Expression, blockUuids *[]string
 		return []string{exprUuid}, nil
 	case *mir.AcessorExpression:
 		exprUuids := make([]string, 0)
+		if variables, ok := s.frameVariables(expression.Acessor); ok {
+			for _, variable := range variables {
+				exprUuids = append(exprUuids, s.omitVariableBlock(variable.Name, variable.Uuid))
+			}
+			return exprUuids, nil
+		}
 		acessor, typeView, err := s.OmitAcessor(expression.Acessor, blockUuids)
 		if err != nil {
 			return nil, err
@@ -115,39 +121,23 @@ func (s *Omitter) OmitExpression(expression mir.Expression, blockUuids *[]string
 				},
 			}
 			substractBlockUuid := s.scir.InsertBlock(&substractBlock)
-			s.scir.SetInput(substractBlockUuid, "NUM1", s.scir.EditingTarget.Blocks[acessor[0]])
-			s.scir.SetInput(blockUuid, "INDEX", &substractBlock)
+			s.linkOperand(substractBlockUuid, "NUM1", acessor[0])
+			s.scir.LinkInput(blockUuid, "INDEX", substractBlockUuid)
 			exprUuids = append(exprUuids, blockUuid)
 		}
 		return exprUuids, nil
+	case *mir.BinaryExpression:
+		return s.OmitBinaryExpression(expression, blockUuids)
+	case *mir.UnaryExpression:
+		return s.OmitUnaryExpression(expression, blockUuids)
 	case *mir.CallExpression:
-		slots, err := s.OmitFunctionCall(expression, blockUuids)
-		if err != nil {
-			return nil, err
-		}
-		exprUuids := make([]string, 0)
-		for _, slot := range slots {
-			exprUuids = append(exprUuids, s.OmitVariable(slot))
-		}
-		return exprUuids, nil
+		return s.OmitFunctionCall(expression, blockUuids)
 	}
 	return nil, fmt.Errorf("not implemented yet")
 }
 
-func (s *Omitter) OmitVariable(slot mir.Slot) string {
-	return s.scir.InsertBlock(&scir.Block{
-		Opcode: "data_variable",
-		Inputs: make(map[string]scir.MaybeShadowedInput),
-		Fields: map[string]scir.Field{
-			"VARIABLE": {
-				Value: slot.Uuid,
-				Id:    &slot.Uuid,
-			},
-		},
-	})
-}
-
-func (s *Omitter) OmitFunctionCall(call *mir.CallExpression, blockUuids *[]string) ([]mir.Slot, error) {
+// returns the blocks reading the registers of the return value of the call
+func (s *Omitter) OmitFunctionCall(call *mir.CallExpression, blockUuids *[]string) ([]string, error) {
 	warpString := strconv.FormatBool(call.Function.Warp)
 	callBlock := scir.Block{
 		Opcode: "procedures_call",
@@ -175,11 +165,14 @@ func (s *Omitter) OmitFunctionCall(call *mir.CallExpression, blockUuids *[]strin
 			return nil, fmt.Errorf("type not fit")
 		}
 		for idx, slot := range argument.TypeView.Slots {
-			exprBlock := s.scir.EditingTarget.Blocks[exprUuids[idx]]
-			s.scir.SetInput(callBlockUuid, slot.Uuid, exprBlock)
+			s.scir.LinkInput(callBlockUuid, slot.Uuid, exprUuids[idx])
 		}
 		idx2 += 1
 	}
 	*blockUuids = append(*blockUuids, callBlockUuid)
-	return call.Function.ReturnTypeView.Slots, nil
+	exprUuids := make([]string, 0)
+	for _, register := range s.returnRegisters(call.Function) {
+		exprUuids = append(exprUuids, s.omitVariableBlock(register.Name, register.Uuid))
+	}
+	return exprUuids, nil
 }
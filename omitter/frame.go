@@ -0,0 +1,274 @@
+package omitter
+
+import (
+	"fmt"
+
+	"yummy-go.com/m/v2/idgen"
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/scir"
+)
+
+// a Scratch variable of the editing target, holding a cell of a global, a
+// cell of the frame of a function that is never called recursively or a
+// return register
+type targetVariable struct {
+	Name string
+	Uuid string
+}
+
+// whether the omitting function keeps its locals in variables
+func (s *Omitter) framesInVariables() bool {
+	if s.recursiveFunctions == nil || s.omittingFunction == nil {
+		return false
+	}
+	_, ok := s.recursiveFunctions[s.omittingFunction]
+	return !ok
+}
+
+func (s *Omitter) frameVariable(offset uint) targetVariable {
+	name := fmt.Sprintf("%s.local%d", s.omittingFunction.Name, offset)
+	return s.declareVariable(name)
+}
+
+// declares a variable of the editing target, its id is kept in the IdTable
+func (s *Omitter) declareVariable(name string) targetVariable {
+	key := s.scir.EditingTarget.Name + "." + name
+	variableUuid := idgen.Derive("variable", key)
+	if usage := s.scir.IdTable.LookupId(key); usage != nil {
+		variableUuid = usage.Uuid
+	}
+	if _, ok := s.scir.EditingTarget.Variables[variableUuid]; !ok {
+		s.scir.EditingTarget.Variables[variableUuid] = scir.Variable{
+			Name:  name,
+			Value: "",
+		}
+	}
+	s.scir.IdTable.UpdateId(key, scir.IdUsage{
+		For:  name,
+		Uuid: variableUuid,
+	})
+	s.scir.IdTable.TrackVariable(s.scir.EditingTarget.Name, variableUuid)
+	return targetVariable{
+		Name: name,
+		Uuid: variableUuid,
+	}
+}
+
+// the variables of each cell of a global, named after it
+func (s *Omitter) globalVariables(global *mir.GlobalDeclaration) ([]targetVariable, bool) {
+	size := global.TypeView.Type.GetSize()
+	if size == nil {
+		return nil, false
+	}
+	if *size == 1 {
+		return []targetVariable{s.declareVariable(global.Name)}, true
+	}
+	variables := make([]targetVariable, 0, *size)
+	for idx := range *size {
+		variables = append(variables, s.declareVariable(fmt.Sprintf("%s.%d", global.Name, idx)))
+	}
+	return variables, true
+}
+
+// the variables of each cell an acessor refers to, if it refers to a global
+// or to a local of a function that keeps its locals in variables. globals
+// never live on `_Stack`, even in recursive functions
+func (s *Omitter) frameVariables(acessor mir.Acessor) ([]targetVariable, bool) {
+	variableAcessor, ok := acessor.(*mir.VariableAcessor)
+	if !ok {
+		return nil, false
+	}
+	if global, ok := variableAcessor.Declaration.(*mir.GlobalDeclaration); ok {
+		return s.globalVariables(global)
+	}
+	if !s.framesInVariables() {
+		return nil, false
+	}
+	typeView := variableAcessor.Declaration.GetTypeView()
+	size := typeView.Type.GetSize()
+	if size == nil {
+		return nil, false
+	}
+	variables := make([]targetVariable, 0, *size)
+	for idx := range *size {
+		variables = append(variables, s.frameVariable(typeView.Offset+idx))
+	}
+	return variables, true
+}
+
+func (s *Omitter) omitVariableBlock(name, variableUuid string) string {
+	return s.scir.InsertBlock(&scir.Block{
+		Opcode: "data_variable",
+		Inputs: make(map[string]scir.MaybeShadowedInput),
+		Fields: map[string]scir.Field{
+			"VARIABLE": {
+				Value: name,
+				Id:    &variableUuid,
+			},
+		},
+	})
+}
+
+func (s *Omitter) omitSetVariableBlock(name, variableUuid, valueUuid string) string {
+	blockUuid := s.scir.InsertBlock(&scir.Block{
+		Opcode: "data_setvariableto",
+		Inputs: make(map[string]scir.MaybeShadowedInput),
+		Fields: map[string]scir.Field{
+			"VARIABLE": {
+				Value: name,
+				Id:    &variableUuid,
+			},
+		},
+	})
+	s.scir.LinkInput(blockUuid, "VALUE", valueUuid)
+	return blockUuid
+}
+
+// the name of the argument reporter of a slot of an argument
+func argumentName(argument mir.Argument, slot mir.Slot) string {
+	return fmt.Sprintf("(%s)%d", argument.Name, slot.Index)
+}
+
+// pushes the frame of the omitting function onto `_Stack`. the blocks are
+// the same whatever the size of the frame
+func (s *Omitter) OmitFramePush() []string {
+	if s.framesInVariables() || s.omittingFunction.StackSize == 0 {
+		return []string{}
+	}
+	pushUuid := s.scir.InsertBlock(&scir.Block{
+		Opcode: "data_addtolist",
+		Inputs: map[string]scir.MaybeShadowedInput{
+			"ITEM": {
+				Type: scir.Shadow,
+				ShadowedInput: &scir.StringInput{
+					Type:  scir.InputString,
+					Value: "",
+				},
+			},
+		},
+		Fields: map[string]scir.Field{
+			"LIST": {
+				Value: "_Stack",
+				Id:    &s.stackUuid,
+			},
+		},
+	})
+	return []string{s.omitRepeat(s.omittingFunction.StackSize, pushUuid)}
+}
+
+// pops the frame of the omitting function off `_Stack`
+func (s *Omitter) OmitFramePop() []string {
+	if s.framesInVariables() || s.omittingFunction.StackSize == 0 {
+		return []string{}
+	}
+	popUuid := s.scir.InsertBlock(&scir.Block{
+		Opcode: "data_deleteoflist",
+		Inputs: map[string]scir.MaybeShadowedInput{
+			"INDEX": {
+				Type: scir.Shadow,
+				ShadowedInput: &scir.StringInput{
+					Type:  scir.InputString,
+					Value: "last",
+				},
+			},
+		},
+		Fields: map[string]scir.Field{
+			"LIST": {
+				Value: "_Stack",
+				Id:    &s.stackUuid,
+			},
+		},
+	})
+	return []string{s.omitRepeat(s.omittingFunction.StackSize, popUuid)}
+}
+
+func (s *Omitter) omitRepeat(times uint, substackUuid string) string {
+	repeatUuid := s.scir.InsertBlock(&scir.Block{
+		Opcode: "control_repeat",
+		Inputs: map[string]scir.MaybeShadowedInput{
+			"TIMES": {
+				Type: scir.Shadow,
+				ShadowedInput: &scir.NumberalInput{
+					Type:  scir.InputPositiveInteger,
+					Value: float64(times),
+				},
+			},
+		},
+		Fields: make(map[string]scir.Field),
+	})
+	s.scir.LinkInput(repeatUuid, "SUBSTACK", substackUuid)
+	return repeatUuid
+}
+
+// copies the arguments of the omitting function into its frame, so they
+// are accessed as any other local
+func (s *Omitter) OmitArgumentsCopy() ([]string, error) {
+	blockUuids := make([]string, 0)
+	for _, argument := range s.omittingFunction.Arguments {
+		size := argument.TypeView.Type.GetSize()
+		if size == nil || *size != uint(len(argument.TypeView.Slots)) {
+			return nil, fmt.Errorf("argument `%s`: type not fit", argument.Name)
+		}
+		for idx, slot := range argument.TypeView.Slots {
+			reporterUuid := s.scir.InsertBlock(&scir.Block{
+				Opcode: "argument_reporter_string_number",
+				Inputs: make(map[string]scir.MaybeShadowedInput),
+				Fields: map[string]scir.Field{
+					"VALUE": {
+						Value: argumentName(argument, slot),
+					},
+				},
+			})
+			blockUuids = append(blockUuids, s.omitStoreCell(argument.TypeView.Offset+uint(idx), reporterUuid))
+		}
+	}
+	for i := 0; i < len(blockUuids)-1; i += 1 {
+		s.scir.ConnectBlocks(blockUuids[i], blockUuids[i+1])
+	}
+	return blockUuids, nil
+}
+
+// stores a value into a cell of the frame of the omitting function
+func (s *Omitter) omitStoreCell(cell uint, valueUuid string) string {
+	if s.framesInVariables() {
+		variable := s.frameVariable(cell)
+		return s.omitSetVariableBlock(variable.Name, variable.Uuid, valueUuid)
+	}
+	blockUuid := s.scir.InsertBlock(&scir.Block{
+		Opcode: "data_replaceitemoflist",
+		Inputs: make(map[string]scir.MaybeShadowedInput),
+		Fields: map[string]scir.Field{
+			"LIST": {
+				Value: "_Stack",
+				Id:    &s.stackUuid,
+			},
+		},
+	})
+	lengthUuid := s.scir.InsertBlock(&scir.Block{
+		Opcode: "data_lengthoflist",
+		Inputs: make(map[string]scir.MaybeShadowedInput),
+		Fields: map[string]scir.Field{
+			"LIST": {
+				Value: "_Stack",
+				Id:    &s.stackUuid,
+			},
+		},
+	})
+	indexUuid := s.scir.InsertBlock(&scir.Block{
+		Opcode: "operator_subtract",
+		Fields: make(map[string]scir.Field),
+		Inputs: map[string]scir.MaybeShadowedInput{
+			"NUM2": {
+				Type: scir.Shadow,
+				ShadowedInput: &scir.NumberalInput{
+					Type:  scir.InputNumber,
+					Value: float64(cell),
+				},
+			},
+		},
+	})
+	s.linkOperand(indexUuid, "NUM1", lengthUuid)
+	s.scir.LinkInput(blockUuid, "INDEX", indexUuid)
+	s.scir.LinkInput(blockUuid, "ITEM", valueUuid)
+	return blockUuid
+}
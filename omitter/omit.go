@@ -5,35 +5,45 @@ import (
 	"fmt"
 	"strconv"
 
-	"github.com/google/uuid"
+	"yummy-go.com/m/v2/idgen"
 	"yummy-go.com/m/v2/mir"
 	"yummy-go.com/m/v2/scir"
 )
 
-const OmitMaxStackSize uint = 20
-
 type Omitter struct {
 	scir             *scir.Scir
 	stackUuid        string
 	omittingFunction *mir.FunctionDeclaration
+	debugComments    bool
+	// nil until the call graph of a program is known, every function keeps
+	// its frame on `_Stack` then
+	recursiveFunctions map[*mir.FunctionDeclaration]struct{}
+	registers          map[*mir.FunctionDeclaration][]targetVariable
+	// the return statement ending the body of the omitting function, if any
+	finalReturn mir.Statement
 }
 
 func New(ctx *scir.Scir) Omitter {
-	var stackUuid string
-	theStackUuid := ctx.IdTable.LookupId("_Stack")
-	if theStackUuid == nil {
-		stackUuid = uuid.NewString()
+	stackUuid := idgen.Derive("list", "_Stack")
+	if usage := ctx.IdTable.LookupId("_Stack"); usage != nil {
+		stackUuid = usage.Uuid
+	}
+	if _, ok := ctx.StageTarget.Lists[stackUuid]; !ok {
 		ctx.StageTarget.Lists[stackUuid] = scir.List{
 			Name:  "_Stack",
 			Value: make([]string, 0),
 		}
-	} else {
-		stackUuid = theStackUuid.Uuid
 	}
+	ctx.IdTable.UpdateId("_Stack", scir.IdUsage{
+		For:  "_Stack",
+		Uuid: stackUuid,
+	})
+	ctx.IdTable.TrackList(ctx.StageTarget.Name, stackUuid)
 	return Omitter{
 		scir:             ctx,
 		stackUuid:        stackUuid,
 		omittingFunction: nil,
+		registers:        make(map[*mir.FunctionDeclaration][]targetVariable),
 	}
 }
 
@@ -41,18 +51,35 @@ func (s *Omitter) SetTarget(name string) {
 	s.scir.SetEditingTarget(name)
 }
 
-func (s *Omitter) Omit(mir mir.Program) error {
-	for _, declaration := range mir.Declarations {
+func (s *Omitter) Omit(program mir.Program) error {
+	mir.EliminateTailCalls(&program)
+	spillCalls(&program)
+	if err := mir.AllocateFrames(&program); err != nil {
+		return err
+	}
+	if err := mir.Verify(&program); err != nil {
+		return err
+	}
+	s.recursiveFunctions = mir.RecursiveFunctions(&program)
+	s.allocateRegisters(&program)
+	for _, declaration := range program.Declarations {
 		if err := s.OmitDeclaration(declaration); err != nil {
 			return err
 		}
 	}
+	if err := s.scir.CollectGarbage(); err != nil {
+		return err
+	}
+	s.scir.LayoutScripts()
 	return nil
 }
 
 func (s *Omitter) OmitDeclaration(declaration mir.Declaration) error {
 	switch declaration := declaration.(type) {
 	case *mir.GlobalDeclaration:
+		if _, ok := s.globalVariables(declaration); !ok {
+			return fmt.Errorf("global `%s`: cannot declare a dyn-sized global", declaration.Name)
+		}
 	case *mir.FunctionDeclaration:
 		return s.OmitFunction(declaration)
 	}
@@ -61,6 +88,15 @@ func (s *Omitter) OmitDeclaration(declaration mir.Declaration) error {
 
 func (s *Omitter) OmitFunction(function *mir.FunctionDeclaration) error {
 	s.omittingFunction = function
+	s.finalReturn = nil
+	if statements := function.Body.Statements; len(statements) > 0 {
+		if _, ok := statements[len(statements)-1].(*mir.ReturnStatement); ok {
+			s.finalReturn = statements[len(statements)-1]
+		}
+	}
+	s.scir.SetIdScope(function.Name)
+	s.scir.SetSourceSpan(&function.Span)
+	defer s.scir.SetSourceSpan(nil)
 	procedureHead := scir.Block{
 		Opcode:   "procedures_definition",
 		Fields:   make(map[string]scir.Field),
@@ -69,6 +105,7 @@ func (s *Omitter) OmitFunction(function *mir.FunctionDeclaration) error {
 		TopLevel: true,
 	}
 	procedureHeadUuid := s.scir.InsertBlock(&procedureHead)
+	s.OmitComment(procedureHeadUuid, function.Doc, function.Span)
 	warpString := strconv.FormatBool(function.Warp)
 	procedurePrototype := scir.Block{
 		Opcode:   "procedures_prototype",
@@ -84,25 +121,24 @@ func (s *Omitter) OmitFunction(function *mir.FunctionDeclaration) error {
 			Warp:        &warpString,
 		},
 	}
-	var procedurePrototypeUuid string
-	usage := s.scir.IdTable.LookupId(function.ProcCode)
-	if usage == nil {
-		procedurePrototypeUuid = s.scir.InsertBlock(&procedurePrototype)
-	} else {
-		s.scir.InsertBlockWithUuid(usage.Uuid, &procedurePrototype)
+	// an id is drawn even when the previous one is kept, so the ids of the
+	// following blocks match the ones of the previous build
+	procedurePrototypeUuid := s.scir.NewBlockId()
+	if usage := s.scir.IdTable.LookupId(function.ProcCode); usage != nil {
 		procedurePrototypeUuid = usage.Uuid
 	}
+	s.scir.InsertBlockWithUuid(procedurePrototypeUuid, &procedurePrototype)
 	s.scir.IdTable.UpdateId(function.ProcCode, scir.IdUsage{
 		For:            function.Name,
 		Uuid:           procedurePrototypeUuid,
 		RawDeclaration: function.Span.String(),
 	})
-	s.scir.SetShadowInput(procedureHeadUuid, "custom_block", &procedurePrototype)
+	s.scir.LinkShadowInput(procedureHeadUuid, "custom_block", procedurePrototypeUuid)
 	argumentNames := make([]string, 0)
 	argumentDefaults := make([]string, 0)
 	for _, argumentDeclaration := range function.Arguments {
 		for _, slot := range argumentDeclaration.TypeView.Slots {
-			argName := fmt.Sprintf("(%s)%d", argumentDeclaration.Name, slot.Index)
+			argName := argumentName(argumentDeclaration, slot)
 			argument := scir.Block{
 				Opcode: "argument_reporter_string_number",
 				Inputs: make(map[string]scir.MaybeShadowedInput),
@@ -114,12 +150,8 @@ func (s *Omitter) OmitFunction(function *mir.FunctionDeclaration) error {
 				Shadow:   true,
 				TopLevel: false,
 			}
-			s.scir.InsertBlock(&argument)
-			s.scir.SetShadowInput(
-				procedurePrototypeUuid,
-				slot.Uuid,
-				&argument,
-			)
+			argumentUuid := s.scir.InsertBlock(&argument)
+			s.scir.LinkShadowInput(procedurePrototypeUuid, slot.Uuid, argumentUuid)
 			argumentNames = append(argumentNames, argName)
 			argumentDefaults = append(argumentDefaults, "")
 		}
@@ -130,43 +162,28 @@ func (s *Omitter) OmitFunction(function *mir.FunctionDeclaration) error {
 	argumentDefaultsString := string(argumentDefaultsBytes)
 	procedurePrototype.Mutation.ArgumentNames = &argumentNamesString
 	procedurePrototype.Mutation.ArgumentDefaults = &argumentDefaultsString
-	bodyUuids, err := s.OmitBlock(function.Body)
+	// the body runs after the frame is pushed and the arguments are copied
+	// into it, the cleanup pops the frame
+	pushUuids := s.OmitFramePush()
+	argumentUuids, err := s.OmitArgumentsCopy()
 	if err != nil {
 		return err
 	}
-	bodyStartUuid := procedureHeadUuid
-	if s.omittingFunction.StackSize > OmitMaxStackSize {
-		return fmt.Errorf("reaches OmitMaxStackSize")
-	}
-	for range function.StackSize {
-		blockUuid := s.scir.InsertBlock(&scir.Block{
-			Opcode: "data_addtolist",
-			Inputs: map[string]scir.MaybeShadowedInput{
-				"ITEM": {
-					Type: scir.Shadow,
-					ShadowedInput: &scir.StringInput{
-						Type:  scir.InputString,
-						Value: "",
-					},
-				},
-			},
-			Fields: map[string]scir.Field{
-				"LIST": {
-					Value: "_Stack",
-					Id:    &s.stackUuid,
-				},
-			},
-		})
-		s.scir.ConnectBlocks(bodyStartUuid, blockUuid)
-		bodyStartUuid = blockUuid
+	bodyUuids, err := s.OmitBlock(function.Body)
+	if err != nil {
+		return err
 	}
-	if len(bodyUuids) > 0 {
-		s.scir.ConnectBlocks(bodyStartUuid, bodyUuids[0])
-		bodyStartUuid = bodyUuids[len(bodyUuids)-1]
+	cleanupUuids, err := s.OmitFunctionCleanup()
+	if err != nil {
+		return err
 	}
-	blockUuids, _ := s.OmitFunctionCleanup()
-	if len(blockUuids) > 0 {
+	bodyStartUuid := procedureHeadUuid
+	for _, blockUuids := range [][]string{pushUuids, argumentUuids, bodyUuids, cleanupUuids} {
+		if len(blockUuids) == 0 {
+			continue
+		}
 		s.scir.ConnectBlocks(bodyStartUuid, blockUuids[0])
+		bodyStartUuid = blockUuids[len(blockUuids)-1]
 	}
 	s.omittingFunction = nil
 	return nil
@@ -176,45 +193,31 @@ func (s *Omitter) OmitFunctionCleanup() ([]string, error) {
 	if s.omittingFunction == nil {
 		return []string{}, fmt.Errorf("cannot omit cleanup blocks outside a function")
 	}
-	blockUuids := make([]string, 0)
-	if s.omittingFunction.StackSize > OmitMaxStackSize {
-		return []string{}, fmt.Errorf("reaches OmitMaxStackSize")
-	}
-	for range s.omittingFunction.StackSize {
-		blockUuid := s.scir.InsertBlock(&scir.Block{
-			Opcode: "data_deleteoflist",
-			Inputs: map[string]scir.MaybeShadowedInput{
-				"INDEX": {
-					Type: scir.Shadow,
-					ShadowedInput: &scir.StringInput{
-						Type:  scir.InputString,
-						Value: "last",
-					},
-				},
-			},
-			Fields: map[string]scir.Field{
-				"LIST": {
-					Value: "_Stack",
-					Id:    &s.stackUuid,
-				},
-			},
-		})
-		blockUuids = append(blockUuids, blockUuid)
-	}
-	for i := 0; i < len(blockUuids)-1; i += 1 {
-		s.scir.ConnectBlocks(blockUuids[i], blockUuids[i+1])
-	}
-	return blockUuids, nil
+	return s.OmitFramePop(), nil
 }
 
 func (s *Omitter) OmitBlock(block mir.Block) ([]string, error) {
 	blockUuids := make([]string, 0)
 	for _, statement := range block.Statements {
+		doc, theSpan := statementSource(statement)
+		enclosingSpan := s.scir.SourceSpan()
+		if theSpan.Path != nil {
+			s.scir.SetSourceSpan(&theSpan)
+		}
 		statementUuids, err := s.OmitStatement(statement)
+		s.scir.SetSourceSpan(enclosingSpan)
 		if err != nil {
 			return nil, err
 		}
+		if len(statementUuids) > 0 {
+			s.OmitComment(statementUuids[0], doc, theSpan)
+		}
 		blockUuids = append(blockUuids, statementUuids...)
+		// a return ends with `stop this script` unless it is the final one,
+		// nothing may follow it and the statements after it never run
+		if _, ok := statement.(*mir.ReturnStatement); ok {
+			break
+		}
 	}
 	for i := 0; i < len(blockUuids)-1; i += 1 {
 		s.scir.ConnectBlocks(blockUuids[i], blockUuids[i+1])
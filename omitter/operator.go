@@ -0,0 +1,153 @@
+package omitter
+
+import (
+	"fmt"
+
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/scir"
+)
+
+// the block of each operator and the names of its operands
+type operatorBlock struct {
+	opcode string
+	lhs    string
+	rhs    string
+	// the result of the block is negated, Scratch has no `!=`, `<=` nor `>=`
+	negated bool
+}
+
+var operatorBlocks = map[mir.OperatorType]operatorBlock{
+	mir.OperatorAdd: {opcode: "operator_add", lhs: "NUM1", rhs: "NUM2"},
+	mir.OperatorSub: {opcode: "operator_subtract", lhs: "NUM1", rhs: "NUM2"},
+	mir.OperatorMul: {opcode: "operator_multiply", lhs: "NUM1", rhs: "NUM2"},
+	mir.OperatorDiv: {opcode: "operator_divide", lhs: "NUM1", rhs: "NUM2"},
+	mir.OperatorEq:  {opcode: "operator_equals", lhs: "OPERAND1", rhs: "OPERAND2"},
+	mir.OperatorLt:  {opcode: "operator_lt", lhs: "OPERAND1", rhs: "OPERAND2"},
+	mir.OperatorGt:  {opcode: "operator_gt", lhs: "OPERAND1", rhs: "OPERAND2"},
+	mir.OperatorNe:  {opcode: "operator_equals", lhs: "OPERAND1", rhs: "OPERAND2", negated: true},
+	mir.OperatorLe:  {opcode: "operator_gt", lhs: "OPERAND1", rhs: "OPERAND2", negated: true},
+	mir.OperatorGe:  {opcode: "operator_lt", lhs: "OPERAND1", rhs: "OPERAND2", negated: true},
+	mir.OperatorAnd: {opcode: "operator_and", lhs: "OPERAND1", rhs: "OPERAND2"},
+	mir.OperatorOr:  {opcode: "operator_or", lhs: "OPERAND1", rhs: "OPERAND2"},
+}
+
+// `+` of strings joins them
+var joinBlock = operatorBlock{opcode: "operator_join", lhs: "STRING1", rhs: "STRING2"}
+
+func (s *Omitter) omitOperand(expression mir.Expression, operator mir.OperatorType, blockUuids *[]string) (string, error) {
+	exprUuids, err := s.OmitExpression(expression, blockUuids)
+	if err != nil {
+		return "", err
+	}
+	if len(exprUuids) != 1 {
+		return "", fmt.Errorf("operator `%s`: operands of %d cells are not supported", operator, len(exprUuids))
+	}
+	return exprUuids[0], nil
+}
+
+// the inputs of each operator block, in the order they are linked
+var operatorInputs = map[string][]string{
+	"operator_add":      {"NUM1", "NUM2"},
+	"operator_subtract": {"NUM1", "NUM2"},
+	"operator_multiply": {"NUM1", "NUM2"},
+	"operator_divide":   {"NUM1", "NUM2"},
+	"operator_join":     {"STRING1", "STRING2"},
+	"operator_equals":   {"OPERAND1", "OPERAND2"},
+	"operator_lt":       {"OPERAND1", "OPERAND2"},
+	"operator_gt":       {"OPERAND1", "OPERAND2"},
+	"operator_and":      {"OPERAND1", "OPERAND2"},
+	"operator_or":       {"OPERAND1", "OPERAND2"},
+	"operator_not":      {"OPERAND"},
+}
+
+// the shadow the editor puts under an input of an operator block, the
+// inputs taking a boolean have none
+func operandShadow(opcode, name string) scir.Input {
+	switch {
+	case name == "NUM1" || name == "NUM2":
+		return &scir.NumberalInput{Type: scir.InputNumber, Value: 0}
+	case name == "STRING1" || name == "STRING2":
+		return &scir.StringInput{Type: scir.InputString, Value: ""}
+	case opcode == "operator_equals" || opcode == "operator_lt" || opcode == "operator_gt":
+		return &scir.StringInput{Type: scir.InputString, Value: ""}
+	}
+	return nil
+}
+
+// links a block into an input of an operator block, over the shadow of
+// the input. an input left without a block shows its shadow alone
+func (s *Omitter) linkOperand(blockUuid, name, inputUuid string) {
+	shadow := operandShadow(s.scir.EditingTarget.Blocks[blockUuid].Opcode, name)
+	if inputUuid == "" {
+		if shadow != nil {
+			s.scir.EditingTarget.Blocks[blockUuid].Inputs[name] = scir.MaybeShadowedInput{
+				Type:          scir.Shadow,
+				ShadowedInput: shadow,
+			}
+		}
+		return
+	}
+	s.scir.LinkInput(blockUuid, name, inputUuid)
+	if shadow == nil {
+		return
+	}
+	input := s.scir.EditingTarget.Blocks[blockUuid].Inputs[name]
+	input.Type = scir.Shadowed
+	input.ShadowedInput = shadow
+	s.scir.EditingTarget.Blocks[blockUuid].Inputs[name] = input
+}
+
+func (s *Omitter) omitOperatorBlock(opcode string, inputs map[string]string) string {
+	blockUuid := s.scir.InsertBlock(&scir.Block{
+		Opcode: opcode,
+		Inputs: make(map[string]scir.MaybeShadowedInput),
+		Fields: make(map[string]scir.Field),
+	})
+	for _, name := range operatorInputs[opcode] {
+		s.linkOperand(blockUuid, name, inputs[name])
+	}
+	return blockUuid
+}
+
+func (s *Omitter) OmitBinaryExpression(expression *mir.BinaryExpression, blockUuids *[]string) ([]string, error) {
+	theBlock, ok := operatorBlocks[expression.Operator]
+	if !ok {
+		return nil, fmt.Errorf("operator `%s`: not implemented yet", expression.Operator)
+	}
+	if _, ok := expression.OutputType.(*mir.StringType); ok && expression.Operator == mir.OperatorAdd {
+		theBlock = joinBlock
+	}
+	lhsUuid, err := s.omitOperand(expression.Lhs, expression.Operator, blockUuids)
+	if err != nil {
+		return nil, err
+	}
+	rhsUuid, err := s.omitOperand(expression.Rhs, expression.Operator, blockUuids)
+	if err != nil {
+		return nil, err
+	}
+	blockUuid := s.omitOperatorBlock(theBlock.opcode, map[string]string{
+		theBlock.lhs: lhsUuid,
+		theBlock.rhs: rhsUuid,
+	})
+	if theBlock.negated {
+		blockUuid = s.omitOperatorBlock("operator_not", map[string]string{"OPERAND": blockUuid})
+	}
+	return []string{blockUuid}, nil
+}
+
+func (s *Omitter) OmitUnaryExpression(expression *mir.UnaryExpression, blockUuids *[]string) ([]string, error) {
+	valueUuid, err := s.omitOperand(expression.Value, expression.Operator, blockUuids)
+	if err != nil {
+		return nil, err
+	}
+	switch expression.Operator {
+	case mir.OperatorNot:
+		return []string{s.omitOperatorBlock("operator_not", map[string]string{"OPERAND": valueUuid})}, nil
+	case mir.OperatorSub:
+		// `0 - value`, NUM1 keeps its shadow alone
+		return []string{s.omitOperatorBlock("operator_subtract", map[string]string{"NUM2": valueUuid})}, nil
+	case mir.OperatorAdd:
+		return []string{s.omitOperatorBlock("operator_add", map[string]string{"NUM1": valueUuid})}, nil
+	}
+	return nil, fmt.Errorf("unary operator `%s`: not implemented yet", expression.Operator)
+}
@@ -0,0 +1,204 @@
+package omitter
+
+import (
+	"fmt"
+
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/span"
+)
+
+// return values are passed through registers: variables written by the
+// return statements of a function and read by its callers right after the
+// call. functions whose return values are never pending at the same time
+// share their registers, a shared register is named after the first
+// function using it
+func (s *Omitter) allocateRegisters(program *mir.Program) {
+	functions := make([]*mir.FunctionDeclaration, 0)
+	for _, declaration := range program.Declarations {
+		if function, ok := declaration.(*mir.FunctionDeclaration); ok {
+			functions = append(functions, function)
+		}
+	}
+	conflicts := registerConflicts(program)
+	colors := make(map[*mir.FunctionDeclaration]int)
+	// the names of the registers of each color, by slot index
+	names := make([][]string, 0)
+	for _, function := range functions {
+		color := 0
+		for ; color < len(names); color += 1 {
+			used := false
+			for other := range conflicts[function] {
+				if otherColor, ok := colors[other]; ok && otherColor == color {
+					used = true
+					break
+				}
+			}
+			if !used {
+				break
+			}
+		}
+		if color == len(names) {
+			names = append(names, make([]string, 0))
+		}
+		colors[function] = color
+		for idx := len(names[color]); idx < len(function.ReturnTypeView.Slots); idx += 1 {
+			names[color] = append(names[color], registerName(function, idx))
+		}
+		registers := make([]targetVariable, 0, len(function.ReturnTypeView.Slots))
+		for idx := range function.ReturnTypeView.Slots {
+			registers = append(registers, s.declareVariable(names[color][idx]))
+		}
+		s.registers[function] = registers
+	}
+}
+
+// the names of the variables the return value of an omitted function is
+// left in, by slot
+func (s *Omitter) ReturnVariables(function *mir.FunctionDeclaration) []string {
+	names := make([]string, 0, len(s.registers[function]))
+	for _, register := range s.registers[function] {
+		names = append(names, register.Name)
+	}
+	return names
+}
+
+func registerName(function *mir.FunctionDeclaration, idx int) string {
+	return fmt.Sprintf("%s.ret%d", function.Name, idx)
+}
+
+// two functions conflict when one may run while the return value of the
+// other is still to be read
+func registerConflicts(program *mir.Program) map[*mir.FunctionDeclaration]map[*mir.FunctionDeclaration]struct{} {
+	graph := mir.CallGraph(program)
+	conflicts := make(map[*mir.FunctionDeclaration]map[*mir.FunctionDeclaration]struct{})
+	conflict := func(lhs, rhs *mir.FunctionDeclaration) {
+		if lhs == rhs {
+			return
+		}
+		for _, pair := range [][2]*mir.FunctionDeclaration{{lhs, rhs}, {rhs, lhs}} {
+			if conflicts[pair[0]] == nil {
+				conflicts[pair[0]] = make(map[*mir.FunctionDeclaration]struct{})
+			}
+			conflicts[pair[0]][pair[1]] = struct{}{}
+		}
+	}
+	for function := range graph {
+		mir.WalkStatements(function.Body, func(statement mir.Statement) {
+			calls := mir.StatementCalls(statement)
+			for idx, call := range calls {
+				// the value of a call is read at the latest by the statement,
+				// after every later call of the statement
+				for _, later := range calls[idx+1:] {
+					for callee := range mir.Callees(graph, later.Function) {
+						conflict(call.Function, callee)
+					}
+				}
+				// a return statement reads the values of its calls while
+				// writing the registers of the function
+				if _, ok := statement.(*mir.ReturnStatement); ok {
+					conflict(call.Function, function)
+				}
+			}
+		})
+	}
+	return conflicts
+}
+
+// the registers of the return value of a function
+func (s *Omitter) returnRegisters(function *mir.FunctionDeclaration) []targetVariable {
+	if registers, ok := s.registers[function]; ok {
+		return registers
+	}
+	// a function omitted on its own shares nothing
+	registers := make([]targetVariable, 0, len(function.ReturnTypeView.Slots))
+	for idx := range function.ReturnTypeView.Slots {
+		registers = append(registers, s.declareVariable(registerName(function, idx)))
+	}
+	s.registers[function] = registers
+	return registers
+}
+
+// a register holds the value of a call only until the function runs again,
+// coloring cannot keep two values of the same function apart. so in a
+// statement where a call runs again before the value of an earlier call is
+// read, as in `square(3) + square(4)`, every call is moved into a local
+// assigned just before the statement, in the order the calls run
+func spillCalls(program *mir.Program) {
+	graph := mir.CallGraph(program)
+	for _, declaration := range program.Declarations {
+		if function, ok := declaration.(*mir.FunctionDeclaration); ok {
+			spillBlockCalls(graph, &function.Body)
+		}
+	}
+}
+
+func spillBlockCalls(graph map[*mir.FunctionDeclaration][]*mir.FunctionDeclaration, block *mir.Block) {
+	statements := make([]mir.Statement, 0, len(block.Statements))
+	for _, statement := range block.Statements {
+		switch statement := statement.(type) {
+		case *mir.LoopStatement:
+			spillBlockCalls(graph, &statement.Body)
+		case *mir.IfStatement:
+			if overwritesCalls(graph, statement) {
+				statement.Condition = spillExpressionCalls(statement.Condition, statement.Span, &statements)
+			}
+			spillBlockCalls(graph, &statement.Then)
+			spillBlockCalls(graph, &statement.Else)
+		case *mir.AssignStatement:
+			if overwritesCalls(graph, statement) {
+				statement.Value = spillExpressionCalls(statement.Value, statement.Span, &statements)
+			}
+		case *mir.ReturnStatement:
+			if overwritesCalls(graph, statement) {
+				statement.Value = spillExpressionCalls(statement.Value, statement.Span, &statements)
+			}
+		}
+		statements = append(statements, statement)
+	}
+	block.Statements = statements
+}
+
+// whether a call of the statement may run during a later call of it
+func overwritesCalls(graph map[*mir.FunctionDeclaration][]*mir.FunctionDeclaration, statement mir.Statement) bool {
+	calls := mir.StatementCalls(statement)
+	for idx, call := range calls {
+		for _, later := range calls[idx+1:] {
+			if _, ok := mir.Callees(graph, later.Function)[call.Function]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// replaces the calls by reads of the locals they are assigned to, the
+// arguments of a call are spilled before it
+func spillExpressionCalls(expression mir.Expression, theSpan span.Span, statements *[]mir.Statement) mir.Expression {
+	switch expression := expression.(type) {
+	case *mir.BinaryExpression:
+		expression.Lhs = spillExpressionCalls(expression.Lhs, theSpan, statements)
+		expression.Rhs = spillExpressionCalls(expression.Rhs, theSpan, statements)
+	case *mir.UnaryExpression:
+		expression.Value = spillExpressionCalls(expression.Value, theSpan, statements)
+	case *mir.CallExpression:
+		for idx, argument := range expression.Arguments {
+			expression.Arguments[idx] = spillExpressionCalls(argument, theSpan, statements)
+		}
+		local := &mir.DeclareStatement{
+			Name: fmt.Sprintf("(call)%s", expression.Function.Name),
+			TypeView: mir.TypeView{
+				Type: expression.Function.ReturnTypeView.Type,
+			},
+			Span: theSpan,
+		}
+		*statements = append(*statements, local, &mir.AssignStatement{
+			Acessor: &mir.VariableAcessor{Declaration: local, Span: theSpan},
+			Value:   expression,
+			Span:    theSpan,
+		})
+		return &mir.AcessorExpression{
+			Acessor: &mir.VariableAcessor{Declaration: local, Span: theSpan},
+		}
+	}
+	return expression
+}
@@ -17,6 +17,16 @@ func (s *Omitter) OmitStatement(statement mir.Statement) ([]string, error) {
 		if err != nil {
 			return nil, err
 		}
+		if variables, ok := s.frameVariables(statement.Acessor); ok {
+			if len(exprUuids) != len(variables) {
+				return nil, fmt.Errorf("type not fit")
+			}
+			for idx, variable := range variables {
+				blockUuid := s.omitSetVariableBlock(variable.Name, variable.Uuid, exprUuids[idx])
+				blockUuids = append(blockUuids, blockUuid)
+			}
+			return blockUuids, nil
+		}
 		acessor, typeView, err := s.OmitAcessor(statement.Acessor, &blockUuids)
 		if err != nil {
 			return nil, err
@@ -60,9 +70,9 @@ func (s *Omitter) OmitStatement(statement mir.Statement) ([]string, error) {
 				},
 			}
 			substractBlockUuid := s.scir.InsertBlock(&substractBlock)
-			s.scir.SetInput(substractBlockUuid, "NUM1", s.scir.EditingTarget.Blocks[acessor[0]])
-			s.scir.SetInput(blockUuid, "INDEX", &substractBlock)
-			s.scir.SetInput(blockUuid, "ITEM", s.scir.EditingTarget.Blocks[exprUuid])
+			s.scir.LinkInput(substractBlockUuid, "NUM1", acessor[0])
+			s.scir.LinkInput(blockUuid, "INDEX", substractBlockUuid)
+			s.scir.LinkInput(blockUuid, "ITEM", exprUuid)
 			blockUuids = append(blockUuids, blockUuid)
 		}
 		return blockUuids, nil
@@ -72,30 +82,98 @@ func (s *Omitter) OmitStatement(statement mir.Statement) ([]string, error) {
 		if err != nil {
 			return nil, err
 		}
-		slots := s.omittingFunction.ReturnTypeView.Slots
-		if len(slots) != len(exprUuids) {
+		registers := s.returnRegisters(s.omittingFunction)
+		if len(registers) != len(exprUuids) {
 			return nil, fmt.Errorf("type not fit")
 		}
 		for idx, exprUuid := range exprUuids {
-			block := scir.Block{
-				Opcode: "data_setvariableto",
-				Inputs: make(map[string]scir.MaybeShadowedInput),
-				Fields: map[string]scir.Field{
-					"VARIABLE": {
-						Value: slots[idx].Uuid,
-						Id:    &slots[idx].Uuid,
-					},
-				},
-			}
-			blockUuid := s.scir.InsertBlock(&block)
-			s.scir.SetInput(blockUuid, "VALUE", s.scir.EditingTarget.Blocks[exprUuid])
+			blockUuid := s.omitSetVariableBlock(registers[idx].Name, registers[idx].Uuid, exprUuid)
 			blockUuids = append(blockUuids, blockUuid)
 		}
+		if statement == s.finalReturn {
+			// the cleanup follows the body anyway
+			return blockUuids, nil
+		}
+		cleanupUuids, err := s.OmitFunctionCleanup()
+		if err != nil {
+			return nil, err
+		}
+		blockUuids = append(blockUuids, cleanupUuids...)
+		blockUuids = append(blockUuids, s.omitStopThisScript())
 		return blockUuids, nil
+	case *mir.LoopStatement:
+		bodyUuids, err := s.OmitBlock(statement.Body)
+		if err != nil {
+			return nil, err
+		}
+		// the condition is left empty so it never holds, the loop is left
+		// by the `stop this script` of a return
+		loopUuid := s.scir.InsertBlock(&scir.Block{
+			Opcode: "control_repeat_until",
+			Inputs: make(map[string]scir.MaybeShadowedInput),
+			Fields: make(map[string]scir.Field),
+		})
+		if len(bodyUuids) > 0 {
+			s.scir.LinkInput(loopUuid, "SUBSTACK", bodyUuids[0])
+		}
+		return []string{loopUuid}, nil
+	case *mir.IfStatement:
+		blockUuids := make([]string, 0)
+		conditionUuids, err := s.OmitExpression(statement.Condition, &blockUuids)
+		if err != nil {
+			return nil, err
+		}
+		if len(conditionUuids) != 1 {
+			return nil, fmt.Errorf("type not fit")
+		}
+		thenUuids, err := s.OmitBlock(statement.Then)
+		if err != nil {
+			return nil, err
+		}
+		elseUuids, err := s.OmitBlock(statement.Else)
+		if err != nil {
+			return nil, err
+		}
+		opcode := "control_if"
+		if len(elseUuids) > 0 {
+			opcode = "control_if_else"
+		}
+		ifUuid := s.scir.InsertBlock(&scir.Block{
+			Opcode: opcode,
+			Inputs: make(map[string]scir.MaybeShadowedInput),
+			Fields: make(map[string]scir.Field),
+		})
+		s.scir.LinkInput(ifUuid, "CONDITION", conditionUuids[0])
+		if len(thenUuids) > 0 {
+			s.scir.LinkInput(ifUuid, "SUBSTACK", thenUuids[0])
+		}
+		if len(elseUuids) > 0 {
+			s.scir.LinkInput(ifUuid, "SUBSTACK2", elseUuids[0])
+		}
+		return append(blockUuids, ifUuid), nil
 	}
 	return nil, fmt.Errorf("not implemented yet")
 }
 
+// in a procedure, `stop this script` returns from the procedure
+func (s *Omitter) omitStopThisScript() string {
+	hasNext := "false"
+	return s.scir.InsertBlock(&scir.Block{
+		Opcode: "control_stop",
+		Inputs: make(map[string]scir.MaybeShadowedInput),
+		Fields: map[string]scir.Field{
+			"STOP_OPTION": {
+				Value: "this script",
+			},
+		},
+		Mutation: &scir.Mutation{
+			TagName:  "mutation",
+			Children: make([]any, 0),
+			HasNext:  &hasNext,
+		},
+	})
+}
+
 func (s *Omitter) OmitAcessor(acessor mir.Acessor, blockUuids *[]string) ([]string, mir.TypeView, error) {
 	switch acessor := acessor.(type) {
 	case *mir.VariableAcessor:
@@ -128,7 +206,8 @@ func (s *Omitter) OmitAcessor(acessor mir.Acessor, blockUuids *[]string) ([]stri
 			},
 		}
 		substractBlockUuid := s.scir.InsertBlock(&substractBlock)
-		s.scir.SetInput(substractBlockUuid, "NUM1", &stackLengthBlock)
+		s.scir.LinkInput(substractBlockUuid, "NUM1", stackLengthBlockUuid)
+		return []string{substractBlockUuid}, typeView, nil
 	}
 	return nil, mir.TypeView{}, fmt.Errorf("not implemented yet")
 }
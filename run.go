@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"yummy-go.com/m/v2/headless"
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/mir/interp"
+	"yummy-go.com/m/v2/span"
+)
+
+func runRun(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	entry := flags.String("entry", "main", "the function to call")
+	optimization := addOptimizationFlags(flags)
+	flags.Parse(expandOptimizationFlags(args))
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: yummy run [-entry main] [-O0|-O1|-O2] [-dump-passes] <source.yum|source.mir>")
+		return 2
+	}
+	sourcePath := flags.Arg(0)
+
+	span.ResetStats()
+	program, ok := loadProgram(sourcePath)
+	if !ok {
+		reportSummary(sourcePath)
+		return 1
+	}
+	if !optimize(&program, optimization) {
+		return 1
+	}
+	if err := mir.AllocateFrames(&program); err != nil {
+		span.ReportNoSpan(span.Error, "%s: %s", sourcePath, err.Error())
+		return 1
+	}
+	if err := mir.Verify(&program); err != nil {
+		span.ReportNoSpan(span.Error, "%s: %s", sourcePath, err.Error())
+		return 1
+	}
+	interpreter := interp.New(&program)
+	function := interpreter.LookupFunction(*entry)
+	if function == nil {
+		span.ReportNoSpan(span.Error, "%s: missing function `%s`", sourcePath, *entry)
+		return 1
+	}
+	if len(function.Arguments) != 0 {
+		span.ReportNoSpan(span.Error, "%s: function `%s` must not take arguments", sourcePath, *entry)
+		return 1
+	}
+	values, err := interpreter.Call(function)
+	if err != nil {
+		span.ReportNoSpan(span.Error, "%s: %s", sourcePath, err.Error())
+		return 1
+	}
+	if len(values) > 0 {
+		strs := make([]string, 0, len(values))
+		for _, value := range values {
+			strs = append(strs, headless.ToString(value))
+		}
+		fmt.Println(strings.Join(strs, " "))
+	}
+	return 0
+}
@@ -0,0 +1,339 @@
+package scir
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	DataFormatSvg = "svg"
+	DataFormatPng = "png"
+	DataFormatJpg = "jpg"
+	DataFormatWav = "wav"
+	DataFormatMp3 = "mp3"
+)
+
+// png stores its resolution in pixels per meter, 72 dpi is the 1x resolution
+const pngPixelsPerMeter1x = 72 / 0.0254
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+func DetectDataFormat(content []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(content, pngSignature):
+		return DataFormatPng, nil
+	case bytes.HasPrefix(content, []byte{0xff, 0xd8, 0xff}):
+		return DataFormatJpg, nil
+	case len(content) >= 12 && string(content[0:4]) == "RIFF" && string(content[8:12]) == "WAVE":
+		return DataFormatWav, nil
+	case bytes.HasPrefix(content, []byte("ID3")) || (len(content) >= 2 && content[0] == 0xff && content[1]&0xe0 == 0xe0):
+		return DataFormatMp3, nil
+	}
+	if bytes.Contains(content[:min(len(content), 1024)], []byte("<svg")) {
+		return DataFormatSvg, nil
+	}
+	return "", fmt.Errorf("unknown asset format")
+}
+
+// creates a costume from the content of an image, the rotation center
+// is the center of the image if not specified
+func NewCostume(name string, content []byte, center *[2]float64) (Costume, error) {
+	dataFormat, err := DetectDataFormat(content)
+	if err != nil {
+		return Costume{}, err
+	}
+	var width, height, resolution float64
+	switch dataFormat {
+	case DataFormatSvg:
+		width, height, err = svgSize(content)
+		resolution = 1
+	case DataFormatPng:
+		width, height, resolution, err = pngSize(content)
+	case DataFormatJpg:
+		width, height, err = jpgSize(content)
+		resolution = 1
+	default:
+		return Costume{}, fmt.Errorf("a costume cannot be a `%s` file", dataFormat)
+	}
+	if err != nil {
+		return Costume{}, err
+	}
+	assetId := md5Hex(content)
+	costume := Costume{
+		AssetId:          assetId,
+		Name:             name,
+		Md5ext:           assetId + "." + dataFormat,
+		DataFormat:       dataFormat,
+		BitmapResolution: resolution,
+		RotationCenterX:  width / 2,
+		RotationCenterY:  height / 2,
+	}
+	if center != nil {
+		costume.RotationCenterX = center[0]
+		costume.RotationCenterY = center[1]
+	}
+	return costume, nil
+}
+
+func NewSound(name string, content []byte) (Sound, error) {
+	dataFormat, err := DetectDataFormat(content)
+	if err != nil {
+		return Sound{}, err
+	}
+	var rate, sampleCount float64
+	switch dataFormat {
+	case DataFormatWav:
+		rate, sampleCount, err = wavSamples(content)
+	case DataFormatMp3:
+		rate, sampleCount, err = mp3Samples(content)
+	default:
+		return Sound{}, fmt.Errorf("a sound cannot be a `%s` file", dataFormat)
+	}
+	if err != nil {
+		return Sound{}, err
+	}
+	assetId := md5Hex(content)
+	return Sound{
+		AssetId:     assetId,
+		Name:        name,
+		Md5ext:      assetId + "." + dataFormat,
+		DataFormat:  dataFormat,
+		Rate:        rate,
+		SampleCount: sampleCount,
+	}, nil
+}
+
+// adds a costume to the target, replacing the costume with the same name.
+// identical contents share one asset. the first costume declared for a new
+// target replaces the one it was made with
+func (s *Scir) AddCostume(target *Target, costume Costume, content []byte) {
+	s.Assets[costume.Md5ext] = content
+	if s.placeholders[target.Name] {
+		delete(s.placeholders, target.Name)
+		placeholders := target.Costumes
+		target.Costumes = []Costume{costume}
+		target.CurrentCostume = 0
+		for _, placeholder := range placeholders {
+			s.releaseAsset(placeholder.Md5ext)
+		}
+		return
+	}
+	for idx := range target.Costumes {
+		if target.Costumes[idx].Name == costume.Name {
+			replaced := target.Costumes[idx].Md5ext
+			target.Costumes[idx] = costume
+			s.releaseAsset(replaced)
+			return
+		}
+	}
+	target.Costumes = append(target.Costumes, costume)
+}
+
+// adds a sound to the target, replacing the sound with the same name.
+// identical contents share one asset
+func (s *Scir) AddSound(target *Target, sound Sound, content []byte) {
+	s.Assets[sound.Md5ext] = content
+	for idx := range target.Sounds {
+		if target.Sounds[idx].Name == sound.Name {
+			replaced := target.Sounds[idx].Md5ext
+			target.Sounds[idx] = sound
+			s.releaseAsset(replaced)
+			return
+		}
+	}
+	target.Sounds = append(target.Sounds, sound)
+}
+
+// drops an asset no costume or sound uses anymore
+func (s *Scir) releaseAsset(md5ext string) {
+	for _, target := range s.Ir.Targets {
+		for _, costume := range target.Costumes {
+			if costume.Md5ext == md5ext {
+				return
+			}
+		}
+		for _, sound := range target.Sounds {
+			if sound.Md5ext == md5ext {
+				return
+			}
+		}
+	}
+	delete(s.Assets, md5ext)
+}
+
+func svgSize(content []byte) (float64, float64, error) {
+	var svg struct {
+		Width   string `xml:"width,attr"`
+		Height  string `xml:"height,attr"`
+		ViewBox string `xml:"viewBox,attr"`
+	}
+	if err := xml.Unmarshal(content, &svg); err != nil {
+		return 0, 0, err
+	}
+	width, errWidth := svgLength(svg.Width)
+	height, errHeight := svgLength(svg.Height)
+	if errWidth == nil && errHeight == nil {
+		return width, height, nil
+	}
+	viewBox := strings.FieldsFunc(svg.ViewBox, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	if len(viewBox) == 4 {
+		width, errWidth = strconv.ParseFloat(viewBox[2], 64)
+		height, errHeight = strconv.ParseFloat(viewBox[3], 64)
+		if errWidth == nil && errHeight == nil {
+			return width, height, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("svg: missing `width`, `height` or `viewBox`")
+}
+
+func svgLength(length string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(length), "px"), 64)
+}
+
+func pngSize(content []byte) (float64, float64, float64, error) {
+	var width, height uint32
+	resolution := 1.0
+	offset := len(pngSignature)
+	for offset+8 <= len(content) {
+		length := int(binary.BigEndian.Uint32(content[offset:]))
+		chunkType := string(content[offset+4 : offset+8])
+		data := content[offset+8:]
+		if len(data) < length {
+			break
+		}
+		data = data[:length]
+		switch chunkType {
+		case "IHDR":
+			if length < 8 {
+				return 0, 0, 0, fmt.Errorf("png: broken `IHDR` chunk")
+			}
+			width = binary.BigEndian.Uint32(data[0:])
+			height = binary.BigEndian.Uint32(data[4:])
+		case "pHYs":
+			// unit 1 means the density is in pixels per meter
+			if length >= 9 && data[8] == 1 {
+				pixelsPerMeter := float64(binary.BigEndian.Uint32(data[0:]))
+				resolution = max(math.Round(pixelsPerMeter/pngPixelsPerMeter1x), 1)
+			}
+		case "IDAT", "IEND":
+			offset = len(content)
+			continue
+		}
+		// length, type, data and crc
+		offset += 12 + length
+	}
+	if width == 0 || height == 0 {
+		return 0, 0, 0, fmt.Errorf("png: missing `IHDR` chunk")
+	}
+	return float64(width), float64(height), resolution, nil
+}
+
+func jpgSize(content []byte) (float64, float64, error) {
+	offset := 2
+	for offset+4 <= len(content) {
+		if content[offset] != 0xff {
+			break
+		}
+		marker := content[offset+1]
+		length := int(binary.BigEndian.Uint16(content[offset+2:]))
+		// start of frame markers, except DHT, JPG and DAC
+		if marker >= 0xc0 && marker <= 0xcf && marker != 0xc4 && marker != 0xc8 && marker != 0xcc {
+			if offset+9 > len(content) {
+				break
+			}
+			height := binary.BigEndian.Uint16(content[offset+5:])
+			width := binary.BigEndian.Uint16(content[offset+7:])
+			return float64(width), float64(height), nil
+		}
+		offset += 2 + length
+	}
+	return 0, 0, fmt.Errorf("jpg: missing frame header")
+}
+
+func wavSamples(content []byte) (float64, float64, error) {
+	var rate uint32
+	var blockAlign uint16
+	offset := 12
+	for offset+8 <= len(content) {
+		chunkType := string(content[offset : offset+4])
+		length := int(binary.LittleEndian.Uint32(content[offset+4:]))
+		data := content[offset+8:]
+		switch chunkType {
+		case "fmt ":
+			if len(data) < 16 {
+				return 0, 0, fmt.Errorf("wav: broken `fmt` chunk")
+			}
+			rate = binary.LittleEndian.Uint32(data[4:])
+			blockAlign = binary.LittleEndian.Uint16(data[12:])
+		case "data":
+			if rate == 0 || blockAlign == 0 {
+				return 0, 0, fmt.Errorf("wav: `data` chunk before `fmt` chunk")
+			}
+			length = min(length, len(data))
+			return float64(rate), float64(length / int(blockAlign)), nil
+		}
+		// chunks are aligned to 2 bytes
+		offset += 8 + length + length%2
+	}
+	return 0, 0, fmt.Errorf("wav: missing `data` chunk")
+}
+
+var mp3Bitrates = [2][16]int{
+	// mpeg 1 layer III
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	// mpeg 2 and 2.5 layer III
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+}
+
+var mp3SampleRates = map[byte][3]int{
+	3: {44100, 48000, 32000}, // mpeg 1
+	2: {22050, 24000, 16000}, // mpeg 2
+	0: {11025, 12000, 8000},  // mpeg 2.5
+}
+
+// walks through the frames of a layer III mp3 to count its samples
+func mp3Samples(content []byte) (float64, float64, error) {
+	offset := 0
+	if bytes.HasPrefix(content, []byte("ID3")) && len(content) >= 10 {
+		// the tag size is a 28 bits syncsafe integer
+		size := int(content[6])<<21 | int(content[7])<<14 | int(content[8])<<7 | int(content[9])
+		offset = 10 + size
+	}
+	rate, sampleCount := 0, 0
+	for offset+4 <= len(content) {
+		header := content[offset : offset+4]
+		if header[0] != 0xff || header[1]&0xe0 != 0xe0 {
+			break
+		}
+		version := (header[1] >> 3) & 0x3
+		layer := (header[1] >> 1) & 0x3
+		rates, ok := mp3SampleRates[version]
+		rateIndex := (header[2] >> 2) & 0x3
+		if !ok || layer != 1 || rateIndex == 3 {
+			return 0, 0, fmt.Errorf("mp3: only layer III is supported")
+		}
+		samplesPerFrame, bitrateTable, slot := 1152, 0, 144
+		if version != 3 {
+			samplesPerFrame, bitrateTable, slot = 576, 1, 72
+		}
+		bitrate := mp3Bitrates[bitrateTable][header[2]>>4] * 1000
+		if bitrate == 0 {
+			return 0, 0, fmt.Errorf("mp3: free-format bitrate is not supported")
+		}
+		rate = rates[rateIndex]
+		padding := int((header[2] >> 1) & 0x1)
+		offset += slot*bitrate/rate + padding
+		sampleCount += samplesPerFrame
+	}
+	if sampleCount == 0 {
+		return 0, 0, fmt.Errorf("mp3: missing frames")
+	}
+	return float64(rate), float64(sampleCount), nil
+}
@@ -0,0 +1,235 @@
+package scir_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"yummy-go.com/m/v2/scir"
+)
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	// the crc is not checked
+	return append(chunk, 0, 0, 0, 0)
+}
+
+// a png of the size, with a `pHYs` chunk when pixelsPerMeter is not 0
+func pngFixture(width, height, pixelsPerMeter uint32) []byte {
+	content := []byte("\x89PNG\r\n\x1a\n")
+	header := binary.BigEndian.AppendUint32(nil, width)
+	header = binary.BigEndian.AppendUint32(header, height)
+	content = append(content, pngChunk("IHDR", append(header, 8, 6, 0, 0, 0))...)
+	if pixelsPerMeter != 0 {
+		density := binary.BigEndian.AppendUint32(nil, pixelsPerMeter)
+		density = binary.BigEndian.AppendUint32(density, pixelsPerMeter)
+		content = append(content, pngChunk("pHYs", append(density, 1))...)
+	}
+	content = append(content, pngChunk("IDAT", []byte{0})...)
+	return append(content, pngChunk("IEND", nil)...)
+}
+
+// a mono 16 bits wav of the samples
+func wavFixture(rate uint32, samples int) []byte {
+	format := binary.LittleEndian.AppendUint16(nil, 1)
+	format = binary.LittleEndian.AppendUint16(format, 1)
+	format = binary.LittleEndian.AppendUint32(format, rate)
+	format = binary.LittleEndian.AppendUint32(format, rate*2)
+	format = binary.LittleEndian.AppendUint16(format, 2)
+	format = binary.LittleEndian.AppendUint16(format, 16)
+	chunks := append([]byte("fmt "), binary.LittleEndian.AppendUint32(nil, uint32(len(format)))...)
+	chunks = append(chunks, format...)
+	chunks = append(chunks, "data"...)
+	chunks = binary.LittleEndian.AppendUint32(chunks, uint32(samples*2))
+	chunks = append(chunks, make([]byte, samples*2)...)
+	content := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(4+len(chunks)))...)
+	return append(append(content, "WAVE"...), chunks...)
+}
+
+// frames of a 128 kbps 44.1 kHz mpeg 1 layer III mp3, 417 bytes each
+func mp3Fixture(frames int) []byte {
+	content := make([]byte, 0)
+	for range frames {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+		content = append(content, frame...)
+	}
+	return content
+}
+
+func TestDetectDataFormat(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content []byte
+		format  string
+	}{
+		{"png", pngFixture(1, 1, 0), scir.DataFormatPng},
+		{"jpg", []byte{0xff, 0xd8, 0xff, 0xe0}, scir.DataFormatJpg},
+		{"svg", []byte(`<?xml version="1.0"?><svg width="1" height="1"></svg>`), scir.DataFormatSvg},
+		{"wav", wavFixture(8000, 1), scir.DataFormatWav},
+		{"mp3", mp3Fixture(1), scir.DataFormatMp3},
+		{"mp3 with a tag", append([]byte("ID3\x03\x00\x00\x00\x00\x00\x00"), mp3Fixture(1)...), scir.DataFormatMp3},
+	} {
+		format, err := scir.DetectDataFormat(test.content)
+		if err != nil || format != test.format {
+			t.Errorf("%s: expected %s, found %s (%v)", test.name, test.format, format, err)
+		}
+	}
+	if _, err := scir.DetectDataFormat([]byte("hello")); err == nil {
+		t.Error("expected text to be of no known format")
+	}
+}
+
+func TestNewCostume(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		content    []byte
+		center     *[2]float64
+		resolution float64
+		x, y       float64
+	}{
+		{"svg size", []byte(`<svg width="10px" height="20"></svg>`), nil, 1, 5, 10},
+		{"svg view box", []byte(`<svg viewBox="0 0 30,40"></svg>`), nil, 1, 15, 20},
+		{"png", pngFixture(64, 32, 0), nil, 1, 32, 16},
+		// 144 dpi
+		{"png at 2x", pngFixture(64, 32, 5669), nil, 2, 32, 16},
+		{"explicit center", pngFixture(64, 32, 0), &[2]float64{0, -3}, 1, 0, -3},
+	} {
+		costume, err := scir.NewCostume("costume", test.content, test.center)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if costume.BitmapResolution != test.resolution || costume.RotationCenterX != test.x || costume.RotationCenterY != test.y {
+			t.Errorf("%s: expected resolution %g and center (%g, %g), found %+v", test.name, test.resolution, test.x, test.y, costume)
+		}
+	}
+	for name, content := range map[string][]byte{
+		"sound":       wavFixture(8000, 1),
+		"sizeless":    []byte(`<svg></svg>`),
+		"without hdr": []byte("\x89PNG\r\n\x1a\n"),
+	} {
+		if _, err := scir.NewCostume("costume", content, nil); err == nil {
+			t.Errorf("%s: expected an invalid costume", name)
+		}
+	}
+}
+
+func TestNewSound(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content []byte
+		rate    float64
+		samples float64
+	}{
+		{"wav", wavFixture(22050, 100), 22050, 100},
+		{"mp3", mp3Fixture(3), 44100, 3 * 1152},
+	} {
+		sound, err := scir.NewSound("sound", test.content)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if sound.Rate != test.rate || sound.SampleCount != test.samples {
+			t.Errorf("%s: expected %g samples at %g, found %g at %g", test.name, test.samples, test.rate, sound.SampleCount, sound.Rate)
+		}
+	}
+	if _, err := scir.NewSound("sound", pngFixture(1, 1, 0)); err == nil {
+		t.Error("expected an image to be an invalid sound")
+	}
+}
+
+func costume(t *testing.T, name string, content []byte) scir.Costume {
+	t.Helper()
+	theCostume, err := scir.NewCostume(name, content, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return theCostume
+}
+
+func costumeNames(target *scir.Target) string {
+	names := make([]string, 0, len(target.Costumes))
+	for _, theCostume := range target.Costumes {
+		names = append(names, theCostume.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func TestAddCostume(t *testing.T) {
+	project := scir.NewProject()
+	project.SetEditingTarget("Sprite")
+	sprite := project.EditingTarget
+	red, blue := pngFixture(2, 2, 0), pngFixture(4, 4, 0)
+
+	// the backdrop the sprite was made with gives way, the stage keeps it
+	project.AddCostume(sprite, costume(t, "red", red), red)
+	if names := costumeNames(sprite); names != "red" {
+		t.Errorf("expected the placeholder to be replaced, found %s", names)
+	}
+	if len(project.Assets) != 2 {
+		t.Errorf("expected the backdrop and the costume as assets, found %d", len(project.Assets))
+	}
+	// identical contents share one asset
+	project.AddCostume(sprite, costume(t, "red again", red), red)
+	if names := costumeNames(sprite); names != "red, red again" || len(project.Assets) != 2 {
+		t.Errorf("expected two costumes on one asset, found %s on %d assets", names, len(project.Assets))
+	}
+	// replacing one of them keeps the asset of the other
+	redAsset := sprite.Costumes[0].Md5ext
+	project.AddCostume(sprite, costume(t, "red", blue), blue)
+	if _, ok := project.Assets[redAsset]; !ok || len(project.Assets) != 3 {
+		t.Errorf("expected the shared asset to be kept, found %d assets", len(project.Assets))
+	}
+	// replacing the last costume using it drops it
+	project.AddCostume(sprite, costume(t, "red again", blue), blue)
+	if _, ok := project.Assets[redAsset]; ok || len(project.Assets) != 2 {
+		t.Errorf("expected the released asset to be dropped, found %d assets", len(project.Assets))
+	}
+	if names := costumeNames(sprite); names != "red, red again" {
+		t.Errorf("expected the costumes to be replaced in place, found %s", names)
+	}
+
+	// the stage backdrop is made up too
+	project.AddCostume(project.StageTarget, costume(t, "sky", blue), blue)
+	if names := costumeNames(project.StageTarget); names != "sky" || len(project.Assets) != 1 {
+		t.Errorf("expected the backdrop to be replaced, found %s on %d assets", names, len(project.Assets))
+	}
+}
+
+func TestAddSound(t *testing.T) {
+	project := scir.NewProject()
+	stage := project.StageTarget
+	sound := func(name string, content []byte) scir.Sound {
+		theSound, err := scir.NewSound(name, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return theSound
+	}
+	short, long := wavFixture(8000, 1), wavFixture(8000, 2)
+	project.AddSound(stage, sound("pop", short), short)
+	project.AddSound(stage, sound("pop copy", short), short)
+	if len(stage.Sounds) != 2 || len(project.Assets) != 2 {
+		t.Errorf("expected two sounds on one asset, found %d on %d assets", len(stage.Sounds), len(project.Assets))
+	}
+	project.AddSound(stage, sound("pop", long), long)
+	project.AddSound(stage, sound("pop copy", long), long)
+	if len(stage.Sounds) != 2 || len(project.Assets) != 2 || !bytes.Equal(project.Assets[stage.Sounds[0].Md5ext], long) {
+		t.Errorf("expected the sounds replaced and the old asset dropped, found %d sounds on %d assets", len(stage.Sounds), len(project.Assets))
+	}
+}
+
+func TestCostumeCenterAtZero(t *testing.T) {
+	content, err := json.Marshal(scir.Costume{Name: "centered"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(content, []byte(`"rotationCenterX":0`)) || !bytes.Contains(content, []byte(`"rotationCenterY":0`)) {
+		t.Errorf("expected the center at 0 to be written, found %s", content)
+	}
+}
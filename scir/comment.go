@@ -0,0 +1,31 @@
+package scir
+
+import "strings"
+
+const (
+	commentWidth      float64 = 200
+	commentLineHeight float64 = 18
+	commentMinHeight  float64 = 48
+)
+
+// attaches a comment to a block of the editing target, it is placed next
+// to the script by LayoutScripts
+func (s *Scir) AttachComment(blockUuid, text string) string {
+	commentId := s.ids.Next(s.EditingTarget.Name, s.idScope, "comment")
+	if s.EditingTarget.Comments == nil {
+		s.EditingTarget.Comments = make(map[string]Comment)
+	}
+	lines := float64(strings.Count(text, "\n") + 1)
+	s.EditingTarget.Comments[commentId] = Comment{
+		BlockId: &blockUuid,
+		Width:   commentWidth,
+		Height:  max(commentMinHeight, commentLineHeight*(lines+1)),
+		Text:    text,
+	}
+	// Scratch links the comment from both sides
+	if block, ok := s.EditingTarget.Blocks[blockUuid]; ok {
+		block.Comment = &commentId
+	}
+	s.IdTable.TrackComment(s.EditingTarget.Name, commentId)
+	return commentId
+}
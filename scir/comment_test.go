@@ -0,0 +1,52 @@
+package scir
+
+import "testing"
+
+func TestCommentLinks(t *testing.T) {
+	project := NewProject()
+	project.SetEditingTarget("Stage")
+	newBlock := func(opcode string) *Block {
+		x, y := 0.0, 0.0
+		return &Block{
+			Opcode:   opcode,
+			Inputs:   make(map[string]MaybeShadowedInput),
+			Fields:   make(map[string]Field),
+			TopLevel: true,
+			X:        &x,
+			Y:        &y,
+		}
+	}
+	blockId := project.InsertBlock(newBlock("motion_movesteps"))
+	commentId := project.AttachComment(blockId, "moves")
+	if comment := project.EditingTarget.Blocks[blockId].Comment; comment == nil || *comment != commentId {
+		t.Fatalf("the block does not refer to its comment")
+	}
+	copiedId := project.CopyBlocks([]string{blockId})[0]
+	if project.EditingTarget.Blocks[copiedId].Comment != nil {
+		t.Error("the copy refers to the comment of the original")
+	}
+	delete(project.EditingTarget.Blocks, copiedId)
+	if err := Validate(&project); err != nil {
+		t.Fatal(err)
+	}
+
+	// the artist moved the generated comment onto a script of their own
+	artistBlock := newBlock("motion_turnright")
+	project.EditingTarget.Blocks["artistBlock000000000"] = artistBlock
+	project.EditingTarget.Blocks[blockId].Comment = nil
+	artistBlockId := "artistBlock000000000"
+	comment := project.EditingTarget.Comments[commentId]
+	comment.BlockId = &artistBlockId
+	project.EditingTarget.Comments[commentId] = comment
+	artistBlock.Comment = &commentId
+	if err := Validate(&project); err != nil {
+		t.Fatal(err)
+	}
+	project.RemoveGeneratedBlocks()
+	if artistBlock.Comment != nil {
+		t.Error("the block of the artist refers to a removed comment")
+	}
+	if err := Validate(&project); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,148 @@
+package scir
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// removes the generated blocks that no script reaches through `next` or
+// inputs, and checks that each reachable block has exactly one parent.
+// sharing a block is always a bug of the compiler, so it is reported instead
+// of fixed. blocks the IdTable does not track belong to the artist and are
+// kept even when unreachable. the variables and lists of the previous build
+// that this one did not declare are removed too
+func (s *Scir) CollectGarbage() error {
+	errs := make([]error, 0)
+	for idx := range s.Ir.Targets {
+		target := &s.Ir.Targets[idx]
+		errs = append(errs, s.collectTarget(target)...)
+	}
+	s.collectVariables()
+	return errors.Join(errs...)
+}
+
+// a stale variable or list some block still refers to is kept, and tracked
+// so the next build removes it once nothing does
+func (s *Scir) collectVariables() {
+	referred := make(map[string]struct{})
+	for idx := range s.Ir.Targets {
+		for _, block := range s.Ir.Targets[idx].Blocks {
+			for _, name := range []string{"VARIABLE", "LIST"} {
+				if field, ok := block.Fields[name]; ok && field.Id != nil {
+					referred[*field.Id] = struct{}{}
+				}
+			}
+			for _, input := range block.Inputs {
+				for _, input := range []Input{input.ObscuredInput, input.ShadowedInput} {
+					if variableInput, ok := input.(*VariableOrListInput); ok && variableInput != nil {
+						referred[variableInput.Id] = struct{}{}
+					}
+				}
+			}
+		}
+	}
+	for idx := range s.Ir.Targets {
+		target := &s.Ir.Targets[idx]
+		for _, variableId := range s.previousVariables[target.Name] {
+			if slices.Contains(s.IdTable.Variables[target.Name], variableId) {
+				continue
+			}
+			if _, ok := referred[variableId]; ok {
+				s.IdTable.TrackVariable(target.Name, variableId)
+				continue
+			}
+			delete(target.Variables, variableId)
+		}
+		for _, listId := range s.previousLists[target.Name] {
+			if slices.Contains(s.IdTable.Lists[target.Name], listId) {
+				continue
+			}
+			if _, ok := referred[listId]; ok {
+				s.IdTable.TrackList(target.Name, listId)
+				continue
+			}
+			delete(target.Lists, listId)
+		}
+	}
+	s.previousVariables = nil
+	s.previousLists = nil
+}
+
+func (s *Scir) collectTarget(target *Target) []error {
+	errs := make([]error, 0)
+	report := func(blockId string, message string, args ...any) {
+		errs = append(errs, &ValidationError{
+			Target:  target.Name,
+			BlockId: blockId,
+			Message: fmt.Sprintf(message, args...),
+		})
+	}
+	// the block that refers to each reachable block, roots refer to themselves
+	referrers := make(map[string]string)
+	owners := make(map[*Block]string)
+	var visit func(blockId, referrerId string)
+	visit = func(blockId, referrerId string) {
+		if previous, ok := referrers[blockId]; ok {
+			report(blockId, "referred by both `%s` and `%s`", previous, referrerId)
+			return
+		}
+		block, ok := target.Blocks[blockId]
+		if !ok {
+			report(referrerId, "refers to missing block `%s`", blockId)
+			return
+		}
+		referrers[blockId] = referrerId
+		if owner, ok := owners[block]; ok {
+			report(blockId, "shares its block with `%s`", owner)
+		} else {
+			owners[block] = blockId
+		}
+		if blockId != referrerId && (block.Parent == nil || *block.Parent != referrerId) {
+			report(blockId, "referred by `%s` which is not its parent", referrerId)
+		}
+		if block.Next != nil {
+			visit(*block.Next, blockId)
+		}
+		for _, name := range sortedInputNames(block.Inputs) {
+			for _, inputId := range inputBlockIds(block.Inputs[name]) {
+				visit(inputId, blockId)
+			}
+		}
+	}
+	for _, blockId := range sortedBlockIds(target.Blocks) {
+		if target.Blocks[blockId].TopLevel {
+			visit(blockId, blockId)
+		}
+	}
+	removed := make(map[string]struct{})
+	for _, blockId := range s.IdTable.Blocks[target.Name] {
+		if _, ok := referrers[blockId]; ok {
+			continue
+		}
+		if _, ok := target.Blocks[blockId]; ok {
+			delete(target.Blocks, blockId)
+			removed[blockId] = struct{}{}
+		}
+	}
+	if len(removed) == 0 {
+		return errs
+	}
+	for commentId, comment := range target.Comments {
+		if comment.BlockId == nil {
+			continue
+		}
+		if _, ok := removed[*comment.BlockId]; ok {
+			comment.BlockId = nil
+			target.Comments[commentId] = comment
+		}
+	}
+	tracked := make([]string, 0)
+	for _, blockId := range s.IdTable.Blocks[target.Name] {
+		if _, ok := removed[blockId]; !ok {
+			tracked = append(tracked, blockId)
+		}
+	}
+	s.IdTable.Blocks[target.Name] = tracked
+	return errs
+}
@@ -0,0 +1,33 @@
+package scir
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCollectGarbageKeepsArtistBlocks(t *testing.T) {
+	project := NewProject()
+	project.SetEditingTarget("Stage")
+	newBlock := func(opcode string) *Block {
+		return &Block{
+			Opcode: opcode,
+			Inputs: make(map[string]MaybeShadowedInput),
+			Fields: make(map[string]Field),
+		}
+	}
+	// a block dragged out of its script in the editor, reached by nothing
+	project.EditingTarget.Blocks["artistBlock000000000"] = newBlock("motion_movesteps")
+	generated := project.InsertBlock(newBlock("motion_turnright"))
+	if err := project.CollectGarbage(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := project.EditingTarget.Blocks["artistBlock000000000"]; !ok {
+		t.Error("the block of the artist was collected")
+	}
+	if _, ok := project.EditingTarget.Blocks[generated]; ok {
+		t.Error("the unreachable generated block was kept")
+	}
+	if slices.Contains(project.IdTable.Blocks["Stage"], generated) {
+		t.Error("the collected block is still tracked")
+	}
+}
@@ -3,10 +3,20 @@ package scir
 import (
 	"encoding/json"
 	"os"
+	"slices"
 )
 
 type IdTable struct {
 	Ids map[string]IdUsage
+	// the blocks generated by the build, by target name
+	Blocks map[string][]string
+	// the comments generated by the build, by target name
+	Comments map[string][]string
+	// the variables and lists declared by the build, by target name
+	Variables map[string][]string
+	Lists     map[string][]string
+	// the positions of the generated scripts, by target name and block id
+	Positions map[string]map[string][2]float64
 }
 
 type IdUsage struct {
@@ -26,9 +36,54 @@ func (s *IdTable) LookupId(id string) *IdUsage {
 	return nil
 }
 
+func (s *IdTable) TrackBlock(target string, blockId string) {
+	if s.Blocks == nil {
+		s.Blocks = make(map[string][]string)
+	}
+	s.Blocks[target] = append(s.Blocks[target], blockId)
+}
+
+func (s *IdTable) TrackComment(target string, commentId string) {
+	if s.Comments == nil {
+		s.Comments = make(map[string][]string)
+	}
+	s.Comments[target] = append(s.Comments[target], commentId)
+}
+
+// a variable is declared each time it is used, it is tracked once
+func (s *IdTable) TrackVariable(target string, variableId string) {
+	if s.Variables == nil {
+		s.Variables = make(map[string][]string)
+	}
+	if !slices.Contains(s.Variables[target], variableId) {
+		s.Variables[target] = append(s.Variables[target], variableId)
+	}
+}
+
+func (s *IdTable) TrackList(target string, listId string) {
+	if s.Lists == nil {
+		s.Lists = make(map[string][]string)
+	}
+	if !slices.Contains(s.Lists[target], listId) {
+		s.Lists[target] = append(s.Lists[target], listId)
+	}
+}
+
+func (s *IdTable) SetPositions(target string, positions map[string][2]float64) {
+	if s.Positions == nil {
+		s.Positions = make(map[string]map[string][2]float64)
+	}
+	s.Positions[target] = positions
+}
+
 func NewIdTable() IdTable {
 	return IdTable{
-		Ids: make(map[string]IdUsage),
+		Ids:       make(map[string]IdUsage),
+		Blocks:    make(map[string][]string),
+		Comments:  make(map[string][]string),
+		Variables: make(map[string][]string),
+		Lists:     make(map[string][]string),
+		Positions: make(map[string]map[string][2]float64),
 	}
 }
 
@@ -41,5 +96,23 @@ func OpenIdTable(path string) (IdTable, error) {
 	if err := json.Unmarshal(content, &idTable); err != nil {
 		return IdTable{}, err
 	}
+	if idTable.Ids == nil {
+		idTable.Ids = make(map[string]IdUsage)
+	}
+	if idTable.Blocks == nil {
+		idTable.Blocks = make(map[string][]string)
+	}
+	if idTable.Comments == nil {
+		idTable.Comments = make(map[string][]string)
+	}
+	if idTable.Variables == nil {
+		idTable.Variables = make(map[string][]string)
+	}
+	if idTable.Lists == nil {
+		idTable.Lists = make(map[string][]string)
+	}
+	if idTable.Positions == nil {
+		idTable.Positions = make(map[string]map[string][2]float64)
+	}
 	return idTable, nil
 }
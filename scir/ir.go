@@ -1,7 +1,9 @@
 package scir
 
 import (
+	"bytes"
 	"encoding/json"
+	"slices"
 	"strconv"
 )
 
@@ -38,6 +40,96 @@ type Target struct {
 	Direction     *float64 `json:"direction,omitempty"`
 	Draggable     *bool    `json:"draggable,omitempty"`
 	RotationStyle *string  `json:"rotationStyle,omitempty"`
+	// the block ids in the order they were inserted or loaded, Scratch starts
+	// the scripts of an event in this order
+	blockOrder []string
+}
+
+// the ids of the blocks in the order they were inserted or loaded, blocks
+// put in the map directly come last, by id
+func (s *Target) BlockIds() []string {
+	ids := make([]string, 0, len(s.Blocks))
+	seen := make(map[string]bool, len(s.Blocks))
+	for _, id := range s.blockOrder {
+		if _, ok := s.Blocks[id]; ok && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	rest := make([]string, 0)
+	for id := range s.Blocks {
+		if !seen[id] {
+			rest = append(rest, id)
+		}
+	}
+	slices.Sort(rest)
+	return append(ids, rest...)
+}
+
+func (s *Target) insertBlock(id string, block *Block) {
+	s.Blocks[id] = block
+	s.blockOrder = append(s.blockOrder, id)
+}
+
+// the fields of a target, without its json methods
+type targetFields Target
+
+func (s *Target) UnmarshalJSON(data []byte) error {
+	fields := struct {
+		*targetFields
+		Blocks json.RawMessage `json:"blocks"`
+	}{targetFields: (*targetFields)(s)}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	s.Blocks = make(map[string]*Block)
+	s.blockOrder = make([]string, 0)
+	if len(fields.Blocks) == 0 || string(fields.Blocks) == "null" {
+		return nil
+	}
+	// the keys are read one by one to keep their order
+	decoder := json.NewDecoder(bytes.NewReader(fields.Blocks))
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		var block *Block
+		if err := decoder.Decode(&block); err != nil {
+			return err
+		}
+		s.insertBlock(token.(string), block)
+	}
+	return nil
+}
+
+func (s Target) MarshalJSON() ([]byte, error) {
+	var blocks bytes.Buffer
+	blocks.WriteByte('{')
+	for idx, id := range s.BlockIds() {
+		if idx > 0 {
+			blocks.WriteByte(',')
+		}
+		key, err := json.Marshal(id)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(s.Blocks[id])
+		if err != nil {
+			return nil, err
+		}
+		blocks.Write(key)
+		blocks.WriteByte(':')
+		blocks.Write(value)
+	}
+	blocks.WriteByte('}')
+	return json.Marshal(struct {
+		targetFields
+		Blocks json.RawMessage `json:"blocks"`
+	}{targetFields(s), blocks.Bytes()})
 }
 
 func NewTarget(name string, costumes []Costume) Target {
@@ -76,8 +168,9 @@ type Costume struct {
 	Md5ext           string  `json:"md5ext"`
 	DataFormat       string  `json:"dataFormat"`
 	BitmapResolution float64 `json:"bitmapResolution,omitempty"`
-	RotationCenterX  float64 `json:"rotationCenterX,omitempty"`
-	RotationCenterY  float64 `json:"rotationCenterY,omitempty"`
+	// a center at 0 is kept, it is not the default
+	RotationCenterX float64 `json:"rotationCenterX"`
+	RotationCenterY float64 `json:"rotationCenterY"`
 }
 
 type Sound struct {
@@ -235,7 +328,11 @@ func unmarshalInput(data json.RawMessage) (Input, error) {
 	case 7:
 		fallthrough
 	case 8:
-		value, _ := array[1].(float64)
+		// projects saved by Scratch mostly keep numbers as strings
+		value, ok := array[1].(float64)
+		if text, isText := array[1].(string); !ok && isText {
+			value, _ = strconv.ParseFloat(text, 64)
+		}
 		return &NumberalInput{
 			Type:  InputType(inputType),
 			Value: value,
@@ -268,6 +365,7 @@ func unmarshalInput(data json.RawMessage) (Input, error) {
 			y = &theY
 		}
 		return &VariableOrListInput{
+			Type:  InputType(inputType),
 			Value: value,
 			Id:    id,
 			X:     x,
@@ -472,7 +570,7 @@ type Mutation struct {
 }
 
 type Comment struct {
-	BlockId   string  `json:"blockId"`
+	BlockId   *string `json:"blockId"`
 	X         float64 `json:"x"`
 	Y         float64 `json:"y"`
 	Width     float64 `json:"width"`
@@ -499,7 +597,7 @@ func (s *Variable) UnmarshalJSON(data []byte) error {
 		s.IsCloud, _ = array[2].(bool)
 	}
 	s.Name, _ = array[0].(string)
-	s.Value, _ = array[1].(string)
+	s.Value = jsonValueString(array[1])
 	return nil
 }
 
@@ -524,6 +622,19 @@ func (s Variable) MarshalJSON() ([]byte, error) {
 	return bytes, nil
 }
 
+// Scratch saves values as numbers, strings or booleans
+func jsonValueString(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	return ""
+}
+
 type List struct {
 	Name  string
 	Value []string
@@ -538,7 +649,11 @@ func (s *List) UnmarshalJSON(data []byte) error {
 		return &json.UnmarshalTypeError{}
 	}
 	s.Name, _ = array[0].(string)
-	s.Value, _ = array[1].([]string)
+	items, _ := array[1].([]any)
+	s.Value = make([]string, 0, len(items))
+	for _, item := range items {
+		s.Value = append(s.Value, jsonValueString(item))
+	}
 	return nil
 }
 
@@ -0,0 +1,263 @@
+package scir
+
+import "slices"
+
+// rough sizes of rendered blocks in the editor at the default zoom, only
+// used to keep the scripts from overlapping
+const (
+	layoutBlockHeight    float64 = 48
+	layoutHatHeight      float64 = 80
+	layoutArmHeight      float64 = 24
+	layoutReporterHeight float64 = 40
+	layoutCharWidth      float64 = 8
+	layoutMinWidth       float64 = 64
+	layoutPadding        float64 = 16
+	layoutGap            float64 = 48
+	// a column is ended when it grows taller than this
+	layoutColumnHeight float64 = 1200
+)
+
+// the inputs that hold a stack of blocks instead of a reporter
+var substackInputs = []string{"SUBSTACK", "SUBSTACK2"}
+
+// gives a position to every top-level block that has none. scripts placed
+// by a previous build keep their position, others are put in columns on the
+// right of the existing scripts
+func (s *Scir) LayoutScripts() {
+	for idx := range s.Ir.Targets {
+		s.layoutTarget(&s.Ir.Targets[idx])
+	}
+}
+
+func (s *Scir) layoutTarget(target *Target) {
+	known := s.IdTable.Positions[target.Name]
+	commented := s.commentedScripts(target)
+	scriptWidth := func(blockId string) (float64, float64) {
+		width, height := estimateStack(target, blockId)
+		if _, ok := commented[blockId]; ok {
+			width += layoutGap + commentWidth
+		}
+		return width, height
+	}
+	unplaced := make([]string, 0)
+	var right float64 = 0
+	for _, blockId := range s.topLevelBlockIds(target) {
+		block := target.Blocks[blockId]
+		if block.X == nil || block.Y == nil {
+			if position, ok := known[blockId]; ok {
+				x, y := position[0], position[1]
+				block.X = &x
+				block.Y = &y
+			} else {
+				unplaced = append(unplaced, blockId)
+				continue
+			}
+		}
+		width, _ := scriptWidth(blockId)
+		right = max(right, *block.X+width)
+	}
+	var x float64 = 0
+	if right > 0 {
+		x = right + layoutGap
+	}
+	var y, columnWidth float64 = 0, 0
+	for _, blockId := range unplaced {
+		width, height := scriptWidth(blockId)
+		if y > 0 && y+height > layoutColumnHeight {
+			x += columnWidth + layoutGap
+			y = 0
+			columnWidth = 0
+		}
+		blockX, blockY := x, y
+		target.Blocks[blockId].X = &blockX
+		target.Blocks[blockId].Y = &blockY
+		y += height + layoutGap
+		columnWidth = max(columnWidth, width)
+	}
+	s.layoutComments(target)
+	// remembers where the generated scripts are for the next build
+	positions := make(map[string][2]float64)
+	for _, blockId := range s.IdTable.Blocks[target.Name] {
+		block, ok := target.Blocks[blockId]
+		if ok && block.TopLevel && block.X != nil && block.Y != nil {
+			positions[blockId] = [2]float64{*block.X, *block.Y}
+		}
+	}
+	s.IdTable.SetPositions(target.Name, positions)
+}
+
+// the top-level blocks of the scripts that have generated comments
+func (s *Scir) commentedScripts(target *Target) map[string]struct{} {
+	scripts := make(map[string]struct{})
+	for _, commentId := range s.IdTable.Comments[target.Name] {
+		comment, ok := target.Comments[commentId]
+		if !ok || comment.BlockId == nil {
+			continue
+		}
+		if rootId, ok := scriptOf(target, *comment.BlockId); ok {
+			scripts[rootId] = struct{}{}
+		}
+	}
+	return scripts
+}
+
+// puts the generated comments on the right of their scripts, in line with
+// the blocks they are attached to
+func (s *Scir) layoutComments(target *Target) {
+	offsets := make(map[string]float64)
+	for _, commentId := range s.IdTable.Comments[target.Name] {
+		comment, ok := target.Comments[commentId]
+		if !ok || comment.BlockId == nil {
+			continue
+		}
+		rootId, ok := scriptOf(target, *comment.BlockId)
+		if !ok {
+			continue
+		}
+		root := target.Blocks[rootId]
+		if root.X == nil || root.Y == nil {
+			continue
+		}
+		if _, ok := offsets[rootId]; !ok {
+			stackOffsets(target, rootId, 0, offsets)
+		}
+		width, _ := estimateStack(target, rootId)
+		comment.X = *root.X + width + layoutGap
+		comment.Y = *root.Y + offsets[*comment.BlockId]
+		target.Comments[commentId] = comment
+	}
+}
+
+// the top-level block of the script containing a block
+func scriptOf(target *Target, blockId string) (string, bool) {
+	for range len(target.Blocks) {
+		block, ok := target.Blocks[blockId]
+		if !ok {
+			return "", false
+		}
+		if block.Parent == nil {
+			return blockId, block.TopLevel
+		}
+		blockId = *block.Parent
+	}
+	// parents form a loop
+	return "", false
+}
+
+// records the vertical offset of each block of a stack, reporters share
+// the offset of the block they are in
+func stackOffsets(target *Target, blockId string, y float64, offsets map[string]float64) {
+	for {
+		block, ok := target.Blocks[blockId]
+		if !ok {
+			return
+		}
+		if _, ok := offsets[blockId]; ok {
+			return
+		}
+		offsets[blockId] = y
+		_, height := estimateBlock(target, block)
+		armY := y + layoutBlockHeight
+		for _, name := range sortedInputNames(block.Inputs) {
+			for _, inputId := range inputBlockIds(block.Inputs[name]) {
+				if slices.Contains(substackInputs, name) {
+					stackOffsets(target, inputId, armY, offsets)
+					_, stackHeight := estimateStack(target, inputId)
+					armY += stackHeight + layoutArmHeight
+				} else {
+					stackOffsets(target, inputId, y, offsets)
+				}
+			}
+		}
+		y += height
+		if block.Next == nil {
+			return
+		}
+		blockId = *block.Next
+	}
+}
+
+// the top-level blocks in the order they were generated, followed by the
+// others sorted by id
+func (s *Scir) topLevelBlockIds(target *Target) []string {
+	blockIds := make([]string, 0)
+	for _, blockId := range s.IdTable.Blocks[target.Name] {
+		if block, ok := target.Blocks[blockId]; ok && block.TopLevel {
+			blockIds = append(blockIds, blockId)
+		}
+	}
+	for _, blockId := range sortedBlockIds(target.Blocks) {
+		if target.Blocks[blockId].TopLevel && !slices.Contains(blockIds, blockId) {
+			blockIds = append(blockIds, blockId)
+		}
+	}
+	return blockIds
+}
+
+// the width and height of a stack of blocks linked by `next`
+func estimateStack(target *Target, blockId string) (float64, float64) {
+	var width, height float64 = 0, 0
+	for {
+		block, ok := target.Blocks[blockId]
+		if !ok {
+			break
+		}
+		blockWidth, blockHeight := estimateBlock(target, block)
+		width = max(width, blockWidth)
+		height += blockHeight
+		if block.Next == nil {
+			break
+		}
+		blockId = *block.Next
+	}
+	return width, height
+}
+
+func estimateBlock(target *Target, block *Block) (float64, float64) {
+	width := layoutPadding + layoutCharWidth*float64(len(block.Opcode))
+	height := layoutBlockHeight
+	if block.TopLevel && block.Parent == nil && isHat(block.Opcode) {
+		height = layoutHatHeight
+	}
+	for _, name := range sortedInputNames(block.Inputs) {
+		inputIds := inputBlockIds(block.Inputs[name])
+		if len(inputIds) == 0 {
+			width += layoutReporterHeight
+			continue
+		}
+		// an obscured input hides its shadow
+		inputId := inputIds[0]
+		if slices.Contains(substackInputs, name) {
+			stackWidth, stackHeight := estimateStack(target, inputId)
+			width = max(width, layoutPadding+stackWidth)
+			height += stackHeight + layoutArmHeight
+			continue
+		}
+		if inputBlock, ok := target.Blocks[inputId]; ok {
+			reporterWidth, reporterHeight := estimateBlock(target, inputBlock)
+			width += reporterWidth
+			height = max(height, reporterHeight+layoutPadding)
+		}
+	}
+	if !block.Shadow {
+		return max(width, layoutMinWidth), height
+	}
+	// shadows are drawn inside their parent
+	return max(width, layoutMinWidth), layoutReporterHeight
+}
+
+func isHat(opcode string) bool {
+	switch opcode {
+	case "procedures_definition",
+		"event_whenflagclicked",
+		"event_whenkeypressed",
+		"event_whenthisspriteclicked",
+		"event_whenstageclicked",
+		"event_whenbackdropswitchesto",
+		"event_whengreaterthan",
+		"event_whenbroadcastreceived",
+		"control_start_as_clone":
+		return true
+	}
+	return false
+}
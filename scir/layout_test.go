@@ -0,0 +1,74 @@
+package scir
+
+import "testing"
+
+func TestLayoutColumns(t *testing.T) {
+	project := NewProject()
+	project.SetEditingTarget("Stage")
+	target := project.EditingTarget
+	// a script of the artist, generated scripts go on its right
+	artistX, artistY := 100.0, 300.0
+	target.Blocks["artistBlock000000000"] = &Block{
+		Opcode:   "motion_movesteps",
+		Inputs:   make(map[string]MaybeShadowedInput),
+		Fields:   make(map[string]Field),
+		TopLevel: true,
+		X:        &artistX,
+		Y:        &artistY,
+	}
+	artistWidth, _ := estimateStack(target, "artistBlock000000000")
+	// scripts of ten blocks, a column holds a few of them
+	scripts := make([]string, 0)
+	for range 8 {
+		var previous string
+		for idx := range 10 {
+			blockId := project.InsertBlock(&Block{
+				Opcode: "looks_nextcostume",
+				Inputs: make(map[string]MaybeShadowedInput),
+				Fields: make(map[string]Field),
+			})
+			if idx == 0 {
+				target.Blocks[blockId].TopLevel = true
+				scripts = append(scripts, blockId)
+			} else {
+				project.ConnectBlocks(previous, blockId)
+			}
+			previous = blockId
+		}
+	}
+	project.LayoutScripts()
+
+	if x, y := *target.Blocks["artistBlock000000000"].X, *target.Blocks["artistBlock000000000"].Y; x != artistX || y != artistY {
+		t.Errorf("the script of the artist moved to (%g, %g)", x, y)
+	}
+	first := target.Blocks[scripts[0]]
+	if *first.X != artistX+artistWidth+layoutGap || *first.Y != 0 {
+		t.Errorf("expected the first script on the right of the artist's, found (%g, %g)", *first.X, *first.Y)
+	}
+	columns := 1
+	for idx := 1; idx < len(scripts); idx += 1 {
+		previous, block := target.Blocks[scripts[idx-1]], target.Blocks[scripts[idx]]
+		width, height := estimateStack(target, scripts[idx-1])
+		if *block.X == *previous.X {
+			if *block.Y != *previous.Y+height+layoutGap {
+				t.Errorf("script %d: expected y %g below the previous one, found %g", idx, *previous.Y+height+layoutGap, *block.Y)
+			}
+			continue
+		}
+		// a new column starts at the top, on the right of the previous one
+		columns += 1
+		if *block.Y != 0 || *block.X < *previous.X+width+layoutGap {
+			t.Errorf("script %d: expected a new column, found (%g, %g)", idx, *block.X, *block.Y)
+		}
+		if *previous.Y+height > layoutColumnHeight {
+			t.Errorf("script %d: the previous column grew to %g", idx, *previous.Y+height)
+		}
+	}
+	if columns < 2 {
+		t.Errorf("expected the scripts in several columns, found %d", columns)
+	}
+	positions := project.IdTable.Positions["Stage"]
+	if len(positions) != len(scripts) {
+		t.Errorf("expected the positions of the %d generated scripts kept, found %d", len(scripts), len(positions))
+	}
+}
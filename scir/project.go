@@ -0,0 +1,86 @@
+package scir
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+
+	"yummy-go.com/m/v2/idgen"
+)
+
+const (
+	ProjectSemver = "3.0.0"
+	ProjectVm     = "0.2.0"
+	ProjectAgent  = "yummy-go"
+)
+
+// an empty 480x360 backdrop, the same size as the stage
+const defaultBackdropSvg = `<svg version="1.1" width="480" height="360" viewBox="0 0 480 360" xmlns="http://www.w3.org/2000/svg"></svg>`
+
+func md5Hex(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func NewDefaultCostume(name string) (Costume, []byte) {
+	content := []byte(defaultBackdropSvg)
+	assetId := md5Hex(content)
+	return Costume{
+		AssetId:          assetId,
+		Name:             name,
+		Md5ext:           assetId + ".svg",
+		DataFormat:       "svg",
+		BitmapResolution: 1,
+		RotationCenterX:  240,
+		RotationCenterY:  180,
+	}, content
+}
+
+func NewStageTarget(costumes []Costume) Target {
+	var tempo float64 = 60
+	videoState := "on"
+	var videoTransparency float64 = 50
+	return Target{
+		IsStage:           true,
+		Name:              "Stage",
+		Variables:         make(map[string]Variable),
+		Lists:             make(map[string]List),
+		Broadcasts:        make(map[string]string),
+		Blocks:            make(map[string]*Block),
+		Comments:          make(map[string]Comment),
+		CurrentCostume:    0,
+		Costumes:          costumes,
+		Sounds:            make([]Sound, 0),
+		LayerOrder:        0,
+		Volume:            100,
+		Tempo:             &tempo,
+		VideoState:        &videoState,
+		VideoTransparency: &videoTransparency,
+	}
+}
+
+func NewProject() Scir {
+	backdrop, content := NewDefaultCostume("backdrop1")
+	project := Scir{
+		Assets: map[string][]byte{
+			backdrop.Md5ext: content,
+		},
+		Ir: Project{
+			Targets: []Target{
+				NewStageTarget([]Costume{backdrop}),
+			},
+			Monitors:   make([]Monitor, 0),
+			Extensions: make([]string, 0),
+			Meta: Meta{
+				Semver: ProjectSemver,
+				Vm:     ProjectVm,
+				Agent:  ProjectAgent,
+			},
+		},
+		IdTable:       NewIdTable(),
+		EditingTarget: nil,
+		ids:           idgen.New(""),
+		placeholders:  map[string]bool{"Stage": true},
+	}
+	project.StageTarget = &project.Ir.Targets[0]
+	return project
+}
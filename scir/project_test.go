@@ -0,0 +1,70 @@
+package scir_test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"yummy-go.com/m/v2/scir"
+)
+
+func TestNewProject(t *testing.T) {
+	project := scir.NewProject()
+	if len(project.Ir.Targets) != 1 || project.StageTarget != &project.Ir.Targets[0] {
+		t.Fatalf("expected the stage as the only target, found %d targets", len(project.Ir.Targets))
+	}
+	stage := project.StageTarget
+	if !stage.IsStage || stage.Name != "Stage" || stage.LayerOrder != 0 {
+		t.Errorf("unexpected stage `%s`, isStage %t, layer %g", stage.Name, stage.IsStage, stage.LayerOrder)
+	}
+	if len(stage.Costumes) != 1 {
+		t.Fatalf("expected a single backdrop, found %d", len(stage.Costumes))
+	}
+	backdrop := stage.Costumes[0]
+	if backdrop.Name != "backdrop1" || backdrop.DataFormat != "svg" || backdrop.RotationCenterX != 240 || backdrop.RotationCenterY != 180 {
+		t.Errorf("unexpected backdrop %+v", backdrop)
+	}
+	content, ok := project.Assets[backdrop.Md5ext]
+	if !ok {
+		t.Fatalf("missing asset `%s`", backdrop.Md5ext)
+	}
+	sum := md5.Sum(content)
+	if hex.EncodeToString(sum[:])+".svg" != backdrop.Md5ext || backdrop.AssetId+".svg" != backdrop.Md5ext {
+		t.Errorf("the backdrop is not named after the md5 of its content: %s", backdrop.Md5ext)
+	}
+	meta := project.Ir.Meta
+	if meta.Semver != scir.ProjectSemver || meta.Vm != scir.ProjectVm || meta.Agent != scir.ProjectAgent {
+		t.Errorf("unexpected meta %+v", meta)
+	}
+	if err := scir.Validate(&project); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewProjectRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "empty.sb3")
+	if err := scir.ExportSb3(outputPath, outputPath+".json", scir.NewProject()); err != nil {
+		t.Fatal(err)
+	}
+	project, err := scir.LoadSb3(outputPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.StageTarget == nil || len(project.StageTarget.Costumes) != 1 || len(project.Assets) != 1 {
+		t.Errorf("the stage and its backdrop did not survive the export")
+	}
+}
+
+// a full disk fails the writes of the zip, which must not pass for success
+func TestExportReportsWriteErrors(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full")
+	}
+	idTablePath := filepath.Join(t.TempDir(), "output.sb3.json")
+	if err := scir.ExportSb3("/dev/full", idTablePath, scir.NewProject()); err == nil {
+		t.Fatal("expected the export to fail")
+	}
+}
@@ -0,0 +1,112 @@
+package scir
+
+// removes the blocks generated by the previous build, which are tracked by
+// the IdTable. everything else is kept, blocks attached to removed ones are
+// detached and become scripts on their own. the generated scripts may have
+// been moved in the editor, so their positions are kept for the layout.
+// the generated variables and lists keep their values until the build is
+// done, CollectGarbage removes the ones it did not declare again
+func (s *Scir) RemoveGeneratedBlocks() {
+	for idx := range s.Ir.Targets {
+		target := &s.Ir.Targets[idx]
+		removed := make(map[string]*Block)
+		positions := make(map[string][2]float64)
+		for _, blockId := range s.IdTable.Blocks[target.Name] {
+			if block, ok := target.Blocks[blockId]; ok {
+				removed[blockId] = block
+				delete(target.Blocks, blockId)
+				if block.TopLevel && block.X != nil && block.Y != nil {
+					positions[blockId] = [2]float64{*block.X, *block.Y}
+				}
+			}
+		}
+		if len(positions) > 0 {
+			s.IdTable.SetPositions(target.Name, positions)
+		}
+		removedComments := make(map[string]struct{})
+		for _, commentId := range s.IdTable.Comments[target.Name] {
+			delete(target.Comments, commentId)
+			removedComments[commentId] = struct{}{}
+		}
+		for _, block := range target.Blocks {
+			if block.Comment == nil {
+				continue
+			}
+			if _, ok := removedComments[*block.Comment]; ok {
+				block.Comment = nil
+			}
+		}
+		if len(removed) == 0 {
+			continue
+		}
+		for _, block := range target.Blocks {
+			detachRemoved(block, removed)
+		}
+		for commentId, comment := range target.Comments {
+			if comment.BlockId == nil {
+				continue
+			}
+			if _, ok := removed[*comment.BlockId]; ok {
+				comment.BlockId = nil
+				target.Comments[commentId] = comment
+			}
+		}
+	}
+	s.IdTable.Blocks = make(map[string][]string)
+	s.IdTable.Comments = make(map[string][]string)
+	s.previousVariables = s.IdTable.Variables
+	s.previousLists = s.IdTable.Lists
+	s.IdTable.Variables = make(map[string][]string)
+	s.IdTable.Lists = make(map[string][]string)
+}
+
+func detachRemoved(block *Block, removed map[string]*Block) {
+	if block.Next != nil {
+		if _, ok := removed[*block.Next]; ok {
+			block.Next = nil
+		}
+	}
+	for name, input := range block.Inputs {
+		if isRemovedInput(input.ObscuredInput, removed) {
+			input.ObscuredInput = nil
+		}
+		if isRemovedInput(input.ShadowedInput, removed) {
+			input.ShadowedInput = nil
+		}
+		switch {
+		case input.ObscuredInput == nil && input.ShadowedInput == nil:
+			delete(block.Inputs, name)
+			continue
+		case input.ObscuredInput == nil:
+			// the shadow shows up again once the obscuring block is gone
+			input.Type = Shadow
+		case input.ShadowedInput == nil:
+			input.Type = Nonshadow
+		}
+		block.Inputs[name] = input
+	}
+	if block.Parent == nil {
+		return
+	}
+	parent, ok := removed[*block.Parent]
+	if !ok {
+		return
+	}
+	var x, y float64 = 0, 0
+	if parent.X != nil && parent.Y != nil {
+		x, y = *parent.X, *parent.Y
+	}
+	block.Parent = nil
+	block.TopLevel = true
+	block.X = &x
+	block.Y = &y
+}
+
+func isRemovedInput(input Input, removed map[string]*Block) bool {
+	blockInput, ok := input.(*BlockInput)
+	if !ok || blockInput == nil {
+		return false
+	}
+	_, ok = removed[string(*blockInput)]
+	return ok
+}
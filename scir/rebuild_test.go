@@ -0,0 +1,206 @@
+package scir_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/scir"
+)
+
+const counting = `global counter: number @0 slots(0 "counterSlot000000000")
+
+func count() -> number @0 slots(1 "countReturn000000000") stack 0 proccode "count" {
+    var value: number @0
+    value = (counter + 1)
+    counter = value
+    return value
+}
+`
+
+// the same program, with the global and the function renamed
+const tallying = `global total: number @0 slots(0 "counterSlot000000000")
+
+func tally() -> number @0 slots(1 "countReturn000000000") stack 0 proccode "tally" {
+    var value: number @0
+    value = (total + 1)
+    total = value
+    return value
+}
+`
+
+func parseSource(t *testing.T, path, source string) mir.Program {
+	t.Helper()
+	program, err := mir.Parse(path, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func variableNames(target *scir.Target) map[string]bool {
+	names := make(map[string]bool)
+	for _, variable := range target.Variables {
+		names[variable.Name] = true
+	}
+	return names
+}
+
+// a script, a comment, a variable and a costume of the artist
+func addArtistWork(t *testing.T, project *scir.Scir) {
+	t.Helper()
+	stage := project.StageTarget
+	stage.Variables["artistVariable000000"] = scir.Variable{Name: "lives", Value: "3"}
+	var counterId string
+	for id, variable := range stage.Variables {
+		if variable.Name == "counter" {
+			counterId = id
+		}
+	}
+	if counterId == "" {
+		t.Fatal("the global was not declared")
+	}
+	stage.Blocks["artistHat00000000000"] = &scir.Block{
+		Opcode:   "event_whenflagclicked",
+		Inputs:   map[string]scir.MaybeShadowedInput{},
+		Fields:   map[string]scir.Field{},
+		Next:     pointer("artistSay00000000000"),
+		TopLevel: true,
+		X:        pointer(-300.0),
+		Y:        pointer(40.0),
+		Comment:  pointer("artistComment0000000"),
+	}
+	// the artist shows the generated global, it outlives the rename
+	stage.Blocks["artistSay00000000000"] = &scir.Block{
+		Opcode: "looks_say",
+		Inputs: map[string]scir.MaybeShadowedInput{
+			"MESSAGE": {
+				Type:          scir.Shadowed,
+				ObscuredInput: &scir.VariableOrListInput{Type: scir.InputVariable, Value: "counter", Id: counterId},
+				ShadowedInput: &scir.StringInput{Type: scir.InputString, Value: "hello"},
+			},
+		},
+		Fields: map[string]scir.Field{},
+		Parent: pointer("artistHat00000000000"),
+	}
+	stage.Comments["artistComment0000000"] = scir.Comment{BlockId: pointer("artistHat00000000000"), Text: "keep me", Width: 200, Height: 100}
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="2" height="2"></svg>`)
+	costume, err := scir.NewCostume("night", svg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project.AddCostume(stage, costume, svg)
+}
+
+func artistWork(t *testing.T, stage *scir.Target) string {
+	t.Helper()
+	content, err := json.Marshal([]any{
+		stage.Blocks["artistHat00000000000"],
+		stage.Blocks["artistSay00000000000"],
+		stage.Comments["artistComment0000000"],
+		stage.Variables["artistVariable000000"],
+		stage.Costumes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestRebuildKeepsArtistWork(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "output.sb3")
+	build(t, scir.NewProject(), parseSource(t, "counting.mir", counting), outputPath)
+	idTablePath := outputPath + ".json"
+	project, err := scir.LoadSb3(outputPath, &idTablePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addArtistWork(t, &project)
+	expected := artistWork(t, project.StageTarget)
+	build(t, project, parseSource(t, "tallying.mir", tallying), outputPath)
+
+	rebuilt, err := scir.LoadSb3(outputPath, &idTablePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage := rebuilt.StageTarget
+	if actual := artistWork(t, stage); actual != expected {
+		t.Errorf("the work of the artist changed:\n%s\nexpected:\n%s", actual, expected)
+	}
+	names := variableNames(stage)
+	for _, name := range []string{"total", "tally.ret0", "tally.local0", "lives", "counter"} {
+		if !names[name] {
+			t.Errorf("missing variable `%s`", name)
+		}
+	}
+	for _, name := range []string{"count.ret0", "count.local0"} {
+		if names[name] {
+			t.Errorf("the stale variable `%s` was kept", name)
+		}
+	}
+	if len(stage.Lists) != 1 {
+		t.Errorf("expected `_Stack` as the only list, found %d lists", len(stage.Lists))
+	}
+	if err := scir.Validate(&rebuilt); err != nil {
+		t.Error(err)
+	}
+
+	// once the artist stops showing it, the old global goes too
+	delete(stage.Blocks, "artistSay00000000000")
+	stage.Blocks["artistHat00000000000"].Next = nil
+	build(t, rebuilt, parseSource(t, "tallying.mir", tallying), outputPath)
+	again, err := scir.LoadSb3(outputPath, &idTablePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variableNames(again.StageTarget)["counter"] {
+		t.Error("the stale global was kept once nothing refers to it")
+	}
+}
+
+// a generated script the artist moved in the editor stays where they put it
+func TestRebuildKeepsMovedScripts(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "output.sb3")
+	idTablePath := outputPath + ".json"
+	build(t, scir.NewProject(), parse(t), outputPath)
+	edited, err := scir.LoadSb3(outputPath, &idTablePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scriptId string
+	for _, blockId := range edited.StageTarget.BlockIds() {
+		if edited.StageTarget.Blocks[blockId].TopLevel {
+			scriptId = blockId
+			break
+		}
+	}
+	if scriptId == "" {
+		t.Fatal("no generated script")
+	}
+	moved := edited.StageTarget.Blocks[scriptId]
+	moved.X, moved.Y = pointer(-420.0), pointer(640.0)
+	// saved by the editor
+	if err := scir.ExportSb3(outputPath, idTablePath, edited); err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := scir.LoadSb3(outputPath, &idTablePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build(t, project, parse(t), outputPath)
+	rebuilt, err := scir.LoadSb3(outputPath, &idTablePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, ok := rebuilt.StageTarget.Blocks[scriptId]
+	if !ok {
+		t.Fatalf("the script `%s` was not generated again", scriptId)
+	}
+	if block.X == nil || block.Y == nil || *block.X != -420 || *block.Y != 640 {
+		t.Errorf("expected the script at (-420, 640), found (%v, %v)", block.X, block.Y)
+	}
+}
@@ -6,41 +6,92 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"time"
 
-	"github.com/google/uuid"
+	"yummy-go.com/m/v2/idgen"
+	"yummy-go.com/m/v2/span"
 )
 
+// zip entries are dated to the dos epoch so builds are reproducible
+var zipModified = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 type Scir struct {
 	Assets        map[string][]byte
 	Ir            Project
 	IdTable       IdTable
 	EditingTarget *Target
 	StageTarget   *Target
+	ids           idgen.Generator
+	idScope       string
+	sourceSpan    *span.Span
+	sourceSpans   map[string]map[string]span.Span
+	// the targets whose costumes were made up rather than declared, by name
+	placeholders map[string]bool
+	// the variables and lists of the previous build, by target name, the
+	// ones the build does not declare again are removed by CollectGarbage
+	previousVariables map[string][]string
+	previousLists     map[string][]string
+}
+
+// block ids are derived from the editing target, the id scope and the
+// order of insertion within the scope
+func (s *Scir) SetIdScope(scope string) {
+	s.idScope = scope
+}
+
+func (s *Scir) NewBlockId() string {
+	return s.ids.Next(s.EditingTarget.Name, s.idScope)
 }
 
 func (s *Scir) SetEditingTarget(name string) {
-	for _, target := range s.Ir.Targets {
-		if target.Name == name {
-			s.EditingTarget = &target
+	for idx := range s.Ir.Targets {
+		if s.Ir.Targets[idx].Name == name {
+			s.EditingTarget = &s.Ir.Targets[idx]
 			return
 		}
 	}
-	newTarget := NewTarget(name, []Costume{
+	var costume Costume
+	if len(s.StageTarget.Costumes) > 0 {
 		// use the first costume of stage by default
-		s.StageTarget.Costumes[0],
-	})
+		costume = s.StageTarget.Costumes[0]
+	} else {
+		var content []byte
+		costume, content = NewDefaultCostume("costume1")
+		s.Assets[costume.Md5ext] = content
+	}
+	newTarget := NewTarget(name, []Costume{costume})
+	if s.placeholders == nil {
+		s.placeholders = make(map[string]bool)
+	}
+	s.placeholders[name] = true
+	// the stage always takes the bottom layer
+	newTarget.LayerOrder = float64(len(s.Ir.Targets))
 	s.Ir.Targets = append(s.Ir.Targets, newTarget)
-	s.EditingTarget = &newTarget
+	s.refreshStageTarget()
+	s.EditingTarget = &s.Ir.Targets[len(s.Ir.Targets)-1]
+}
+
+// appending to Targets may move them, so the pointers must be taken again
+func (s *Scir) refreshStageTarget() {
+	for idx := range s.Ir.Targets {
+		if s.Ir.Targets[idx].IsStage {
+			s.StageTarget = &s.Ir.Targets[idx]
+			return
+		}
+	}
 }
 
 func (s *Scir) InsertBlock(block *Block) string {
-	blockUuid := uuid.NewString()
-	s.EditingTarget.Blocks[blockUuid] = block
+	blockUuid := s.NewBlockId()
+	s.InsertBlockWithUuid(blockUuid, block)
 	return blockUuid
 }
 
 func (s *Scir) InsertBlockWithUuid(uuid string, block *Block) {
-	s.EditingTarget.Blocks[uuid] = block
+	s.EditingTarget.insertBlock(uuid, block)
+	s.IdTable.TrackBlock(s.EditingTarget.Name, uuid)
+	s.mapBlock(uuid)
 }
 
 func (s *Scir) ConnectBlocks(blockUuid, nextBlockUuid string) {
@@ -48,30 +99,108 @@ func (s *Scir) ConnectBlocks(blockUuid, nextBlockUuid string) {
 	s.EditingTarget.Blocks[nextBlockUuid].Parent = &blockUuid
 }
 
+// deep-copies the subtrees rooted at the blocks, including the stacks in
+// their inputs. the copied roots have no parent nor next block
 func (s *Scir) CopyBlocks(blockUuids []string) []string {
 	newBlockUuids := make([]string, 0)
 	for _, uuid := range blockUuids {
-		block := s.EditingTarget.Blocks[uuid]
-		newBlockUuid := s.InsertBlock(block)
-		newBlockUuids = append(newBlockUuids, newBlockUuid)
+		newBlockUuids = append(newBlockUuids, s.copyBlock(uuid, nil))
 	}
 	return newBlockUuids
 }
 
+func (s *Scir) copyBlock(blockUuid string, parentUuid *string) string {
+	block := s.EditingTarget.Blocks[blockUuid]
+	copied := *block
+	copied.Parent = parentUuid
+	copied.Next = nil
+	copied.TopLevel = false
+	copied.X = nil
+	copied.Y = nil
+	// a comment is attached to one block, the copy goes without it
+	copied.Comment = nil
+	copied.Fields = make(map[string]Field, len(block.Fields))
+	for name, field := range block.Fields {
+		copied.Fields[name] = field
+	}
+	if block.Mutation != nil {
+		mutation := *block.Mutation
+		copied.Mutation = &mutation
+	}
+	copied.Inputs = make(map[string]MaybeShadowedInput, len(block.Inputs))
+	copiedUuid := s.InsertBlock(&copied)
+	// sorted so the ids of the copies are stable
+	for _, name := range sortedInputNames(block.Inputs) {
+		input := block.Inputs[name]
+		copied.Inputs[name] = MaybeShadowedInput{
+			Type:          input.Type,
+			ObscuredInput: s.copyInput(input.ObscuredInput, copiedUuid),
+			ShadowedInput: s.copyInput(input.ShadowedInput, copiedUuid),
+		}
+	}
+	return copiedUuid
+}
+
+// copies a stack of blocks linked by `next`
+func (s *Scir) copyStack(blockUuid string, parentUuid string) string {
+	firstUuid := s.copyBlock(blockUuid, &parentUuid)
+	previousUuid := firstUuid
+	next := s.EditingTarget.Blocks[blockUuid].Next
+	for next != nil {
+		copiedUuid := s.copyBlock(*next, nil)
+		s.ConnectBlocks(previousUuid, copiedUuid)
+		previousUuid = copiedUuid
+		next = s.EditingTarget.Blocks[*next].Next
+	}
+	return firstUuid
+}
+
+func (s *Scir) copyInput(input Input, parentUuid string) Input {
+	switch input := input.(type) {
+	case *BlockInput:
+		if input == nil {
+			return nil
+		}
+		copiedInput := BlockInput(s.copyStack(string(*input), parentUuid))
+		return &copiedInput
+	case *NumberalInput:
+		copiedInput := *input
+		return &copiedInput
+	case *StringInput:
+		copiedInput := *input
+		return &copiedInput
+	case *BroadcastInput:
+		copiedInput := *input
+		return &copiedInput
+	case *VariableOrListInput:
+		copiedInput := *input
+		return &copiedInput
+	}
+	return input
+}
+
 func (s *Scir) SetInput(blockUUid, input string, inputBlock *Block) {
-	inputUuid := s.InsertBlock(inputBlock)
+	s.LinkInput(blockUUid, input, s.InsertBlock(inputBlock))
+}
+
+func (s *Scir) SetShadowInput(blockUUid, input string, inputBlock *Block) {
+	s.LinkShadowInput(blockUUid, input, s.InsertBlock(inputBlock))
+}
+
+// puts an inserted block into an input of another block
+func (s *Scir) LinkInput(blockUUid, input string, inputUuid string) {
 	blockInput := BlockInput(inputUuid)
-	inputBlock.Parent = &blockUUid
+	s.EditingTarget.Blocks[inputUuid].Parent = &blockUUid
 	s.EditingTarget.Blocks[blockUUid].Inputs[input] = MaybeShadowedInput{
 		Type:          Nonshadow,
 		ObscuredInput: &blockInput,
 	}
 }
 
-func (s *Scir) SetShadowInput(blockUUid, input string, inputBlock *Block) {
-	inputUuid := s.InsertBlock(inputBlock)
+// puts an inserted block into an input of another block as its shadow
+func (s *Scir) LinkShadowInput(blockUUid, input string, inputUuid string) {
 	blockInput := BlockInput(inputUuid)
-	inputBlock.Parent = &blockUUid
+	s.EditingTarget.Blocks[inputUuid].Parent = &blockUUid
 	s.EditingTarget.Blocks[blockUUid].Inputs[input] = MaybeShadowedInput{
 		Type:          Shadow,
 		ShadowedInput: &blockInput,
@@ -118,46 +247,72 @@ func LoadSb3(path string, idTablePath *string) (Scir, error) {
 			idTable = theIdTable
 		}
 	}
-	for _, target := range ir.Targets {
-		if target.IsStage {
-			return Scir{
-				Assets:        assets,
-				Ir:            *ir,
-				IdTable:       idTable,
-				EditingTarget: nil,
-				StageTarget:   &target,
-			}, nil
-		}
+	if ir == nil {
+		return Scir{}, fmt.Errorf("%s: missing `project.json`", path)
+	}
+	sb3 := Scir{
+		Assets:        assets,
+		Ir:            *ir,
+		IdTable:       idTable,
+		EditingTarget: nil,
+		ids:           idgen.New(""),
+	}
+	sb3.refreshStageTarget()
+	if sb3.StageTarget == nil {
+		return Scir{}, fmt.Errorf("project.json: missing target `stage`")
 	}
-	return Scir{}, fmt.Errorf("project.json: missing target `stage`")
+	return sb3, nil
 }
 
 func ExportSb3(path, idTablePath string, sb3 Scir) error {
-	idTableFile, err := os.Create(idTablePath)
-	if err != nil {
+	if err := Validate(&sb3); err != nil {
 		return err
 	}
-	defer idTableFile.Close()
 	idTableContent, err := json.Marshal(sb3.IdTable)
 	if err != nil {
 		return err
 	}
-	idTableFile.Write(idTableContent)
+	if err := os.WriteFile(idTablePath, idTableContent, 0o644); err != nil {
+		return err
+	}
+	sourceMapPath := SourceMapPath(idTablePath)
+	sourceMapContent, err := json.Marshal(sb3.SourceMap(sourceMapPath))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(sourceMapPath, sourceMapContent, 0o644); err != nil {
+		return err
+	}
 	zipFile, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-	defer zipFile.Close()
-	writer := zip.NewWriter(zipFile)
-	defer writer.Close()
-	for assetName, asset := range sb3.Assets {
-		assetFile, err := writer.Create(assetName)
+	if err := writeSb3(zipFile, sb3); err != nil {
+		zipFile.Close()
+		return err
+	}
+	// a file that is not closed cleanly may be truncated
+	return zipFile.Close()
+}
+
+// the assets in the order of their names, then `project.json`
+func writeSb3(w io.Writer, sb3 Scir) error {
+	writer := zip.NewWriter(w)
+	assetNames := make([]string, 0, len(sb3.Assets))
+	for assetName := range sb3.Assets {
+		assetNames = append(assetNames, assetName)
+	}
+	slices.Sort(assetNames)
+	for _, assetName := range assetNames {
+		assetFile, err := createZipEntry(writer, assetName)
 		if err != nil {
 			return err
 		}
-		assetFile.Write(asset)
+		if _, err := assetFile.Write(sb3.Assets[assetName]); err != nil {
+			return err
+		}
 	}
-	projectJsonFile, err := writer.Create("project.json")
+	projectJsonFile, err := createZipEntry(writer, "project.json")
 	if err != nil {
 		return err
 	}
@@ -165,6 +320,16 @@ func ExportSb3(path, idTablePath string, sb3 Scir) error {
 	if err != nil {
 		return err
 	}
-	projectJsonFile.Write(jsonContent)
-	return nil
+	if _, err := projectJsonFile.Write(jsonContent); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func createZipEntry(writer *zip.Writer, name string) (io.Writer, error) {
+	return writer.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: zipModified,
+	})
 }
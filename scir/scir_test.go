@@ -0,0 +1,192 @@
+package scir_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"yummy-go.com/m/v2/mir"
+	"yummy-go.com/m/v2/omitter"
+	"yummy-go.com/m/v2/scir"
+)
+
+// builds the program into the project and exports it, returning the
+// contents of the written files
+func build(t *testing.T, project scir.Scir, program mir.Program, outputPath string) map[string][]byte {
+	t.Helper()
+	project.RemoveGeneratedBlocks()
+	theOmitter := omitter.New(&project)
+	theOmitter.SetTarget("Stage")
+	if err := theOmitter.Omit(program); err != nil {
+		t.Fatal(err)
+	}
+	idTablePath := outputPath + ".json"
+	if err := scir.ExportSb3(outputPath, idTablePath, project); err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	for _, path := range []string{outputPath, idTablePath, scir.SourceMapPath(idTablePath)} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[filepath.Ext(path)] = content
+	}
+	return files
+}
+
+func parse(t *testing.T) mir.Program {
+	t.Helper()
+	program, err := mir.ParseFile("../examples/hello.mir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func expectIdentical(t *testing.T, lhs, rhs map[string][]byte) {
+	t.Helper()
+	for ext, content := range lhs {
+		if !bytes.Equal(content, rhs[ext]) {
+			t.Errorf("the %s files differ", ext)
+		}
+	}
+}
+
+func TestBuildIsDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	first := build(t, scir.NewProject(), parse(t), filepath.Join(dir, "first.sb3"))
+	second := build(t, scir.NewProject(), parse(t), filepath.Join(dir, "second.sb3"))
+	expectIdentical(t, first, second)
+}
+
+func TestRebuildIsIdentical(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "output.sb3")
+	first := build(t, scir.NewProject(), parse(t), outputPath)
+	// like `yummy build -into`, the previous build is loaded with its id table
+	idTablePath := outputPath + ".json"
+	project, err := scir.LoadSb3(outputPath, &idTablePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := build(t, project, parse(t), outputPath)
+	expectIdentical(t, first, second)
+}
+
+// the block and everything it reaches through `next` and inputs, in order
+func reachable(target *scir.Target, blockId string) []string {
+	ids := []string{blockId}
+	block := target.Blocks[blockId]
+	names := make([]string, 0, len(block.Inputs))
+	for name := range block.Inputs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		input := block.Inputs[name]
+		for _, input := range []scir.Input{input.ObscuredInput, input.ShadowedInput} {
+			if blockInput, ok := input.(*scir.BlockInput); ok && blockInput != nil {
+				ids = append(ids, reachable(target, string(*blockInput))...)
+			}
+		}
+	}
+	if block.Next != nil {
+		ids = append(ids, reachable(target, *block.Next)...)
+	}
+	return ids
+}
+
+func TestCopyBlocks(t *testing.T) {
+	project := scir.NewProject()
+	project.SetEditingTarget("Stage")
+	newBlock := func(opcode string, fields map[string]scir.Field) *scir.Block {
+		return &scir.Block{Opcode: opcode, Inputs: map[string]scir.MaybeShadowedInput{}, Fields: fields}
+	}
+	number := func(value string) string {
+		shadow := newBlock("math_number", map[string]scir.Field{"NUM": {Value: value}})
+		shadow.Shadow = true
+		return project.InsertBlock(shadow)
+	}
+	// if ((1 + (2 * 3)) < 4) { say (score) ; think "hm" }, the operands
+	// obscuring their shadows
+	ifId := project.InsertBlock(newBlock("control_if", map[string]scir.Field{}))
+	ltId := project.InsertBlock(newBlock("operator_lt", map[string]scir.Field{}))
+	addId := project.InsertBlock(newBlock("operator_add", map[string]scir.Field{}))
+	mulId := project.InsertBlock(newBlock("operator_multiply", map[string]scir.Field{}))
+	project.LinkShadowInput(mulId, "NUM1", number("2"))
+	project.LinkShadowInput(mulId, "NUM2", number("3"))
+	project.LinkShadowInput(addId, "NUM1", number("1"))
+	project.LinkInput(addId, "NUM2", mulId)
+	shadowId := number("0")
+	project.EditingTarget.Blocks[shadowId].Parent = &addId
+	addInput := project.EditingTarget.Blocks[addId].Inputs["NUM2"]
+	addInput.Type, addInput.ShadowedInput = scir.Shadowed, pointer(scir.BlockInput(shadowId))
+	project.EditingTarget.Blocks[addId].Inputs["NUM2"] = addInput
+	project.LinkInput(ltId, "OPERAND1", addId)
+	project.LinkShadowInput(ltId, "OPERAND2", number("4"))
+	project.LinkInput(ifId, "CONDITION", ltId)
+	sayId := project.InsertBlock(newBlock("looks_say", map[string]scir.Field{}))
+	project.LinkInput(sayId, "MESSAGE", project.InsertBlock(newBlock("data_variable", map[string]scir.Field{"VARIABLE": {Value: "score", Id: pointer("scoreId")}})))
+	thinkId := project.InsertBlock(newBlock("looks_think", map[string]scir.Field{}))
+	project.EditingTarget.Blocks[thinkId].Inputs["MESSAGE"] = scir.MaybeShadowedInput{Type: scir.Shadow, ShadowedInput: &scir.StringInput{Type: scir.InputString, Value: "hm"}}
+	project.ConnectBlocks(sayId, thinkId)
+	project.LinkInput(ifId, "SUBSTACK", sayId)
+
+	target := project.EditingTarget
+	original := reachable(target, ifId)
+	copiedId := project.CopyBlocks([]string{ifId})[0]
+	copied := reachable(target, copiedId)
+	if len(copied) != len(original) {
+		t.Fatalf("expected %d blocks in the copy, found %d", len(original), len(copied))
+	}
+	inCopy := make(map[string]bool)
+	for _, id := range copied {
+		inCopy[id] = true
+	}
+	root := target.Blocks[copiedId]
+	if root.Parent != nil || root.Next != nil {
+		t.Error("the copied root has a parent or a next block")
+	}
+	for idx, id := range copied {
+		originalBlock, block := target.Blocks[original[idx]], target.Blocks[id]
+		if slices.Contains(original, id) || block == originalBlock {
+			t.Fatalf("the copy shares block `%s` with the original", id)
+		}
+		if block.Opcode != originalBlock.Opcode || block.Shadow != originalBlock.Shadow {
+			t.Errorf("block %d: expected %s (shadow %t), found %s (shadow %t)", idx, originalBlock.Opcode, originalBlock.Shadow, block.Opcode, block.Shadow)
+		}
+		if id != copiedId && (block.Parent == nil || !inCopy[*block.Parent]) {
+			t.Errorf("the parent of the copied `%s` points out of the copy", block.Opcode)
+		}
+		if block.Next != nil && !inCopy[*block.Next] {
+			t.Errorf("the next of the copied `%s` points out of the copy", block.Opcode)
+		}
+		for name, input := range block.Inputs {
+			for _, input := range []scir.Input{input.ObscuredInput, input.ShadowedInput} {
+				if blockInput, ok := input.(*scir.BlockInput); ok && blockInput != nil && !inCopy[string(*blockInput)] {
+					t.Errorf("input `%s` of the copied `%s` points out of the copy", name, block.Opcode)
+				}
+			}
+		}
+	}
+	// the literal inputs are values of their own
+	copiedThink := target.Blocks[copied[len(copied)-1]]
+	copiedThink.Inputs["MESSAGE"].ShadowedInput.(*scir.StringInput).Value = "changed"
+	if value := target.Blocks[thinkId].Inputs["MESSAGE"].ShadowedInput.(*scir.StringInput).Value; value != "hm" {
+		t.Errorf("changing the copy changed the original to %q", value)
+	}
+	// both scripts are whole, no block is referred to twice
+	for _, id := range []string{ifId, copiedId} {
+		target.Blocks[id].TopLevel, target.Blocks[id].X, target.Blocks[id].Y = true, pointer(0.0), pointer(0.0)
+	}
+	if err := project.CollectGarbage(); err != nil {
+		t.Error(err)
+	}
+	target.Variables["scoreId"] = scir.Variable{Name: "score", Value: "0"}
+	if err := scir.Validate(&project); err != nil {
+		t.Error(err)
+	}
+}
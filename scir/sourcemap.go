@@ -0,0 +1,112 @@
+package scir
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"yummy-go.com/m/v2/span"
+)
+
+// where a block comes from in the source. the path is relative to the
+// source map file
+type SourceLocation struct {
+	Path string
+	From span.Position
+	To   span.Position
+}
+
+type SourceMap struct {
+	// the locations of the generated blocks, by target name and block id
+	Blocks map[string]map[string]SourceLocation
+}
+
+// the sidecar of the IdTable at `idTablePath`
+func SourceMapPath(idTablePath string) string {
+	return strings.TrimSuffix(idTablePath, ".json") + ".yummymap.json"
+}
+
+// the blocks inserted from now on are mapped to `theSpan`, nil stops the
+// mapping
+func (s *Scir) SetSourceSpan(theSpan *span.Span) {
+	s.sourceSpan = theSpan
+}
+
+func (s *Scir) SourceSpan() *span.Span {
+	return s.sourceSpan
+}
+
+func (s *Scir) mapBlock(blockUuid string) {
+	if s.sourceSpan == nil || s.sourceSpan.Path == nil {
+		return
+	}
+	if s.sourceSpans == nil {
+		s.sourceSpans = make(map[string]map[string]span.Span)
+	}
+	target := s.EditingTarget.Name
+	if s.sourceSpans[target] == nil {
+		s.sourceSpans[target] = make(map[string]span.Span)
+	}
+	s.sourceSpans[target][blockUuid] = *s.sourceSpan
+}
+
+// the source map of the blocks that are still in the project
+func (s *Scir) SourceMap(sourceMapPath string) SourceMap {
+	sourceMap := SourceMap{
+		Blocks: make(map[string]map[string]SourceLocation),
+	}
+	baseDir, err := filepath.Abs(filepath.Dir(sourceMapPath))
+	if err != nil {
+		baseDir = filepath.Dir(sourceMapPath)
+	}
+	for idx := range s.Ir.Targets {
+		target := &s.Ir.Targets[idx]
+		locations := make(map[string]SourceLocation)
+		for blockUuid, theSpan := range s.sourceSpans[target.Name] {
+			if _, ok := target.Blocks[blockUuid]; !ok {
+				continue
+			}
+			path := *theSpan.Path
+			if absPath, err := filepath.Abs(path); err == nil {
+				if relPath, err := filepath.Rel(baseDir, absPath); err == nil {
+					path = relPath
+				}
+			}
+			locations[blockUuid] = SourceLocation{
+				Path: filepath.ToSlash(path),
+				From: theSpan.From,
+				To:   theSpan.To,
+			}
+		}
+		if len(locations) > 0 {
+			sourceMap.Blocks[target.Name] = locations
+		}
+	}
+	return sourceMap
+}
+
+func OpenSourceMap(path string) (SourceMap, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return SourceMap{}, err
+	}
+	var sourceMap SourceMap
+	if err := json.Unmarshal(content, &sourceMap); err != nil {
+		return SourceMap{}, err
+	}
+	if sourceMap.Blocks == nil {
+		sourceMap.Blocks = make(map[string]map[string]SourceLocation)
+	}
+	return sourceMap, nil
+}
+
+// finds a block in any target, returns the name of the target too
+func (s *SourceMap) Lookup(blockUuid string) (string, *SourceLocation) {
+	for target, locations := range s.Blocks {
+		if location, ok := locations[blockUuid]; ok {
+			return target, &location
+		}
+	}
+	return "", nil
+}
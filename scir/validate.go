@@ -0,0 +1,267 @@
+package scir
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+type ValidationError struct {
+	Target  string
+	BlockId string
+	Message string
+}
+
+func (s *ValidationError) Error() string {
+	if s.BlockId == "" {
+		return fmt.Sprintf("target `%s`: %s", s.Target, s.Message)
+	}
+	return fmt.Sprintf("target `%s`: block `%s`: %s", s.Target, s.BlockId, s.Message)
+}
+
+type validator struct {
+	sb3    *Scir
+	target *Target
+	errs   []error
+}
+
+func (s *validator) report(blockId string, message string, args ...any) {
+	s.errs = append(s.errs, &ValidationError{
+		Target:  s.target.Name,
+		BlockId: blockId,
+		Message: fmt.Sprintf(message, args...),
+	})
+}
+
+// checks the structure of the project before handing it to Scratch,
+// all problems found are joined into the returned error
+func Validate(sb3 *Scir) error {
+	v := validator{sb3: sb3}
+	for idx := range sb3.Ir.Targets {
+		v.target = &sb3.Ir.Targets[idx]
+		v.validateTarget()
+	}
+	return errors.Join(v.errs...)
+}
+
+func sortedBlockIds(blocks map[string]*Block) []string {
+	blockIds := make([]string, 0, len(blocks))
+	for blockId := range blocks {
+		blockIds = append(blockIds, blockId)
+	}
+	slices.Sort(blockIds)
+	return blockIds
+}
+
+func (s *validator) validateTarget() {
+	prototypes := make(map[string]*Block)
+	for _, block := range s.target.Blocks {
+		if block.Opcode == "procedures_prototype" && block.Mutation != nil && block.Mutation.ProcCode != nil {
+			prototypes[*block.Mutation.ProcCode] = block
+		}
+	}
+	for _, blockId := range sortedBlockIds(s.target.Blocks) {
+		block := s.target.Blocks[blockId]
+		s.validateLinks(blockId, block)
+		s.validateFields(blockId, block)
+		if block.Opcode == "procedures_call" {
+			s.validateCall(blockId, block, prototypes)
+		}
+	}
+	s.validateComments()
+	for _, costume := range s.target.Costumes {
+		if _, ok := s.sb3.Assets[costume.Md5ext]; !ok {
+			s.report("", "costume `%s` refers to missing asset `%s`", costume.Name, costume.Md5ext)
+		}
+	}
+	for _, sound := range s.target.Sounds {
+		if _, ok := s.sb3.Assets[sound.Md5ext]; !ok {
+			s.report("", "sound `%s` refers to missing asset `%s`", sound.Name, sound.Md5ext)
+		}
+	}
+}
+
+// a comment and its block refer to each other
+func (s *validator) validateComments() {
+	for _, blockId := range sortedBlockIds(s.target.Blocks) {
+		block := s.target.Blocks[blockId]
+		if block.Comment == nil {
+			continue
+		}
+		comment, ok := s.target.Comments[*block.Comment]
+		if !ok {
+			s.report(blockId, "refers to missing comment `%s`", *block.Comment)
+		} else if comment.BlockId == nil || *comment.BlockId != blockId {
+			s.report(blockId, "refers to comment `%s` which is not attached to it", *block.Comment)
+		}
+	}
+	commentIds := slices.Sorted(maps.Keys(s.target.Comments))
+	for _, commentId := range commentIds {
+		comment := s.target.Comments[commentId]
+		if comment.BlockId == nil {
+			continue
+		}
+		block, ok := s.target.Blocks[*comment.BlockId]
+		if !ok {
+			s.report("", "comment `%s` is attached to missing block `%s`", commentId, *comment.BlockId)
+		} else if block.Comment == nil || *block.Comment != commentId {
+			s.report(*comment.BlockId, "comment `%s` is attached to the block which does not refer to it", commentId)
+		}
+	}
+}
+
+// returns whether the block refers to the child by `next` or an input
+func refersTo(block *Block, childId string) bool {
+	if block.Next != nil && *block.Next == childId {
+		return true
+	}
+	for _, input := range block.Inputs {
+		for _, inputId := range inputBlockIds(input) {
+			if inputId == childId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inputBlockIds(input MaybeShadowedInput) []string {
+	blockIds := make([]string, 0)
+	for _, input := range []Input{input.ObscuredInput, input.ShadowedInput} {
+		if blockInput, ok := input.(*BlockInput); ok && blockInput != nil {
+			blockIds = append(blockIds, string(*blockInput))
+		}
+	}
+	return blockIds
+}
+
+func (s *validator) validateLinks(blockId string, block *Block) {
+	if block.TopLevel {
+		if block.Parent != nil {
+			s.report(blockId, "top-level block has parent `%s`", *block.Parent)
+		}
+		if block.X == nil || block.Y == nil {
+			s.report(blockId, "top-level block has no position")
+		}
+	} else if block.Parent == nil {
+		s.report(blockId, "block is neither top-level nor has a parent")
+	}
+	if block.Parent != nil {
+		parent, ok := s.target.Blocks[*block.Parent]
+		if !ok {
+			s.report(blockId, "parent `%s` does not exist", *block.Parent)
+		} else if !refersTo(parent, blockId) {
+			s.report(blockId, "parent `%s` does not refer to the block", *block.Parent)
+		}
+	}
+	// a cap block such as `stop this script` ends its stack
+	if block.Next != nil && block.Mutation != nil && block.Mutation.HasNext != nil && *block.Mutation.HasNext == "false" {
+		s.report(blockId, "cap block `%s` has next `%s`", block.Opcode, *block.Next)
+	}
+	if block.Next != nil {
+		next, ok := s.target.Blocks[*block.Next]
+		if !ok {
+			s.report(blockId, "next `%s` does not exist", *block.Next)
+		} else if next.Parent == nil || *next.Parent != blockId {
+			s.report(blockId, "next `%s` has a different parent", *block.Next)
+		}
+	}
+	for _, name := range sortedInputNames(block.Inputs) {
+		for _, inputId := range inputBlockIds(block.Inputs[name]) {
+			input, ok := s.target.Blocks[inputId]
+			if !ok {
+				s.report(blockId, "input `%s` refers to missing block `%s`", name, inputId)
+			} else if input.Parent == nil || *input.Parent != blockId {
+				s.report(blockId, "input `%s` refers to block `%s` with a different parent", name, inputId)
+			}
+		}
+	}
+}
+
+func sortedInputNames(inputs map[string]MaybeShadowedInput) []string {
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (s *validator) hasVariable(id string) bool {
+	if _, ok := s.target.Variables[id]; ok {
+		return true
+	}
+	_, ok := s.sb3.StageTarget.Variables[id]
+	return ok
+}
+
+func (s *validator) hasList(id string) bool {
+	if _, ok := s.target.Lists[id]; ok {
+		return true
+	}
+	_, ok := s.sb3.StageTarget.Lists[id]
+	return ok
+}
+
+func (s *validator) validateFields(blockId string, block *Block) {
+	if field, ok := block.Fields["VARIABLE"]; ok && field.Id != nil && !s.hasVariable(*field.Id) {
+		s.report(blockId, "variable `%s` (%s) does not exist", field.Value, *field.Id)
+	}
+	if field, ok := block.Fields["LIST"]; ok && field.Id != nil && !s.hasList(*field.Id) {
+		s.report(blockId, "list `%s` (%s) does not exist", field.Value, *field.Id)
+	}
+	for _, name := range sortedInputNames(block.Inputs) {
+		input := block.Inputs[name]
+		for _, input := range []Input{input.ObscuredInput, input.ShadowedInput} {
+			reference, ok := input.(*VariableOrListInput)
+			if !ok || reference == nil {
+				continue
+			}
+			if reference.Type == InputVariable && !s.hasVariable(reference.Id) {
+				s.report(blockId, "input `%s` refers to missing variable `%s` (%s)", name, reference.Value, reference.Id)
+			}
+			if reference.Type == InputList && !s.hasList(reference.Id) {
+				s.report(blockId, "input `%s` refers to missing list `%s` (%s)", name, reference.Value, reference.Id)
+			}
+		}
+	}
+}
+
+func parseArgumentIds(argumentIds *string) ([]string, error) {
+	ids := make([]string, 0)
+	if argumentIds == nil {
+		return ids, nil
+	}
+	err := json.Unmarshal([]byte(*argumentIds), &ids)
+	return ids, err
+}
+
+func (s *validator) validateCall(blockId string, block *Block, prototypes map[string]*Block) {
+	if block.Mutation == nil || block.Mutation.ProcCode == nil {
+		s.report(blockId, "procedure call without `proccode`")
+		return
+	}
+	procCode := *block.Mutation.ProcCode
+	prototype, ok := prototypes[procCode]
+	if !ok {
+		s.report(blockId, "call to undefined procedure `%s`", procCode)
+		return
+	}
+	argumentIds, err := parseArgumentIds(block.Mutation.ArgumentIds)
+	if err != nil {
+		s.report(blockId, "invalid `argumentids`: %s", err.Error())
+		return
+	}
+	prototypeArgumentIds, err := parseArgumentIds(prototype.Mutation.ArgumentIds)
+	if err != nil || !slices.Equal(argumentIds, prototypeArgumentIds) {
+		s.report(blockId, "`argumentids` of the call to `%s` mismatch its prototype", procCode)
+		return
+	}
+	for _, name := range sortedInputNames(block.Inputs) {
+		if !slices.Contains(argumentIds, name) {
+			s.report(blockId, "call to `%s` has unknown argument `%s`", procCode, name)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package scir_test
+
+import (
+	"errors"
+	"testing"
+
+	"yummy-go.com/m/v2/scir"
+)
+
+func pointer[T any](value T) *T {
+	return &value
+}
+
+// a flag script setting a variable then calling a custom block, and the
+// definition of the block
+func validProject() scir.Scir {
+	project := scir.NewProject()
+	stage := project.StageTarget
+	stage.Variables["varId"] = scir.Variable{Name: "score", Value: "0"}
+	stage.Lists["listId"] = scir.List{Name: "items", Value: make([]string, 0)}
+	block := func(opcode string, parent *string, next *string) *scir.Block {
+		return &scir.Block{
+			Opcode: opcode,
+			Inputs: make(map[string]scir.MaybeShadowedInput),
+			Fields: make(map[string]scir.Field),
+			Parent: parent,
+			Next:   next,
+		}
+	}
+	hat := block("event_whenflagclicked", nil, pointer("set"))
+	hat.TopLevel, hat.X, hat.Y = true, pointer(0.0), pointer(0.0)
+	set := block("data_setvariableto", pointer("hat"), pointer("add"))
+	set.Fields["VARIABLE"] = scir.Field{Value: "score", Id: pointer("varId")}
+	add := block("data_addtolist", pointer("set"), pointer("call"))
+	add.Fields["LIST"] = scir.Field{Value: "items", Id: pointer("listId")}
+	call := block("procedures_call", pointer("add"), pointer("stop"))
+	call.Mutation = &scir.Mutation{TagName: "mutation", Children: make([]any, 0), ProcCode: pointer("jump %s"), ArgumentIds: pointer(`["heightId"]`)}
+	call.Inputs["heightId"] = scir.MaybeShadowedInput{Type: scir.Shadow, ShadowedInput: &scir.StringInput{Type: scir.InputString, Value: "10"}}
+	stop := block("control_stop", pointer("call"), nil)
+	stop.Mutation = &scir.Mutation{TagName: "mutation", Children: make([]any, 0), HasNext: pointer("false")}
+	definition := block("procedures_definition", nil, nil)
+	definition.TopLevel, definition.X, definition.Y = true, pointer(0.0), pointer(200.0)
+	prototypeInput := scir.BlockInput("prototype")
+	definition.Inputs["custom_block"] = scir.MaybeShadowedInput{Type: scir.Shadow, ShadowedInput: &prototypeInput}
+	prototype := block("procedures_prototype", pointer("definition"), nil)
+	prototype.Shadow = true
+	prototype.Mutation = &scir.Mutation{TagName: "mutation", Children: make([]any, 0), ProcCode: pointer("jump %s"), ArgumentIds: pointer(`["heightId"]`)}
+	stage.Blocks = map[string]*scir.Block{
+		"hat": hat, "set": set, "add": add, "call": call, "stop": stop,
+		"definition": definition, "prototype": prototype,
+	}
+	stage.Comments["comment"] = scir.Comment{BlockId: pointer("hat"), Text: "starts"}
+	hat.Comment = pointer("comment")
+	return project
+}
+
+func TestValidate(t *testing.T) {
+	if err := scir.Validate(pointer(validProject())); err != nil {
+		t.Fatalf("expected the project to be valid: %s", err)
+	}
+	for _, test := range []struct {
+		name    string
+		mutate  func(stage *scir.Target, project *scir.Scir)
+		blockId string
+		message string
+		// the problems found, breaking a link breaks both of its ends
+		count int
+	}{
+		{"dangling next", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["add"].Next = pointer("missing")
+		}, "add", "next `missing` does not exist", 2},
+		{"next with another parent", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["call"].Parent = pointer("hat")
+		}, "add", "next `call` has a different parent", 2},
+		{"dangling parent", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["hat"].Next = nil
+			stage.Blocks["set"].Parent = pointer("missing")
+		}, "set", "parent `missing` does not exist", 1},
+		{"parent not referring to the block", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["hat"].Next = nil
+		}, "set", "parent `hat` does not refer to the block", 1},
+		{"orphan", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["hat"].Next = nil
+			stage.Blocks["set"].Parent = nil
+		}, "set", "block is neither top-level nor has a parent", 1},
+		{"top-level without position", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["definition"].Y = nil
+		}, "definition", "top-level block has no position", 1},
+		{"top-level with parent", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["set"].TopLevel, stage.Blocks["set"].X, stage.Blocks["set"].Y = true, pointer(0.0), pointer(0.0)
+		}, "set", "top-level block has parent `hat`", 1},
+		{"cap block with next", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["stop"].Next = pointer("after")
+			stage.Blocks["after"] = &scir.Block{Opcode: "looks_show", Inputs: map[string]scir.MaybeShadowedInput{}, Fields: map[string]scir.Field{}, Parent: pointer("stop")}
+		}, "stop", "cap block `control_stop` has next `after`", 1},
+		{"unknown variable", func(stage *scir.Target, _ *scir.Scir) {
+			delete(stage.Variables, "varId")
+		}, "set", "variable `score` (varId) does not exist", 1},
+		{"unknown list", func(stage *scir.Target, _ *scir.Scir) {
+			delete(stage.Lists, "listId")
+		}, "add", "list `items` (listId) does not exist", 1},
+		{"unknown variable input", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["call"].Inputs["heightId"] = scir.MaybeShadowedInput{
+				Type:          scir.Shadowed,
+				ObscuredInput: &scir.VariableOrListInput{Type: scir.InputVariable, Value: "lost", Id: "lostId"},
+				ShadowedInput: &scir.StringInput{Type: scir.InputString, Value: "10"},
+			}
+		}, "call", "input `heightId` refers to missing variable `lost` (lostId)", 1},
+		{"mismatched argumentids", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["call"].Mutation.ArgumentIds = pointer(`["otherId"]`)
+		}, "call", "`argumentids` of the call to `jump %s` mismatch its prototype", 1},
+		{"undefined procedure", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["call"].Mutation.ProcCode = pointer("fall %s")
+		}, "call", "call to undefined procedure `fall %s`", 1},
+		{"unknown argument", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["call"].Inputs["otherId"] = stage.Blocks["call"].Inputs["heightId"]
+		}, "call", "call to `jump %s` has unknown argument `otherId`", 1},
+		{"comment on a missing block", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Blocks["hat"].Comment = nil
+			stage.Comments["comment"] = scir.Comment{BlockId: pointer("missing"), Text: "starts"}
+		}, "", "comment `comment` is attached to missing block `missing`", 1},
+		{"block referring to a missing comment", func(stage *scir.Target, _ *scir.Scir) {
+			delete(stage.Comments, "comment")
+		}, "hat", "refers to missing comment `comment`", 1},
+		{"missing asset", func(stage *scir.Target, project *scir.Scir) {
+			delete(project.Assets, stage.Costumes[0].Md5ext)
+		}, "", "costume `backdrop1` refers to missing asset `" + validProject().StageTarget.Costumes[0].Md5ext + "`", 1},
+		{"missing sound asset", func(stage *scir.Target, _ *scir.Scir) {
+			stage.Sounds = append(stage.Sounds, scir.Sound{Name: "pop", Md5ext: "missing.wav"})
+		}, "", "sound `pop` refers to missing asset `missing.wav`", 1},
+	} {
+		project := validProject()
+		test.mutate(project.StageTarget, &project)
+		err := scir.Validate(&project)
+		if err == nil {
+			t.Errorf("%s: expected the project to be invalid", test.name)
+			continue
+		}
+		problems := err.(interface{ Unwrap() []error }).Unwrap()
+		if len(problems) != test.count {
+			t.Errorf("%s: expected %d problems, found %s", test.name, test.count, err)
+		}
+		found := false
+		for _, problem := range problems {
+			var validationError *scir.ValidationError
+			if errors.As(problem, &validationError) && validationError.Target == "Stage" && validationError.BlockId == test.blockId && validationError.Message == test.message {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected `%s` on block `%s`, found %s", test.name, test.message, test.blockId, err)
+		}
+	}
+}
@@ -49,8 +49,26 @@ func GetStats(level ReportLevel) uint {
 	return Stats[level]
 }
 
+// receives the reports instead of the terminal, the span is nil for
+// reports without one
+type Reporter func(level ReportLevel, span *Span, message string)
+
+var reporter Reporter
+
+// sets the reporter used by Report and ReportNoSpan, nil restores printing,
+// the previous reporter is returned
+func SetReporter(newReporter Reporter) Reporter {
+	previous := reporter
+	reporter = newReporter
+	return previous
+}
+
 func ReportNoSpan(level ReportLevel, message string, args ...any) error {
 	Stats[level] += 1
+	if reporter != nil {
+		reporter(level, nil, fmt.Sprintf(message, args...))
+		return fmt.Errorf("%s: %s", level, message)
+	}
 	reportLevelColorMap[level].Printf("%s", level)
 	fmt.Printf(": ")
 	fmt.Printf(message, args...)
@@ -60,6 +78,10 @@ func ReportNoSpan(level ReportLevel, message string, args ...any) error {
 
 func Report(span Span, level ReportLevel, message string, args ...any) error {
 	Stats[level] += 1
+	if reporter != nil {
+		reporter(level, &span, fmt.Sprintf(message, args...))
+		return fmt.Errorf("%s: %s", level, message)
+	}
 	reportLevelColorMap[level].Printf("%s", level)
 	fmt.Printf(": ")
 	fmt.Printf(message, args...)
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"yummy-go.com/m/v2/scir"
+	"yummy-go.com/m/v2/span"
+)
+
+func runWhere(args []string) int {
+	flags := flag.NewFlagSet("where", flag.ExitOnError)
+	mapPath := flags.String("map", "", "the source map written by the build (default: the only .yummymap.json here)")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: yummy where [-map project.sb3.yummymap.json] <blockId>")
+		return 2
+	}
+	blockId := flags.Arg(0)
+	if *mapPath == "" {
+		matches, _ := filepath.Glob("*.yummymap.json")
+		if len(matches) != 1 {
+			fmt.Fprintln(os.Stderr, "yummy where: cannot choose a source map, use -map")
+			return 2
+		}
+		*mapPath = matches[0]
+	}
+
+	span.ResetStats()
+	sourceMap, err := scir.OpenSourceMap(*mapPath)
+	if err != nil {
+		span.ReportNoSpan(span.Error, "%s", err.Error())
+		return 1
+	}
+	target, location := sourceMap.Lookup(blockId)
+	if location == nil {
+		span.ReportNoSpan(span.Error, "%s: no block `%s`", *mapPath, blockId)
+		return 1
+	}
+	sourcePath := filepath.Join(filepath.Dir(*mapPath), filepath.FromSlash(location.Path))
+	sourceCode, err := os.ReadFile(sourcePath)
+	if err != nil {
+		span.ReportNoSpan(span.Error, "%s", err.Error())
+		return 1
+	}
+	source := string(sourceCode)
+	sourceLines := strings.Split(source, "\n")
+	if !fitsSource(location.From, location.To, source, sourceLines) {
+		span.ReportNoSpan(span.Error, "%s: changed since the build", sourcePath)
+		return 1
+	}
+	span.Report(span.Span{
+		From:        location.From,
+		To:          location.To,
+		Source:      &source,
+		SourceLines: &sourceLines,
+		Path:        &sourcePath,
+	}, span.Info, "block `%s` of target `%s`", blockId, target)
+	return 0
+}
+
+// a location read from the source map may point past a source edited since
+// the build, span.Report slices the lines with it
+func fitsSource(from, to span.Position, source string, sourceLines []string) bool {
+	if from.Index > to.Index || to.Index > uint(len(source)) {
+		return false
+	}
+	if from.Lineno > to.Lineno || to.Lineno >= uint(len(sourceLines)) {
+		return false
+	}
+	if from.LineIndex > uint(len(sourceLines[from.Lineno])) || to.LineIndex > uint(len(sourceLines[to.Lineno])) {
+		return false
+	}
+	return from.Lineno != to.Lineno || from.LineIndex <= to.LineIndex
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"yummy-go.com/m/v2/span"
+)
+
+func TestFitsSource(t *testing.T) {
+	source := "target Stage\nfunc f() {\n}\n"
+	sourceLines := strings.Split(source, "\n")
+	position := func(index, lineno, lineIndex uint) span.Position {
+		return span.Position{Index: index, Lineno: lineno, LineIndex: lineIndex}
+	}
+	for _, test := range []struct {
+		name     string
+		from, to span.Position
+		fits     bool
+	}{
+		{"the function", position(13, 1, 0), position(24, 2, 1), true},
+		{"an empty span", position(13, 1, 0), position(13, 1, 0), true},
+		{"past the source", position(13, 1, 0), position(40, 2, 1), false},
+		{"past the lines", position(13, 1, 0), position(24, 5, 1), false},
+		{"reversed", position(24, 2, 1), position(13, 1, 0), false},
+		{"reversed on a line", position(17, 1, 4), position(15, 1, 2), false},
+		{"past the line", position(13, 1, 0), position(24, 2, 7), false},
+		{"past the first line", position(13, 1, 30), position(24, 2, 1), false},
+	} {
+		if fits := fitsSource(test.from, test.to, source, sourceLines); fits != test.fits {
+			t.Errorf("%s: expected %v, found %v", test.name, test.fits, fits)
+		}
+	}
+}